@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,6 +25,8 @@ import (
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/todo"
+	"github.com/charmbracelet/crush/internal/usage"
 )
 
 type App struct {
@@ -30,12 +34,18 @@ type App struct {
 	Messages    message.Service
 	History     history.Service
 	Permissions permission.Service
+	Todos       todo.Service
+	Usage       usage.Service
 
 	CoderAgent agent.Service
+	agentCfg   config.Agent
+	// agentCancel stops forwarding the events of the current agent.
+	agentCancel context.CancelFunc
 
 	LSPClients *csync.Map[string, *lsp.Client]
 
 	config *config.Config
+	db     *sql.DB
 
 	serviceEventsWG *sync.WaitGroup
 	eventsCtx       context.Context
@@ -64,11 +74,14 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 		Messages:    messages,
 		History:     files,
 		Permissions: permission.NewPermissionService(cfg.WorkingDir(), skipPermissionsRequests, allowedTools),
+		Todos:       todo.NewService(q),
+		Usage:       usage.NewService(q),
 		LSPClients:  csync.NewMap[string, *lsp.Client](),
 
 		globalCtx: ctx,
 
 		config: cfg,
+		db:     conn,
 
 		events:          make(chan tea.Msg, 100),
 		serviceEventsWG: &sync.WaitGroup{},
@@ -99,11 +112,38 @@ func (app *App) Config() *config.Config {
 	return app.config
 }
 
+// RunOptions configures a non-interactive run.
+type RunOptions struct {
+	// SessionID is the session to continue. When empty, a new session is
+	// created.
+	SessionID string
+	// Quiet hides the spinner.
+	Quiet bool
+	// Policy decides the permission requests of the run. When nil, every
+	// request is approved.
+	Policy *permission.Policy
+	// OutputFormat selects how the run is reported on stdout. Defaults to
+	// OutputFormatText.
+	OutputFormat OutputFormat
+	// Budget limits the run on top of the configured budget.
+	Budget config.Budget
+	// Plan runs the prompt in plan mode: the agent only explores, with
+	// read-only tools, and answers with a plan.
+	Plan bool
+}
+
 // RunNonInteractive handles the execution flow when a prompt is provided via
 // CLI flag.
-func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool) error {
+func (app *App) RunNonInteractive(ctx context.Context, prompt string, opts RunOptions) error {
 	slog.Info("Running in non-interactive mode")
 
+	outputFormat := opts.OutputFormat
+	if outputFormat == "" {
+		outputFormat = OutputFormatText
+	}
+	// The spinner would get in the way of machine-readable output.
+	quiet := opts.Quiet || outputFormat != OutputFormatText
+
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -123,76 +163,73 @@ func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool
 	}
 	defer stopSpinner()
 
-	const maxPromptLengthForTitle = 100
-	titlePrefix := "Non-interactive: "
-	var titleSuffix string
+	sess, err := app.nonInteractiveSession(ctx, prompt, opts.SessionID)
+	if err != nil {
+		return err
+	}
 
-	if len(prompt) > maxPromptLengthForTitle {
-		titleSuffix = prompt[:maxPromptLengthForTitle] + "..."
+	if opts.Policy != nil {
+		app.Permissions.SetSessionPolicy(sess.ID, *opts.Policy)
 	} else {
-		titleSuffix = prompt
+		// Automatically approve all permission requests for this non-interactive session
+		app.Permissions.AutoApproveSession(sess.ID)
 	}
-	title := titlePrefix + titleSuffix
+	if !opts.Budget.IsZero() {
+		app.CoderAgent.SetSessionBudget(sess.ID, opts.Budget)
+	}
+	app.CoderAgent.SetPlanMode(sess.ID, opts.Plan)
 
-	sess, err := app.Sessions.Create(ctx, title)
+	existing, err := app.Messages.List(ctx, sess.ID)
 	if err != nil {
-		return fmt.Errorf("failed to create session for non-interactive mode: %w", err)
+		return fmt.Errorf("failed to list session messages: %w", err)
+	}
+	output := newRunOutput(os.Stdout, outputFormat, sess.ID, existing)
+	// What the session spent before, so that the result only reports the
+	// run when continuing a session.
+	spentBefore, err := app.Usage.SessionTotal(ctx, sess.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get session usage: %w", err)
 	}
-	slog.Info("Created session for non-interactive run", "session_id", sess.ID)
 
-	// Automatically approve all permission requests for this non-interactive session
-	app.Permissions.AutoApproveSession(sess.ID)
+	messageEvents := app.Messages.Subscribe(ctx)
+	agentEvents := app.CoderAgent.Subscribe(ctx)
 
 	done, err := app.CoderAgent.Run(ctx, sess.ID, prompt)
 	if err != nil {
 		return fmt.Errorf("failed to start agent processing stream: %w", err)
 	}
 
-	messageEvents := app.Messages.Subscribe(ctx)
-	messageReadBytes := make(map[string]int)
-
 	for {
 		select {
 		case result := <-done:
 			stopSpinner()
-
-			if result.Error != nil {
-				if errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled) {
-					slog.Info("Non-interactive: agent processing cancelled", "session_id", sess.ID)
-					return nil
+			// Write the events published before the run ended that weren't
+			// received yet.
+		drain:
+			for {
+				select {
+				case event := <-agentEvents:
+					if err := handleRunAgentEvent(output, event.Payload); err != nil {
+						return fmt.Errorf("failed to write output: %w", err)
+					}
+				default:
+					break drain
 				}
-				return fmt.Errorf("agent processing failed: %w", result.Error)
-			}
-
-			msgContent := result.Message.Content().String()
-			readBts := messageReadBytes[result.Message.ID]
-
-			if len(msgContent) < readBts {
-				slog.Error("Non-interactive: message content is shorter than read bytes", "message_length", len(msgContent), "read_bytes", readBts)
-				return fmt.Errorf("message content is shorter than read bytes: %d < %d", len(msgContent), readBts)
 			}
-			fmt.Println(msgContent[readBts:])
-			messageReadBytes[result.Message.ID] = len(msgContent)
-
-			slog.Info("Non-interactive: run completed", "session_id", sess.ID)
-			return nil
+			return app.finishNonInteractiveRun(sess.ID, output, result, spentBefore)
 
 		case event := <-messageEvents:
 			msg := event.Payload
 			if msg.SessionID == sess.ID && msg.Role == message.Assistant && len(msg.Parts) > 0 {
 				stopSpinner()
+			}
+			if err := output.handleMessage(msg); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
 
-				content := msg.Content().String()
-				readBytes := messageReadBytes[msg.ID]
-
-				if len(content) < readBytes {
-					slog.Error("Non-interactive: message content is shorter than read bytes", "message_length", len(content), "read_bytes", readBytes)
-					return fmt.Errorf("message content is shorter than read bytes: %d < %d", len(content), readBytes)
-				}
-
-				part := content[readBytes:]
-				fmt.Print(part)
-				messageReadBytes[msg.ID] = len(content)
+		case event := <-agentEvents:
+			if err := handleRunAgentEvent(output, event.Payload); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
 			}
 
 		case <-ctx.Done():
@@ -202,6 +239,106 @@ func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool
 	}
 }
 
+// handleRunAgentEvent writes an event the agent published during a
+// non-interactive run. The response or error that ends the run is also
+// published, but it is written once the run is over.
+func handleRunAgentEvent(output *runOutput, event agent.AgentEvent) error {
+	if event.Type == agent.AgentEventTypeResponse || event.Type == agent.AgentEventTypeError {
+		return nil
+	}
+	return output.handleAgentEvent(event)
+}
+
+// finishNonInteractiveRun writes the end of the output of a run. Structured
+// output gets the records the message subscription may have missed, the
+// event that ended the run and the final result record.
+func (app *App) finishNonInteractiveRun(sessionID string, output *runOutput, result agent.AgentEvent, spentBefore usage.SessionTotal) error {
+	// The run context may already be cancelled at this point.
+	ctx := context.Background()
+
+	if output.format != OutputFormatText {
+		msgs, err := app.Messages.List(ctx, sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to list session messages: %w", err)
+		}
+		for _, msg := range msgs {
+			if err := output.handleMessage(msg); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+		}
+		if err := output.handleAgentEvent(result); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	total, err := app.Usage.SessionTotal(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session usage: %w", err)
+	}
+	runErr := result.Error
+	if runErr == nil {
+		runErr = overBudget(result)
+	}
+	if err := output.finish(total.Since(spentBefore), result, runErr); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	if result.Error != nil {
+		if errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled) {
+			slog.Info("Non-interactive: agent processing cancelled", "session_id", sessionID)
+			return nil
+		}
+		return fmt.Errorf("agent processing failed: %w", result.Error)
+	}
+	if runErr != nil {
+		return runErr
+	}
+	slog.Info("Non-interactive: run completed", "session_id", sessionID)
+	return nil
+}
+
+// overBudget returns an error if the run was stopped because it reached one
+// of its budget limits.
+func overBudget(result agent.AgentEvent) error {
+	finish := result.Message.FinishPart()
+	if finish == nil || finish.Reason != message.FinishReasonBudgetExceeded {
+		return nil
+	}
+	return fmt.Errorf("%s: %s", strings.ToLower(finish.Message), finish.Details)
+}
+
+// nonInteractiveSession returns the session a non-interactive run should use:
+// the existing session with the given ID, or a new one titled after the
+// prompt.
+func (app *App) nonInteractiveSession(ctx context.Context, prompt, sessionID string) (session.Session, error) {
+	if sessionID != "" {
+		sess, err := app.Sessions.Get(ctx, sessionID)
+		if err != nil {
+			return session.Session{}, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+		}
+		slog.Info("Continuing session for non-interactive run", "session_id", sess.ID)
+		return sess, nil
+	}
+
+	const maxPromptLengthForTitle = 100
+	titlePrefix := "Non-interactive: "
+	var titleSuffix string
+
+	if len(prompt) > maxPromptLengthForTitle {
+		titleSuffix = prompt[:maxPromptLengthForTitle] + "..."
+	} else {
+		titleSuffix = prompt
+	}
+	title := titlePrefix + titleSuffix
+
+	sess, err := app.Sessions.Create(ctx, title)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to create session for non-interactive mode: %w", err)
+	}
+	slog.Info("Created session for non-interactive run", "session_id", sess.ID)
+	return sess, nil
+}
+
 func (app *App) UpdateAgentModel() error {
 	return app.CoderAgent.UpdateModel()
 }
@@ -214,6 +351,7 @@ func (app *App) setupEvents() {
 	setupSubscriber(ctx, app.serviceEventsWG, "permissions", app.Permissions.Subscribe, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "permissions-notifications", app.Permissions.SubscribeNotifications, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "history", app.History.Subscribe, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "todos", app.Todos.Subscribe, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "mcp", agent.SubscribeMCPEvents, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "lsp", SubscribeLSPEvents, app.events)
 	cleanupFunc := func() error {
@@ -262,25 +400,65 @@ func (app *App) InitCoderAgent() error {
 	if coderAgentCfg.ID == "" {
 		return fmt.Errorf("coder agent configuration is missing")
 	}
-	var err error
-	app.CoderAgent, err = agent.NewAgent(
+	if err := app.setAgent(coderAgentCfg); err != nil {
+		slog.Error("Failed to create coder agent", "err", err)
+		return err
+	}
+
+	// Add MCP client cleanup to shutdown process
+	app.cleanupFuncs = append(app.cleanupFuncs, agent.CloseMCPClients)
+	return nil
+}
+
+// SwitchAgent replaces the agent that runs the prompts with the configured
+// agent with the given id.
+func (app *App) SwitchAgent(id string) error {
+	agentCfg, ok := app.config.Agents[id]
+	if !ok || id == "task" {
+		return fmt.Errorf("agent %q not found in config", id)
+	}
+	if app.CoderAgent != nil && app.CoderAgent.IsBusy() {
+		return fmt.Errorf("agent is busy")
+	}
+	if err := app.setAgent(agentCfg); err != nil {
+		return fmt.Errorf("failed to create agent %s: %w", agentCfg.Name, err)
+	}
+	slog.Info("Switched agent", "agent", id)
+	return nil
+}
+
+// AgentConfig returns the configuration of the agent that runs the prompts.
+func (app *App) AgentConfig() config.Agent {
+	return app.agentCfg
+}
+
+func (app *App) setAgent(agentCfg config.Agent) error {
+	coderAgent, err := agent.NewAgent(
 		app.globalCtx,
-		coderAgentCfg,
+		agentCfg,
 		app.Permissions,
 		app.Sessions,
 		app.Messages,
 		app.History,
+		app.Todos,
+		app.Usage,
 		app.LSPClients,
 	)
 	if err != nil {
-		slog.Error("Failed to create coder agent", "err", err)
 		return err
 	}
+	if app.CoderAgent != nil {
+		agent.CopySessionSettings(app.CoderAgent, coderAgent)
+	}
+	if app.agentCancel != nil {
+		app.agentCancel()
+	}
+	app.CoderAgent = coderAgent
+	app.agentCfg = agentCfg
 
-	// Add MCP client cleanup to shutdown process
-	app.cleanupFuncs = append(app.cleanupFuncs, agent.CloseMCPClients)
-
-	setupSubscriber(app.eventsCtx, app.serviceEventsWG, "coderAgent", app.CoderAgent.Subscribe, app.events)
+	ctx, cancel := context.WithCancel(app.eventsCtx)
+	app.agentCancel = cancel
+	setupSubscriber(ctx, app.serviceEventsWG, "coderAgent", app.CoderAgent.Subscribe, app.events)
 	return nil
 }
 
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockApp creates an app answered by the mock provider with script, in a
+// project of its own whose crush.json also holds settings. It returns the
+// app and the project directory. The agent reads the global configuration,
+// so tests using it can't run in parallel.
+func newMockApp(t *testing.T, script string, settings map[string]any) (*App, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	workingDir := filepath.Join(dir, "project")
+	require.NoError(t, os.MkdirAll(workingDir, 0o755))
+	cfg := map[string]any{
+		"options":   map[string]any{"disable_provider_auto_update": true},
+		"providers": map[string]any{"mock": map[string]any{"type": "mock", "script": "script.json"}},
+		"models": map[string]any{
+			"large": map[string]any{"provider": "mock", "model": "mock"},
+			"small": map[string]any{"provider": "mock", "model": "mock"},
+		},
+	}
+	for key, value := range settings {
+		cfg[key] = value
+	}
+	data, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "crush.json"), data, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "script.json"), []byte(script), 0o644))
+
+	c, err := config.Init(workingDir, "", false)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(c.Options.DataDirectory, 0o700))
+	conn, err := db.Connect(t.Context(), c.Options.DataDirectory)
+	require.NoError(t, err)
+	app, err := New(t.Context(), conn, c)
+	require.NoError(t, err)
+	t.Cleanup(app.Shutdown)
+	require.NotNil(t, app.CoderAgent)
+	return app, workingDir
+}
+
+func TestRunNonInteractiveTurnEndHooks(t *testing.T) {
+	app, workingDir := newMockApp(t, `{"responses": [{"content": "Done"}]}`, map[string]any{
+		"hooks": map[string]any{
+			"on_turn_end": []map[string]any{{"command": "sleep 0.2 && cat > turn.json"}},
+		},
+	})
+
+	require.NoError(t, app.RunNonInteractive(t.Context(), "Hello", RunOptions{Quiet: true, OutputFormat: OutputFormatJSON}))
+
+	// The run only returns once the hooks are done, as the process exits
+	// right after.
+	data, err := os.ReadFile(filepath.Join(workingDir, "turn.json"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"response":"Done"`)
+}
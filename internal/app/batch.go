@@ -0,0 +1,229 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+// BatchPrompt is a prompt of a batch run.
+type BatchPrompt struct {
+	// ID identifies the prompt in the results. Defaults to its line number.
+	ID     string `json:"id,omitempty"`
+	Prompt string `json:"prompt"`
+	// Dir is the directory the prompt works in, relative to the working
+	// directory unless absolute. Defaults to the working directory.
+	Dir string `json:"dir,omitempty"`
+}
+
+// BatchOptions configures a batch run.
+type BatchOptions struct {
+	// Parallel is the number of prompts run at the same time. Defaults to 1.
+	Parallel int
+	// Policy decides the permission requests of every prompt. When nil,
+	// every request is approved.
+	Policy *permission.Policy
+	// Budget limits each prompt on top of the configured budget.
+	Budget config.Budget
+}
+
+// BatchResult is the outcome of a prompt of a batch run.
+type BatchResult struct {
+	ID               string  `json:"id"`
+	SessionID        string  `json:"session_id,omitempty"`
+	IsError          bool    `json:"is_error"`
+	Error            string  `json:"error,omitempty"`
+	FinishReason     string  `json:"finish_reason,omitempty"`
+	Text             string  `json:"text"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	DurationMS       int64   `json:"duration_ms"`
+}
+
+// ReadBatchPrompts reads prompts from JSON lines. Blank lines are skipped.
+func ReadBatchPrompts(r io.Reader) ([]BatchPrompt, error) {
+	var prompts []BatchPrompt
+	ids := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var p BatchPrompt
+		if err := json.Unmarshal([]byte(text), &p); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if strings.TrimSpace(p.Prompt) == "" {
+			return nil, fmt.Errorf("line %d: prompt is empty", line)
+		}
+		if p.ID == "" {
+			p.ID = strconv.Itoa(line)
+		}
+		if ids[p.ID] {
+			return nil, fmt.Errorf("line %d: duplicate id %q", line, p.ID)
+		}
+		ids[p.ID] = true
+		prompts = append(prompts, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return prompts, nil
+}
+
+// RunBatch runs each prompt in a new session, opts.Parallel at a time. Each
+// prompt runs its commands in a shell of its own, so that prompts running at
+// the same time don't change each other's directory or environment. The
+// result of each prompt is written to w as a JSON line as soon as it's done,
+// and all results are returned in the order of the prompts. Prompts not
+// started when ctx is cancelled have no session.
+func (app *App) RunBatch(ctx context.Context, prompts []BatchPrompt, opts BatchOptions, w io.Writer) ([]BatchResult, error) {
+	parallel := max(opts.Parallel, 1)
+	results := make([]BatchResult, len(prompts))
+	for i, p := range prompts {
+		results[i] = BatchResult{ID: p.ID, IsError: true, Error: context.Canceled.Error()}
+	}
+
+	var (
+		mu       sync.Mutex
+		enc      = json.NewEncoder(w)
+		writeErr error
+	)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(parallel, len(prompts)) {
+		wg.Go(func() {
+			for i := range indexes {
+				result := app.runBatchPrompt(ctx, prompts[i], opts)
+				mu.Lock()
+				results[i] = result
+				if writeErr == nil {
+					writeErr = enc.Encode(result)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+
+send:
+	for i := range prompts {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if writeErr != nil {
+		return results, fmt.Errorf("failed to write results: %w", writeErr)
+	}
+	return results, ctx.Err()
+}
+
+func (app *App) runBatchPrompt(ctx context.Context, p BatchPrompt, opts BatchOptions) BatchResult {
+	startedAt := time.Now()
+	result := BatchResult{ID: p.ID}
+	fail := func(err error) BatchResult {
+		result.IsError = true
+		result.Error = err.Error()
+		result.DurationMS = time.Since(startedAt).Milliseconds()
+		return result
+	}
+
+	dir, err := app.batchPromptDir(p)
+	if err != nil {
+		return fail(err)
+	}
+	content := p.Prompt
+	if p.Dir != "" {
+		// The system prompt names the working directory of the project.
+		content = fmt.Sprintf("Work in the directory %s.\n\n%s", dir, p.Prompt)
+	}
+	ctx = tools.WithWorkingDir(ctx, dir)
+
+	sess, err := app.nonInteractiveSession(ctx, p.Prompt, "")
+	if err != nil {
+		return fail(err)
+	}
+	result.SessionID = sess.ID
+	if opts.Policy != nil {
+		app.Permissions.SetSessionPolicy(sess.ID, *opts.Policy)
+	} else {
+		app.Permissions.AutoApproveSession(sess.ID)
+	}
+	if !opts.Budget.IsZero() {
+		app.CoderAgent.SetSessionBudget(sess.ID, opts.Budget)
+	}
+
+	slog.Info("Batch: running prompt", "id", p.ID, "session_id", sess.ID)
+	done, err := app.CoderAgent.Run(ctx, sess.ID, content)
+	if err != nil {
+		return fail(fmt.Errorf("failed to start agent processing stream: %w", err))
+	}
+	if done == nil {
+		return fail(fmt.Errorf("session %s is busy", sess.ID))
+	}
+	event := <-done
+
+	result.FinishReason = string(event.Message.FinishReason())
+	result.Text = event.Message.Content().Text
+	// The session usage is read even if ctx was cancelled, so that the cost
+	// of an interrupted prompt is still reported.
+	spent, err := app.Usage.SessionTotal(context.Background(), sess.ID)
+	if err != nil {
+		return fail(fmt.Errorf("failed to get session usage: %w", err))
+	}
+	result.PromptTokens = spent.PromptTokens
+	result.CompletionTokens = spent.CompletionTokens
+	result.Cost = spent.Cost
+	if event.Error != nil {
+		return fail(event.Error)
+	}
+	if err := overBudget(event); err != nil {
+		return fail(err)
+	}
+	result.DurationMS = time.Since(startedAt).Milliseconds()
+	slog.Info("Batch: prompt completed", "id", p.ID, "session_id", sess.ID)
+	return result
+}
+
+// batchPromptDir returns the absolute directory a prompt works in.
+func (app *App) batchPromptDir(p BatchPrompt) (string, error) {
+	dir := app.config.WorkingDir()
+	if p.Dir == "" {
+		return dir, nil
+	}
+	if filepath.IsAbs(p.Dir) {
+		dir = filepath.Clean(p.Dir)
+	} else {
+		dir = filepath.Join(dir, p.Dir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid dir: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid dir: %s is not a directory", dir)
+	}
+	return dir, nil
+}
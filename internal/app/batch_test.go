@@ -0,0 +1,100 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBatchPrompts(t *testing.T) {
+	t.Parallel()
+
+	prompts, err := ReadBatchPrompts(strings.NewReader(`{"id": "PROJ-1", "prompt": "Fix the flaky test"}
+
+{"prompt": "Update the changelog"}
+`))
+	require.NoError(t, err)
+	require.Equal(t, []BatchPrompt{
+		{ID: "PROJ-1", Prompt: "Fix the flaky test"},
+		{ID: "3", Prompt: "Update the changelog"},
+	}, prompts)
+
+	_, err = ReadBatchPrompts(strings.NewReader(`{"prompt": "a"}` + "\n" + `{"prompt": " "}`))
+	require.EqualError(t, err, "line 2: prompt is empty")
+
+	_, err = ReadBatchPrompts(strings.NewReader(`{"id": "a", "prompt": "a"}` + "\n" + `{"id": "a", "prompt": "b"}`))
+	require.EqualError(t, err, `line 2: duplicate id "a"`)
+
+	_, err = ReadBatchPrompts(strings.NewReader(`Fix the flaky test`))
+	require.ErrorContains(t, err, "line 1:")
+}
+
+// batchToolResults returns the first line of the tool results of a session.
+func batchToolResults(t *testing.T, app *App, sessionID string) []string {
+	t.Helper()
+	msgs, err := app.Messages.List(t.Context(), sessionID)
+	require.NoError(t, err)
+	var results []string
+	for _, msg := range msgs {
+		for _, result := range msg.ToolResults() {
+			first, _, _ := strings.Cut(result.Content, "\n")
+			results = append(results, first)
+		}
+	}
+	return results
+}
+
+func TestRunBatch(t *testing.T) {
+	// The first prompt leaves its shell in another directory, which the
+	// second prompt doesn't see. Titles are free, as they may be recorded
+	// after the result.
+	app, workingDir := newMockApp(t, `{
+		"responses": [
+			{"tool_calls": [{"name": "bash", "input": {"command": "pwd && cd /"}}], "usage": {"input_tokens": 100, "output_tokens": 10}},
+			{"content": "Done", "usage": {"input_tokens": 200, "output_tokens": 5}},
+			{"tool_calls": [{"name": "bash", "input": {"command": "pwd"}}], "usage": {"input_tokens": 100, "output_tokens": 10}},
+			{"content": "Done", "usage": {"input_tokens": 200, "output_tokens": 5}}
+		],
+		"roles": {"title": [{"content": "Where"}, {"content": "Where"}]}
+	}`, nil)
+	require.NoError(t, os.MkdirAll(filepath.Join(workingDir, "services", "api"), 0o755))
+
+	var out bytes.Buffer
+	results, err := app.RunBatch(t.Context(), []BatchPrompt{
+		{ID: "api", Dir: "services/api", Prompt: "Where are you?"},
+		{ID: "root", Prompt: "Where are you?"},
+		{ID: "missing", Dir: "services/web", Prompt: "Where are you?"},
+	}, BatchOptions{Parallel: 1}, &out)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	require.False(t, results[0].IsError, results[0].Error)
+	require.Equal(t, "Done", results[0].Text)
+	require.Equal(t, string(message.FinishReasonEndTurn), results[0].FinishReason)
+	// Both requests of the prompt count.
+	require.Equal(t, int64(300), results[0].PromptTokens)
+	require.Equal(t, int64(15), results[0].CompletionTokens)
+	require.Equal(t, []string{filepath.Join(workingDir, "services", "api")}, batchToolResults(t, app, results[0].SessionID))
+
+	require.False(t, results[1].IsError, results[1].Error)
+	require.Equal(t, []string{workingDir}, batchToolResults(t, app, results[1].SessionID))
+
+	require.True(t, results[2].IsError)
+	require.Empty(t, results[2].SessionID)
+	require.Contains(t, results[2].Error, "invalid dir")
+
+	var written []BatchResult
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var result BatchResult
+		require.NoError(t, dec.Decode(&result))
+		written = append(written, result)
+	}
+	require.Len(t, written, 3)
+}
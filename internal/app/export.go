@@ -0,0 +1,29 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/crush/internal/transcript"
+)
+
+// ExportSession writes the transcript of a session to the exports folder of
+// the data directory and returns the path of the file.
+func (app *App) ExportSession(ctx context.Context, sessionID string, format transcript.Format) (string, error) {
+	t, err := transcript.Load(ctx, app.Sessions, app.Messages, app.History, app.config.WorkingDir(), sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(app.config.Options.DataDirectory, "exports")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create exports directory: %w", err)
+	}
+	path := filepath.Join(dir, sessionID+format.Extension())
+	if err := transcript.WriteFile(path, t, format); err != nil {
+		return "", err
+	}
+	return path, nil
+}
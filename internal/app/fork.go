@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/google/uuid"
+)
+
+// ForkSession creates a new session with the conversation of a session up to
+// and including the given message, and the file history recorded until then.
+// The original session is left alone and files on disk aren't touched.
+func (app *App) ForkSession(ctx context.Context, sessionID, messageID string) (session.Session, error) {
+	if app.CoderAgent != nil && app.CoderAgent.IsSessionBusy(sessionID) {
+		return session.Session{}, errors.New("session is busy, cancel the current request first")
+	}
+	sess, err := app.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	msgs, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	msgs, err = forkMessages(msgs, messageID)
+	if err != nil {
+		return session.Session{}, err
+	}
+	versions, err := app.History.ListBySession(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list file history: %w", err)
+	}
+
+	// Versions don't record which message they belong to, so they are kept
+	// up to the last update of the forked messages.
+	var cutoff int64
+	for _, msg := range msgs {
+		cutoff = max(cutoff, msg.UpdatedAt)
+	}
+	versions = slices.DeleteFunc(versions, func(version history.File) bool {
+		return version.CreatedAt > cutoff
+	})
+	return app.forkSession(ctx, sess, msgs, versions)
+}
+
+// forkMessages returns the messages of a session up to and including the one
+// with the given ID. An assistant message is forked together with the tool
+// results that answer its tool calls.
+func forkMessages(msgs []message.Message, messageID string) ([]message.Message, error) {
+	idx := slices.IndexFunc(msgs, func(msg message.Message) bool {
+		return msg.ID == messageID
+	})
+	if idx == -1 {
+		return nil, fmt.Errorf("message %s not found in session", messageID)
+	}
+	end := idx + 1
+	if msgs[idx].Role == message.Assistant && len(msgs[idx].ToolCalls()) > 0 {
+		for end < len(msgs) && msgs[end].Role == message.Tool {
+			end++
+		}
+	}
+	return msgs[:end], nil
+}
+
+// forkSession creates a new session forked from src with a copy of the given
+// messages and file versions. The copies keep their timestamps, so they stay
+// in the same order as the originals, and everything is copied in a single
+// transaction so a failed fork leaves nothing behind.
+func (app *App) forkSession(ctx context.Context, src session.Session, msgs []message.Message, versions []history.File) (session.Session, error) {
+	tx, err := app.db.BeginTx(ctx, nil)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	q := db.New(tx)
+
+	forkID := uuid.New().String()
+	_, err = q.CreateSession(ctx, db.CreateSessionParams{
+		ID:                  forkID,
+		Title:               "Fork of " + src.Title,
+		PromptTokens:        src.PromptTokens,
+		CompletionTokens:    src.CompletionTokens,
+		ForkedFromSessionID: sql.NullString{String: src.ID, Valid: true},
+	})
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	var summaryMessageID string
+	for _, msg := range msgs {
+		id := uuid.New().String()
+		parts, err := message.MarshalParts(msg.Parts)
+		if err != nil {
+			return session.Session{}, fmt.Errorf("failed to encode message: %w", err)
+		}
+		var finishedAt sql.NullInt64
+		if finish := msg.FinishPart(); finish != nil {
+			finishedAt = sql.NullInt64{Int64: finish.Time, Valid: true}
+		}
+		err = q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:         id,
+			SessionID:  forkID,
+			Role:       string(msg.Role),
+			Parts:      string(parts),
+			Model:      sql.NullString{String: msg.Model, Valid: true},
+			Provider:   sql.NullString{String: msg.Provider, Valid: msg.Provider != ""},
+			CreatedAt:  msg.CreatedAt,
+			UpdatedAt:  msg.UpdatedAt,
+			FinishedAt: finishedAt,
+		})
+		if err != nil {
+			return session.Session{}, fmt.Errorf("failed to copy message: %w", err)
+		}
+		if msg.ID == src.SummaryMessageID {
+			summaryMessageID = id
+		}
+	}
+
+	for _, version := range versions {
+		err := q.ImportFile(ctx, db.ImportFileParams{
+			ID:        uuid.New().String(),
+			SessionID: forkID,
+			Path:      version.Path,
+			Content:   version.Content,
+			Version:   version.Version,
+			IsNew:     version.IsNew,
+			CreatedAt: version.CreatedAt,
+			UpdatedAt: version.UpdatedAt,
+		})
+		if err != nil {
+			return session.Session{}, fmt.Errorf("failed to copy file history: %w", err)
+		}
+	}
+
+	if summaryMessageID != "" {
+		_, err = q.UpdateSession(ctx, db.UpdateSessionParams{
+			ID:               forkID,
+			Title:            "Fork of " + src.Title,
+			PromptTokens:     src.PromptTokens,
+			CompletionTokens: src.CompletionTokens,
+			SummaryMessageID: sql.NullString{String: summaryMessageID, Valid: true},
+		})
+		if err != nil {
+			return session.Session{}, fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return session.Session{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	fork, err := app.Sessions.Get(ctx, forkID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	slog.Info("Forked session", "session_id", src.ID, "fork_id", fork.ID, "messages", len(msgs))
+	return fork, nil
+}
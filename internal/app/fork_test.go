@@ -0,0 +1,135 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestApp creates an app without any provider configured, backed by a
+// fresh database.
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	app, err := New(t.Context(), conn, &config.Config{
+		Providers: csync.NewMap[string, config.ProviderConfig](),
+	})
+	require.NoError(t, err)
+	t.Cleanup(app.Shutdown)
+	return app
+}
+
+func TestForkMessages(t *testing.T) {
+	t.Parallel()
+
+	msgs := []message.Message{
+		{ID: "1", Role: message.User},
+		{ID: "2", Role: message.Assistant, Parts: []message.ContentPart{message.ToolCall{ID: "call"}}},
+		{ID: "3", Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{ToolCallID: "call"}}},
+		{ID: "4", Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "done"}}},
+		{ID: "5", Role: message.User},
+	}
+
+	ids := func(msgs []message.Message) []string {
+		var ids []string
+		for _, msg := range msgs {
+			ids = append(ids, msg.ID)
+		}
+		return ids
+	}
+
+	forked, err := forkMessages(msgs, "1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, ids(forked))
+
+	// The tool results come along with the assistant message that asked for
+	// them.
+	forked, err = forkMessages(msgs, "2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2", "3"}, ids(forked))
+
+	forked, err = forkMessages(msgs, "4")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2", "3", "4"}, ids(forked))
+
+	_, err = forkMessages(msgs, "6")
+	require.EqualError(t, err, "message 6 not found in session")
+}
+
+func TestForkSession(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	ctx := t.Context()
+
+	sess, err := app.Sessions.Create(ctx, "Fix the tests")
+	require.NoError(t, err)
+	var msgs []message.Message
+	for _, params := range []message.CreateMessageParams{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Fix the tests"}}},
+		{Role: message.Assistant, Model: "gpt-4o", Parts: []message.ContentPart{
+			message.TextContent{Text: "Fixed"},
+			message.Finish{Reason: message.FinishReasonEndTurn, Time: 1700000000},
+		}},
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Thanks"}}},
+	} {
+		msg, err := app.Messages.Create(ctx, sess.ID, params)
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+	}
+	sess.SummaryMessageID = msgs[1].ID
+	sess.PromptTokens = 120
+	sess, err = app.Sessions.Save(ctx, sess)
+	require.NoError(t, err)
+
+	_, err = app.History.CreateNew(ctx, sess.ID, "/project/main.go")
+	require.NoError(t, err)
+	_, err = app.History.CreateVersion(ctx, sess.ID, "/project/main.go", "package main\n\nfunc main() {}")
+	require.NoError(t, err)
+
+	fork, err := app.ForkSession(ctx, sess.ID, msgs[1].ID)
+	require.NoError(t, err)
+	require.NotEqual(t, sess.ID, fork.ID)
+	require.Equal(t, "Fork of Fix the tests", fork.Title)
+	require.Equal(t, sess.ID, fork.ForkedFromSessionID)
+	require.Equal(t, int64(120), fork.PromptTokens)
+	require.Equal(t, int64(2), fork.MessageCount)
+
+	forked, err := app.Messages.List(ctx, fork.ID)
+	require.NoError(t, err)
+	require.Len(t, forked, 2)
+	for i, msg := range forked {
+		require.NotEqual(t, msgs[i].ID, msg.ID)
+		require.Equal(t, fork.ID, msg.SessionID)
+		require.Equal(t, msgs[i].Role, msg.Role)
+		require.Equal(t, msgs[i].Model, msg.Model)
+		require.Equal(t, msgs[i].Parts, msg.Parts)
+		require.Equal(t, msgs[i].CreatedAt, msg.CreatedAt)
+	}
+	require.Equal(t, forked[1].ID, fork.SummaryMessageID)
+
+	original, err := app.History.ListBySession(ctx, sess.ID)
+	require.NoError(t, err)
+	versions, err := app.History.ListBySession(ctx, fork.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	require.True(t, versions[0].IsNew)
+	for i, version := range versions {
+		require.Equal(t, original[i].Path, version.Path)
+		require.Equal(t, original[i].Content, version.Content)
+		require.Equal(t, original[i].Version, version.Version)
+		require.Equal(t, original[i].IsNew, version.IsNew)
+		require.Equal(t, original[i].CreatedAt, version.CreatedAt)
+	}
+
+	// The original session is left alone.
+	left, err := app.Messages.List(ctx, sess.ID)
+	require.NoError(t, err)
+	require.Len(t, left, 3)
+}
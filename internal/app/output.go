@@ -0,0 +1,304 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/usage"
+)
+
+// OutputFormat is the format used by non-interactive runs to report what the
+// agent did.
+type OutputFormat string
+
+const (
+	// OutputFormatText prints the assistant's response as plain text.
+	OutputFormatText OutputFormat = "text"
+	// OutputFormatJSON prints a single JSON document once the run is over.
+	OutputFormatJSON OutputFormat = "json"
+	// OutputFormatStreamJSON prints one JSON record per line as the run
+	// progresses.
+	OutputFormatStreamJSON OutputFormat = "stream-json"
+)
+
+// OutputFormats lists all supported output formats.
+var OutputFormats = []OutputFormat{
+	OutputFormatText,
+	OutputFormatJSON,
+	OutputFormatStreamJSON,
+}
+
+// IsValid reports whether f is a supported output format.
+func (f OutputFormat) IsValid() bool {
+	switch f {
+	case OutputFormatText, OutputFormatJSON, OutputFormatStreamJSON:
+		return true
+	}
+	return false
+}
+
+// Record types emitted by the structured output formats.
+const (
+	RecordTypeMessage    = "message"
+	RecordTypeToolCall   = "tool_call"
+	RecordTypeToolResult = "tool_result"
+	RecordTypeAgentEvent = "agent_event"
+	RecordTypeResult     = "result"
+)
+
+type messageRecord struct {
+	Type         string `json:"type"`
+	SessionID    string `json:"session_id"`
+	MessageID    string `json:"message_id"`
+	Role         string `json:"role"`
+	Model        string `json:"model,omitempty"`
+	Provider     string `json:"provider,omitempty"`
+	Text         string `json:"text,omitempty"`
+	Reasoning    string `json:"reasoning,omitempty"`
+	FinishReason string `json:"finish_reason,omitempty"`
+}
+
+type toolCallRecord struct {
+	Type      string `json:"type"`
+	SessionID string `json:"session_id"`
+	MessageID string `json:"message_id"`
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Input     string `json:"input"`
+}
+
+type toolResultRecord struct {
+	Type       string `json:"type"`
+	SessionID  string `json:"session_id"`
+	MessageID  string `json:"message_id"`
+	ToolCallID string `json:"tool_call_id"`
+	Name       string `json:"name,omitempty"`
+	Content    string `json:"content"`
+	Metadata   string `json:"metadata,omitempty"`
+	IsError    bool   `json:"is_error"`
+}
+
+type agentEventRecord struct {
+	Type      string `json:"type"`
+	SessionID string `json:"session_id"`
+	Event     string `json:"event"`
+	MessageID string `json:"message_id,omitempty"`
+	Progress  string `json:"progress,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type resultRecord struct {
+	Type             string  `json:"type"`
+	SessionID        string  `json:"session_id"`
+	IsError          bool    `json:"is_error"`
+	Error            string  `json:"error,omitempty"`
+	FinishReason     string  `json:"finish_reason,omitempty"`
+	Text             string  `json:"text"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	DurationMS       int64   `json:"duration_ms"`
+}
+
+// runOutput writes what a non-interactive run does. In text mode it prints the
+// assistant's responses as they stream in; otherwise it writes structured
+// records. Message records are deduplicated, so the same message may be
+// handled several times as it gets updated.
+type runOutput struct {
+	format    OutputFormat
+	w         io.Writer
+	enc       *json.Encoder
+	sessionID string
+	startedAt time.Time
+
+	// seen holds message IDs that existed before the run started, and the
+	// keys of the records that were already emitted.
+	seen    map[string]bool
+	records []any
+
+	// printed holds how much of each message was printed in text mode.
+	printed map[string]int
+}
+
+func newRunOutput(w io.Writer, format OutputFormat, sessionID string, existing []message.Message) *runOutput {
+	o := &runOutput{
+		format:    format,
+		w:         w,
+		enc:       json.NewEncoder(w),
+		sessionID: sessionID,
+		startedAt: time.Now(),
+		seen:      make(map[string]bool),
+		printed:   make(map[string]int),
+	}
+	for _, msg := range existing {
+		o.seen["msg:"+msg.ID] = true
+		for _, tc := range msg.ToolCalls() {
+			o.seen["call:"+tc.ID] = true
+		}
+		for _, tr := range msg.ToolResults() {
+			o.seen["result:"+tr.ToolCallID] = true
+		}
+	}
+	return o
+}
+
+func (o *runOutput) emit(key string, record any) error {
+	if o.seen[key] {
+		return nil
+	}
+	o.seen[key] = true
+	if o.format == OutputFormatStreamJSON {
+		return o.enc.Encode(record)
+	}
+	o.records = append(o.records, record)
+	return nil
+}
+
+// handleMessage emits the records for the finished parts of msg, or prints
+// what is new in it in text mode.
+func (o *runOutput) handleMessage(msg message.Message) error {
+	if msg.SessionID != o.sessionID {
+		return nil
+	}
+	if o.format == OutputFormatText {
+		if msg.Role != message.Assistant || o.seen["msg:"+msg.ID] {
+			return nil
+		}
+		return o.printText(msg)
+	}
+	for _, tc := range msg.ToolCalls() {
+		if !tc.Finished {
+			continue
+		}
+		if err := o.emit("call:"+tc.ID, toolCallRecord{
+			Type:      RecordTypeToolCall,
+			SessionID: msg.SessionID,
+			MessageID: msg.ID,
+			ID:        tc.ID,
+			Name:      tc.Name,
+			Input:     tc.Input,
+		}); err != nil {
+			return err
+		}
+	}
+	for _, tr := range msg.ToolResults() {
+		if err := o.emit("result:"+tr.ToolCallID, toolResultRecord{
+			Type:       RecordTypeToolResult,
+			SessionID:  msg.SessionID,
+			MessageID:  msg.ID,
+			ToolCallID: tr.ToolCallID,
+			Name:       tr.Name,
+			Content:    tr.Content,
+			Metadata:   tr.Metadata,
+			IsError:    tr.IsError,
+		}); err != nil {
+			return err
+		}
+	}
+	// User messages are complete as soon as they are created.
+	if msg.Role == message.Tool || (msg.Role == message.Assistant && !msg.IsFinished()) {
+		return nil
+	}
+	return o.emit("msg:"+msg.ID, messageRecord{
+		Type:         RecordTypeMessage,
+		SessionID:    msg.SessionID,
+		MessageID:    msg.ID,
+		Role:         string(msg.Role),
+		Model:        msg.Model,
+		Provider:     msg.Provider,
+		Text:         msg.Content().Text,
+		Reasoning:    msg.ReasoningContent().Thinking,
+		FinishReason: string(msg.FinishReason()),
+	})
+}
+
+// printText prints the part of the content of msg that wasn't printed yet.
+func (o *runOutput) printText(msg message.Message) error {
+	content := msg.Content().String()
+	printed := o.printed[msg.ID]
+	if len(content) < printed {
+		return fmt.Errorf("message content is shorter than printed bytes: %d < %d", len(content), printed)
+	}
+	o.printed[msg.ID] = len(content)
+	_, err := io.WriteString(o.w, content[printed:])
+	return err
+}
+
+// handleAgentEvent emits a record for an event produced by the agent. Events
+// of other sessions, like the summaries of the sessions they replace, are
+// skipped.
+func (o *runOutput) handleAgentEvent(event agent.AgentEvent) error {
+	if o.format == OutputFormatText {
+		return nil
+	}
+	sessionID := event.SessionID
+	if sessionID == "" {
+		sessionID = event.Message.SessionID
+	}
+	if sessionID != "" && sessionID != o.sessionID {
+		return nil
+	}
+	record := agentEventRecord{
+		Type:      RecordTypeAgentEvent,
+		SessionID: o.sessionID,
+		Event:     string(event.Type),
+		MessageID: event.Message.ID,
+		Progress:  event.Progress,
+	}
+	if event.Error != nil {
+		record.Error = event.Error.Error()
+	}
+	if o.format == OutputFormatStreamJSON {
+		return o.enc.Encode(record)
+	}
+	o.records = append(o.records, record)
+	return nil
+}
+
+// finish emits the final record with what the run spent. In JSON mode it also
+// writes the whole document. In text mode it prints the rest of the response,
+// if the run succeeded.
+func (o *runOutput) finish(spent usage.SessionTotal, result agent.AgentEvent, runErr error) error {
+	if o.format == OutputFormatText {
+		if result.Error != nil {
+			return nil
+		}
+		if err := o.printText(result.Message); err != nil {
+			return err
+		}
+		_, err := io.WriteString(o.w, "\n")
+		return err
+	}
+
+	record := resultRecord{
+		Type:             RecordTypeResult,
+		SessionID:        o.sessionID,
+		FinishReason:     string(result.Message.FinishReason()),
+		Text:             result.Message.Content().Text,
+		PromptTokens:     spent.PromptTokens,
+		CompletionTokens: spent.CompletionTokens,
+		Cost:             spent.Cost,
+		DurationMS:       time.Since(o.startedAt).Milliseconds(),
+	}
+	if runErr != nil {
+		record.IsError = true
+		record.Error = runErr.Error()
+	}
+
+	if o.format == OutputFormatStreamJSON {
+		return o.enc.Encode(record)
+	}
+	records := o.records
+	if records == nil {
+		records = []any{}
+	}
+	o.enc.SetIndent("", "  ")
+	return o.enc.Encode(struct {
+		resultRecord
+		Events []any `json:"events"`
+	}{record, records})
+}
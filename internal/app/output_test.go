@@ -0,0 +1,202 @@
+package app
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/usage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunOutput(t *testing.T) {
+	t.Parallel()
+
+	existing := []message.Message{{
+		ID:        "old",
+		SessionID: "session",
+		Role:      message.Assistant,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "Earlier answer"},
+			message.Finish{Reason: message.FinishReasonEndTurn},
+		},
+	}}
+	partial := message.Message{
+		ID:        "a1",
+		SessionID: "session",
+		Role:      message.Assistant,
+		Model:     "gpt-4o",
+		Parts:     []message.ContentPart{message.TextContent{Text: "Run"}},
+	}
+	final := message.Message{
+		ID:        "a2",
+		SessionID: "session",
+		Role:      message.Assistant,
+		Model:     "gpt-4o",
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "Done"},
+			message.Finish{Reason: message.FinishReasonEndTurn},
+		},
+	}
+	updates := []message.Message{
+		existing[0],
+		{
+			ID:        "u1",
+			SessionID: "session",
+			Role:      message.User,
+			Parts:     []message.ContentPart{message.TextContent{Text: "Run the tests"}},
+		},
+		partial,
+		{
+			ID:        "a1",
+			SessionID: "session",
+			Role:      message.Assistant,
+			Model:     "gpt-4o",
+			Parts: []message.ContentPart{
+				message.TextContent{Text: "Running the tests. "},
+				message.ToolCall{ID: "call", Name: "bash", Input: `{"command":"go test"}`, Finished: true},
+				message.Finish{Reason: message.FinishReasonToolUse},
+			},
+		},
+		{
+			ID:        "t1",
+			SessionID: "session",
+			Role:      message.Tool,
+			Parts: []message.ContentPart{
+				message.ToolResult{ToolCallID: "call", Name: "bash", Content: "ok"},
+			},
+		},
+		{
+			ID:        "other",
+			SessionID: "task",
+			Role:      message.Assistant,
+			Parts:     []message.ContentPart{message.TextContent{Text: "Not this session"}},
+		},
+		final,
+		final,
+	}
+	events := []agent.AgentEvent{
+		{Type: agent.AgentEventTypeBudgetWarning, SessionID: "session", Progress: "This prompt made 8 of its 10 turn limit"},
+		{Type: agent.AgentEventTypeSummarize, SessionID: "other", Progress: "Summarizing session..."},
+	}
+	spent := usage.SessionTotal{AgentRequests: 2, Tokens: 150, PromptTokens: 120, CompletionTokens: 30, Cost: 0.01}
+	result := agent.AgentEvent{Type: agent.AgentEventTypeResponse, Message: final}
+
+	for _, tt := range []struct {
+		format OutputFormat
+		want   string
+	}{
+		{
+			format: OutputFormatText,
+			want:   "Running the tests. Done\n",
+		},
+		{
+			format: OutputFormatStreamJSON,
+			want: `{"type":"message","session_id":"session","message_id":"u1","role":"user","text":"Run the tests"}
+{"type":"tool_call","session_id":"session","message_id":"a1","id":"call","name":"bash","input":"{\"command\":\"go test\"}"}
+{"type":"message","session_id":"session","message_id":"a1","role":"assistant","model":"gpt-4o","text":"Running the tests. ","finish_reason":"tool_use"}
+{"type":"tool_result","session_id":"session","message_id":"t1","tool_call_id":"call","name":"bash","content":"ok","is_error":false}
+{"type":"agent_event","session_id":"session","event":"budget_warning","progress":"This prompt made 8 of its 10 turn limit"}
+{"type":"message","session_id":"session","message_id":"a2","role":"assistant","model":"gpt-4o","text":"Done","finish_reason":"end_turn"}
+{"type":"agent_event","session_id":"session","event":"response","message_id":"a2"}
+{"type":"result","session_id":"session","is_error":false,"finish_reason":"end_turn","text":"Done","prompt_tokens":120,"completion_tokens":30,"cost":0.01,"duration_ms":0}
+`,
+		},
+		{
+			format: OutputFormatJSON,
+			want: `{
+  "type": "result",
+  "session_id": "session",
+  "is_error": false,
+  "finish_reason": "end_turn",
+  "text": "Done",
+  "prompt_tokens": 120,
+  "completion_tokens": 30,
+  "cost": 0.01,
+  "duration_ms": 0,
+  "events": [
+    {
+      "type": "message",
+      "session_id": "session",
+      "message_id": "u1",
+      "role": "user",
+      "text": "Run the tests"
+    },
+    {
+      "type": "tool_call",
+      "session_id": "session",
+      "message_id": "a1",
+      "id": "call",
+      "name": "bash",
+      "input": "{\"command\":\"go test\"}"
+    },
+    {
+      "type": "message",
+      "session_id": "session",
+      "message_id": "a1",
+      "role": "assistant",
+      "model": "gpt-4o",
+      "text": "Running the tests. ",
+      "finish_reason": "tool_use"
+    },
+    {
+      "type": "tool_result",
+      "session_id": "session",
+      "message_id": "t1",
+      "tool_call_id": "call",
+      "name": "bash",
+      "content": "ok",
+      "is_error": false
+    },
+    {
+      "type": "agent_event",
+      "session_id": "session",
+      "event": "budget_warning",
+      "progress": "This prompt made 8 of its 10 turn limit"
+    },
+    {
+      "type": "message",
+      "session_id": "session",
+      "message_id": "a2",
+      "role": "assistant",
+      "model": "gpt-4o",
+      "text": "Done",
+      "finish_reason": "end_turn"
+    },
+    {
+      "type": "agent_event",
+      "session_id": "session",
+      "event": "response",
+      "message_id": "a2"
+    }
+  ]
+}
+`,
+		},
+	} {
+		t.Run(string(tt.format), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			output := newRunOutput(&buf, tt.format, "session", existing)
+			for _, msg := range updates[:len(updates)-2] {
+				require.NoError(t, output.handleMessage(msg))
+			}
+			for _, event := range events {
+				require.NoError(t, output.handleAgentEvent(event))
+			}
+			for _, msg := range updates[len(updates)-2:] {
+				require.NoError(t, output.handleMessage(msg))
+			}
+			require.NoError(t, output.handleAgentEvent(result))
+			require.NoError(t, output.finish(spent, result, nil))
+
+			got := regexp.MustCompile(`"duration_ms": ?\d+`).ReplaceAllStringFunc(buf.String(), func(s string) string {
+				return regexp.MustCompile(`\d+`).ReplaceAllString(s, "0")
+			})
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// FileRestore is a file that a rewind changes on disk.
+type FileRestore struct {
+	Path string
+	// Current is the content of the file on disk.
+	Current string
+	// Content is what the file is restored to.
+	Content string
+	// Delete is set for files created after the rewind point, which are
+	// removed instead of restored.
+	Delete bool
+}
+
+// Rewind describes what rewinding a session to one of its user messages
+// changes: that message and everything after it are dropped from the
+// conversation, and the files the agent touched since are restored to their
+// prior version.
+type Rewind struct {
+	SessionID string
+	// Message is the user message the session is rewound to.
+	Message message.Message
+	// Files are the files whose content on disk changes.
+	Files []FileRestore
+	// Messages are the messages dropped from the conversation.
+	Messages []message.Message
+
+	// kept are the messages before the rewind point.
+	kept []message.Message
+	// keptVersions and droppedVersions are the file versions recorded
+	// before and after the rewind point.
+	keptVersions    []history.File
+	droppedVersions []history.File
+}
+
+// PlanRewind works out what rewinding the session to the given user message
+// changes, without changing anything.
+func (app *App) PlanRewind(ctx context.Context, sessionID, messageID string) (Rewind, error) {
+	msgs, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return Rewind{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	idx := slices.IndexFunc(msgs, func(msg message.Message) bool {
+		return msg.ID == messageID
+	})
+	if idx == -1 {
+		return Rewind{}, fmt.Errorf("message %s not found in session", messageID)
+	}
+	if msgs[idx].Role != message.User {
+		return Rewind{}, errors.New("can only rewind to a user message")
+	}
+
+	versions, err := app.History.ListBySession(ctx, sessionID)
+	if err != nil {
+		return Rewind{}, fmt.Errorf("failed to list file history: %w", err)
+	}
+
+	r := Rewind{
+		SessionID: sessionID,
+		Message:   msgs[idx],
+		Messages:  msgs[idx:],
+		kept:      msgs[:idx],
+	}
+	// Versions don't record which message they belong to, so the ones
+	// recorded until the last update of the kept messages are kept, like
+	// when forking.
+	cutoff := int64(-1)
+	for _, msg := range r.kept {
+		cutoff = max(cutoff, msg.UpdatedAt)
+	}
+	var restores []FileRestore
+	r.keptVersions, r.droppedVersions, restores = fileRestores(versions, cutoff)
+	for _, restore := range restores {
+		current, err := os.ReadFile(restore.Path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			if restore.Delete {
+				continue
+			}
+		case err != nil:
+			return Rewind{}, fmt.Errorf("failed to read %s: %w", restore.Path, err)
+		case !restore.Delete && string(current) == restore.Content:
+			continue
+		}
+		restore.Current = string(current)
+		r.Files = append(r.Files, restore)
+	}
+	return r, nil
+}
+
+// fileRestores splits the file versions of a session into the ones recorded
+// until cutoff and after it, and works out what each file touched after it
+// goes back to: its last version until the cutoff or, for files first touched
+// after it, the content they had then. Files created after the cutoff are
+// deleted.
+func fileRestores(versions []history.File, cutoff int64) (kept, dropped []history.File, restores []FileRestore) {
+	for _, version := range versions {
+		if version.CreatedAt <= cutoff {
+			kept = append(kept, version)
+		} else {
+			dropped = append(dropped, version)
+		}
+	}
+
+	for _, version := range dropped {
+		if slices.ContainsFunc(restores, func(r FileRestore) bool {
+			return r.Path == version.Path
+		}) {
+			continue
+		}
+		restore := FileRestore{
+			Path:    version.Path,
+			Content: version.Content,
+			Delete:  version.IsNew,
+		}
+		for _, k := range kept {
+			if k.Path == version.Path {
+				restore.Content = k.Content
+				restore.Delete = false
+			}
+		}
+		restores = append(restores, restore)
+	}
+	return kept, dropped, restores
+}
+
+// ApplyRewind restores the files of a planned rewind. Unless fork is set, the
+// session itself is truncated to the messages before the rewind point;
+// otherwise those messages are copied into a new session and the original one
+// is left alone. It returns the session to continue in.
+func (app *App) ApplyRewind(ctx context.Context, r Rewind, fork bool) (session.Session, error) {
+	if app.CoderAgent != nil && app.CoderAgent.IsSessionBusy(r.SessionID) {
+		return session.Session{}, errors.New("session is busy, cancel the current request first")
+	}
+	sess, err := app.Sessions.Get(ctx, r.SessionID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Check every file first so a refused rewind changes nothing.
+	for _, restore := range r.Files {
+		if err := checkRestore(app.config.WorkingDir(), restore); err != nil {
+			return session.Session{}, err
+		}
+	}
+	for _, restore := range r.Files {
+		if err := restoreFile(restore); err != nil {
+			return session.Session{}, err
+		}
+	}
+
+	if fork {
+		return app.forkSession(ctx, sess, r.kept, r.keptVersions)
+	}
+
+	for _, msg := range r.Messages {
+		if err := app.Messages.Delete(ctx, msg.ID); err != nil {
+			return session.Session{}, fmt.Errorf("failed to delete message: %w", err)
+		}
+	}
+	for _, version := range r.droppedVersions {
+		if err := app.History.Delete(ctx, version.ID); err != nil {
+			return session.Session{}, fmt.Errorf("failed to delete file version: %w", err)
+		}
+	}
+	if slices.ContainsFunc(r.Messages, func(msg message.Message) bool {
+		return msg.ID == sess.SummaryMessageID
+	}) {
+		sess.SummaryMessageID = ""
+		if sess, err = app.Sessions.Save(ctx, sess); err != nil {
+			return session.Session{}, fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+	slog.Info("Rewound session", "session_id", sess.ID, "messages", len(r.Messages), "files", len(r.Files))
+	return sess, nil
+}
+
+// checkRestore refuses to restore files outside of the working directory.
+func checkRestore(workingDir string, restore FileRestore) error {
+	if workingDir == "" || !filepath.IsAbs(restore.Path) || !fsext.HasPrefix(restore.Path, workingDir) {
+		return fmt.Errorf("refusing to restore %s, it is outside of the working directory", restore.Path)
+	}
+	return nil
+}
+
+func restoreFile(restore FileRestore) error {
+	if restore.Delete {
+		if err := os.Remove(restore.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", restore.Path, err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(restore.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", restore.Path, err)
+	}
+	if err := os.WriteFile(restore.Path, []byte(restore.Content), 0o644); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", restore.Path, err)
+	}
+	return nil
+}
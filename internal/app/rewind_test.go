@@ -0,0 +1,71 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRestores(t *testing.T) {
+	t.Parallel()
+
+	versions := []history.File{
+		{Path: "/a.go", Content: "a0", Version: 0, CreatedAt: 10},
+		// Recorded in the same second as the last kept message.
+		{Path: "/a.go", Content: "a1", Version: 1, CreatedAt: 20},
+		{Path: "/a.go", Content: "a2", Version: 2, CreatedAt: 30},
+		{Path: "/b.go", Content: "b0", Version: 0, CreatedAt: 30},
+		{Path: "/b.go", Content: "b1", Version: 1, CreatedAt: 30},
+		{Path: "/c.go", Content: "", Version: 0, CreatedAt: 40, IsNew: true},
+		{Path: "/c.go", Content: "c1", Version: 1, CreatedAt: 40},
+		{Path: "/d.go", Content: "d0", Version: 0, CreatedAt: 5},
+		// An empty file that existed is emptied again, not deleted.
+		{Path: "/e.go", Content: "", Version: 0, CreatedAt: 30},
+		{Path: "/e.go", Content: "e1", Version: 1, CreatedAt: 30},
+	}
+
+	kept, dropped, restores := fileRestores(versions, 20)
+	require.Len(t, kept, 3)
+	require.Len(t, dropped, 7)
+	require.Equal(t, []FileRestore{
+		{Path: "/a.go", Content: "a1"},
+		{Path: "/b.go", Content: "b0"},
+		{Path: "/c.go", Delete: true},
+		{Path: "/e.go"},
+	}, restores)
+
+	_, dropped, restores = fileRestores(versions, 40)
+	require.Empty(t, dropped)
+	require.Empty(t, restores)
+
+	// Rewinding to the first message drops every version.
+	kept, _, _ = fileRestores(versions, -1)
+	require.Empty(t, kept)
+}
+
+func TestCheckRestore(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		path    string
+		refused bool
+	}{
+		{path: "/project/main.go"},
+		{path: "/project/internal/app/app.go"},
+		{path: "/etc/passwd", refused: true},
+		{path: "/project-other/main.go", refused: true},
+		{path: "/project/../etc/passwd", refused: true},
+		{path: "main.go", refused: true},
+	} {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+			err := checkRestore("/project", FileRestore{Path: tt.path})
+			if tt.refused {
+				require.EqualError(t, err, "refusing to restore "+tt.path+", it is outside of the working directory")
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/spf13/cobra"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <prompts.jsonl>",
+	Short: "Run many prompts non-interactively",
+	Long: `Run every prompt of a JSON lines file in its own session, and write the
+result of each one as a JSON line once it's done.
+
+Each line of the file is an object with a "prompt", an optional "id" used to
+identify it in the results, and an optional "dir" the prompt works in,
+relative to the working directory, e.g.
+{"id": "PROJ-12", "dir": "services/api", "prompt": "..."}. Every prompt runs
+its commands in a shell of its own. Read the prompts from stdin with "-".
+
+Prompts only get read-only tools unless others are allowed with --allow-tool
+or --approve-all. The command fails if any prompt failed.`,
+	Example: `
+# Run the prompts four at a time
+crush batch prompts.jsonl --parallel 4 -o results.jsonl
+
+# Let the prompts edit files and run commands, but nothing else
+crush batch prompts.jsonl --allow-tool edit --allow-tool write --allow-tool bash
+
+# Approve everything but fetching from the network
+crush batch prompts.jsonl --approve-all --deny-tool fetch
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		outputPath, _ := cmd.Flags().GetString("output")
+		approveAll, _ := cmd.Flags().GetBool("approve-all")
+		if parallel < 1 {
+			return fmt.Errorf("invalid parallelism %d: must be at least 1", parallel)
+		}
+
+		var r io.Reader = cmd.InOrStdin()
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open prompts: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+		prompts, err := app.ReadBatchPrompts(r)
+		if err != nil {
+			return fmt.Errorf("failed to read prompts: %w", err)
+		}
+		if len(prompts) == 0 {
+			return fmt.Errorf("no prompts provided")
+		}
+
+		policy, err := policyFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		opts := app.BatchOptions{
+			Parallel: parallel,
+			Policy:   batchPolicy(policy, approveAll),
+			Budget:   budgetFromFlags(cmd),
+		}
+
+		w := cmd.OutOrStdout()
+		if outputPath != "" {
+			f, err := os.Create(outputPath)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		appInstance, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer appInstance.Shutdown()
+
+		if !appInstance.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
+		results, err := appInstance.RunBatch(cmd.Context(), prompts, opts, w)
+
+		var failed int
+		var cost float64
+		for _, r := range results {
+			if r.IsError {
+				failed++
+			}
+			cost += r.Cost
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "%d prompts, %d failed, cost $%.2f\n", len(results), failed, cost)
+
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d prompts failed", failed, len(results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	batchCmd.Flags().IntP("parallel", "p", 1, "Number of prompts to run at the same time")
+	batchCmd.Flags().StringP("output", "o", "", "Write the results to a file instead of stdout")
+	batchCmd.Flags().Bool("approve-all", false, "Approve every permission request that isn't denied with --deny-tool")
+	addPolicyFlags(batchCmd)
+	addBudgetFlags(batchCmd)
+	batchCmd.MarkFlagsMutuallyExclusive("approve-all", "read-only")
+	batchCmd.MarkFlagsMutuallyExclusive("approve-all", "allow-tool")
+	rootCmd.AddCommand(batchCmd)
+}
+
+// batchPolicy returns the policy the prompts of a batch are decided with.
+// There is nobody to answer permission prompts, so unless approveAll is set
+// only the read-only tools and the tools explicitly allowed are available.
+func batchPolicy(policy *permission.Policy, approveAll bool) *permission.Policy {
+	if approveAll {
+		return policy
+	}
+	if policy == nil {
+		policy = &permission.Policy{}
+	}
+	if len(policy.OnlyTools) == 0 && len(policy.AllowedTools) == 0 {
+		policy.OnlyTools = append(config.ReadOnlyTools(), agent.AgentToolName)
+	}
+	return policy
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name       string
+		policy     *permission.Policy
+		approveAll bool
+		tools      map[string]bool
+	}{
+		{
+			name:   "no flags",
+			policy: nil,
+			tools:  map[string]bool{"view": true, "agent": true, "edit": false, "bash": false},
+		},
+		{
+			name:   "denied tools",
+			policy: &permission.Policy{DeniedTools: []string{"fetch"}},
+			tools:  map[string]bool{"view": true, "fetch": false, "write": false, "bash": false},
+		},
+		{
+			name:   "allowed paths",
+			policy: &permission.Policy{AllowedPaths: []string{"/tmp/shared"}},
+			tools:  map[string]bool{"view": true, "write": false, "bash": false},
+		},
+		{
+			name:   "allowed tools",
+			policy: &permission.Policy{AllowedTools: []string{"edit", "bash"}},
+			tools:  map[string]bool{"view": true, "edit": true, "bash": true},
+		},
+		{
+			name:       "approve all",
+			policy:     &permission.Policy{DeniedTools: []string{"fetch"}},
+			approveAll: true,
+			tools:      map[string]bool{"view": true, "fetch": false, "write": true, "bash": true},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			policy := batchPolicy(tt.policy, tt.approveAll)
+			for tool, allowed := range tt.tools {
+				require.Equal(t, allowed, policy.CheckTool(tool) == nil, tool)
+			}
+		})
+	}
+
+	require.Nil(t, batchPolicy(nil, true))
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and validate the configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [key-prefix]",
+	Short: "Show the effective configuration",
+	Long: `Show every value of the effective configuration along with where it came
+from: the config file and line that set it, the provider catalog, or the
+defaults. Values read from environment variables name the variables.
+
+Secrets like API keys and headers are redacted.`,
+	Example: `
+# Show the whole configuration
+crush config show
+
+# Show why a model is selected
+crush config show models
+
+# Output as JSON
+crush config show --json
+  `,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		debug, _ := cmd.Flags().GetBool("debug")
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Init(cwd, dataDir, debug)
+		if err != nil {
+			return err
+		}
+
+		settings, err := cfg.Settings()
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			settings = filterSettings(settings, args[0])
+		}
+
+		if asJSON {
+			if settings == nil {
+				settings = []config.Setting{}
+			}
+			return printJSON(cmd.OutOrStdout(), settings)
+		}
+		return printSettings(cmd.OutOrStdout(), settings)
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]...",
+	Short: "Validate config files against the schema",
+	Long: `Validate config files against the configuration schema, reporting every
+problem with its line and column.
+
+Without arguments, all the config files that apply to the working directory
+are validated.`,
+	Example: `
+# Validate the config files of the current project
+crush config validate
+
+# Validate a specific file
+crush config validate ~/.config/crush/crush.json
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := args
+		if len(paths) == 0 {
+			cwd, err := ResolveCwd(cmd)
+			if err != nil {
+				return err
+			}
+			for _, path := range config.ConfigPaths(cwd) {
+				if _, err := os.Stat(path); err == nil {
+					paths = append(paths, path)
+				}
+			}
+			if len(paths) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No config files found.")
+				return nil
+			}
+		}
+
+		w := cmd.OutOrStdout()
+		var problems int
+		for _, path := range paths {
+			errs, err := config.ValidateFile(path)
+			if err != nil {
+				return err
+			}
+			if len(errs) == 0 {
+				fmt.Fprintf(w, "%s: ok\n", path)
+				continue
+			}
+			for _, e := range errs {
+				fmt.Fprintf(w, "%s:%s\n", path, e.Error())
+			}
+			problems += len(errs)
+		}
+		if problems > 0 {
+			return fmt.Errorf("found %d problem(s) in config files", problems)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configShowCmd.Flags().Bool("json", false, "Output as JSON")
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// filterSettings keeps the settings whose key is prefix or starts with
+// prefix followed by a dot.
+func filterSettings(settings []config.Setting, prefix string) []config.Setting {
+	prefix = strings.TrimSuffix(prefix, ".")
+	var filtered []config.Setting
+	for _, s := range settings {
+		if s.Key == prefix || strings.HasPrefix(s.Key, prefix+".") {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+func printSettings(w io.Writer, settings []config.Setting) error {
+	if len(settings) == 0 {
+		return errors.New("no matching configuration keys")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, formatSettingValue(s.Value), s.Source)
+	}
+	return tw.Flush()
+}
+
+// formatSettingValue renders a value on a single line. Lists of objects,
+// like the models of a provider, are summarized.
+func formatSettingValue(v any) string {
+	if items, ok := v.([]any); ok && len(items) > 0 {
+		if _, ok := items[0].(map[string]any); ok {
+			return fmt.Sprintf("[%d items]", len(items))
+		}
+	}
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return truncate(string(bts), 80)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/doctor"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/exp/charmtone"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment for problems",
+	Long: `Check that Crush can work in the current environment: config files, the
+data directory and database, ripgrep, provider credentials, and every
+configured LSP and MCP server.
+
+Provider credentials are checked with a cheap request to each provider, and
+LSP and MCP servers are started and stopped again. Exits with a nonzero status
+when a check fails.`,
+	Example: `
+# Check the current project
+crush doctor
+
+# Check another project, with a shorter LSP startup timeout
+crush doctor -c /path/to/project --lsp-timeout 10s
+
+# Output as JSON
+crush doctor --json
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		lspTimeout, _ := cmd.Flags().GetDuration("lsp-timeout")
+		debug, _ := cmd.Flags().GetBool("debug")
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Init(cwd, dataDir, debug)
+		if err != nil {
+			return err
+		}
+
+		report := doctor.Run(cmd.Context(), cfg, doctor.Options{LSPTimeout: lspTimeout})
+
+		if asJSON {
+			if err := printJSON(cmd.OutOrStdout(), report); err != nil {
+				return err
+			}
+		} else {
+			printReport(cmd.OutOrStdout(), report)
+		}
+
+		if report.Failed() {
+			var failed int
+			for _, r := range report.Results {
+				if r.Status == doctor.StatusError {
+					failed++
+				}
+			}
+			return fmt.Errorf("%d check(s) failed", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().Bool("json", false, "Output as JSON")
+	doctorCmd.Flags().Duration("lsp-timeout", 0, "Startup timeout of each LSP server (default 30s)")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+var categoryTitles = map[doctor.Category]string{
+	doctor.CategoryConfig:    "Config files",
+	doctor.CategoryDataDir:   "Data directory",
+	doctor.CategoryDatabase:  "Database",
+	doctor.CategoryRipgrep:   "Ripgrep",
+	doctor.CategoryProviders: "Providers",
+	doctor.CategoryLSP:       "LSP servers",
+	doctor.CategoryMCP:       "MCP servers",
+}
+
+func printReport(w io.Writer, report doctor.Report) {
+	var (
+		titleStyle = lipgloss.NewStyle().Bold(true)
+		mutedStyle = lipgloss.NewStyle().Foreground(charmtone.Squid)
+		statuses   = map[doctor.Status]lipgloss.Style{
+			doctor.StatusOK:      lipgloss.NewStyle().Foreground(charmtone.Guac).SetString("✓"),
+			doctor.StatusWarning: lipgloss.NewStyle().Foreground(charmtone.Zest).SetString("!"),
+			doctor.StatusError:   lipgloss.NewStyle().Foreground(charmtone.Sriracha).SetString("✗"),
+			doctor.StatusSkipped: mutedStyle.SetString("-"),
+		}
+	)
+
+	var category doctor.Category
+	for _, r := range report.Results {
+		if r.Category != category {
+			if category != "" {
+				fmt.Fprintln(w)
+			}
+			category = r.Category
+			fmt.Fprintln(w, titleStyle.Render(categoryTitles[category]))
+		}
+		fmt.Fprintf(w, "  %s %s: %s\n", statuses[r.Status].Render(), r.Name, r.Message)
+		if r.Hint != "" {
+			fmt.Fprintf(w, "    %s\n", mutedStyle.Render("→ "+r.Hint))
+		}
+	}
+}
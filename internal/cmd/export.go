@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/transcript"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <session>",
+	Short: "Export a session transcript",
+	Long: `Export a session with its messages, tool calls and results, file diffs,
+task sessions and costs as Markdown, a standalone HTML page or JSON.
+
+The JSON format includes the file history of the session, and can be imported
+into another project with crush import.`,
+	Example: `
+# Print a session as Markdown (an unambiguous ID prefix is enough)
+crush export 3f2a
+
+# Save a session as a standalone HTML page, the format is taken from the extension
+crush export 3f2a -o transcript.html
+
+# Save a session as JSON
+crush export 3f2a --format json -o session.json
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		formatFlag, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		format := transcript.Format(formatFlag)
+		if format == "" {
+			format = transcript.FormatMarkdown
+			if f, ok := transcript.FormatFromPath(output); ok {
+				format = f
+			}
+		}
+		if !slices.Contains(transcript.Formats, format) {
+			return fmt.Errorf("invalid format %q, must be one of markdown, html or json", format)
+		}
+
+		cfg, conn, err := setupDB(cmd)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		q := db.New(conn)
+		sessions := session.NewService(q)
+		s, err := resolveSession(cmd.Context(), sessions, args[0])
+		if err != nil {
+			return err
+		}
+		t, err := transcript.Load(cmd.Context(), sessions, message.NewService(q), history.NewService(q, conn), cfg.WorkingDir(), s.ID)
+		if err != nil {
+			return err
+		}
+
+		if output == "" || output == "-" {
+			return transcript.Write(cmd.OutOrStdout(), t, format)
+		}
+		if err := transcript.WriteFile(output, t, format); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Exported session %s to %s\n", s.ID, output)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringP("format", "f", "", "Output format: markdown, html, json (default from the output file extension, or markdown)")
+	exportCmd.Flags().StringP("output", "o", "", "File to write to (default stdout)")
+	rootCmd.AddCommand(exportCmd)
+}
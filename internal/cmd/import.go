@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/transcript"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a session exported as JSON",
+	Long: `Import a session exported with crush export --format json, along with its
+messages, task sessions and file history, into the current project.
+
+The imported session gets new IDs, so the same file can be imported more than
+once. File paths under the directory the session was exported from are moved
+under the current project, including the ones in tool calls and attachments.
+The history of files outside that directory is left out.`,
+	Example: `
+# Import a session a teammate exported
+crush import session.json
+
+# Import from stdin
+crush export 3f2a -f json -c ../other-project | crush import -
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		var r io.Reader = cmd.InOrStdin()
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open transcript: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+		t, err := transcript.Read(r)
+		if err != nil {
+			return err
+		}
+
+		cfg, conn, err := setupDB(cmd)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		id, err := transcript.Import(cmd.Context(), conn, t, cfg.WorkingDir())
+		if err != nil {
+			return fmt.Errorf("failed to import session: %w", err)
+		}
+
+		if asJSON {
+			return printJSON(cmd.OutOrStdout(), map[string]string{"id": id, "title": t.Session.Title})
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Imported session %s (%s)\n", id, t.Session.Title)
+		fmt.Fprintf(cmd.ErrOrStderr(), "Continue it from the sessions dialog, or with: crush run --session %s <prompt>\n", id)
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().Bool("json", false, "Output as JSON")
+	rootCmd.AddCommand(importCmd)
+}
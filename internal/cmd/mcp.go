@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/mcpserver"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// mcpSessionTitle is the title of the session MCP tool calls run in.
+const mcpSessionTitle = "MCP server"
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Model Context Protocol utilities",
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve Crush's built-in tools over MCP on stdio",
+	Long: `Expose Crush's view, edit, multiedit, grep, glob, ls, bash and diagnostics
+tools to other MCP hosts over stdio.
+
+Only the read-only tools are served by default; allow the others with
+--allow-tool. Paths hidden by .crushignore can't be accessed, and are left out
+of search results. Bash commands naming them are refused, but scripts can
+still read them, so don't allow bash if that matters.
+
+File changes are recorded in the project's "MCP server" session, which is
+reused by every run.`,
+	Example: `
+# Serve the read-only tools of the current project
+crush mcp serve
+
+# Also allow editing files
+crush mcp serve --allow-tool edit --allow-tool multiedit
+
+# Serve the tools of another project, including bash
+crush mcp serve -c /path/to/project --allow-tool bash
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		policy, err := policyFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		policy = mcpPolicy(policy)
+
+		ctx := cmd.Context()
+		sess, err := mcpSession(ctx, app.Sessions)
+		if err != nil {
+			return err
+		}
+		app.Permissions.SetSessionPolicy(sess.ID, *policy)
+
+		cfg := app.Config()
+		var served []tools.BaseTool
+		for _, tool := range mcpserver.Tools(cfg, app.Permissions, app.History, app.LSPClients) {
+			if policy.CheckTool(tool.Name()) == nil {
+				served = append(served, tool)
+			}
+		}
+		srv := mcpserver.New(sess.ID, cfg.WorkingDir(), app.Permissions, served)
+		return srv.Serve(ctx, os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	addPolicyFlags(mcpServeCmd)
+	mcpCmd.AddCommand(mcpServeCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
+
+// mcpPolicy returns the policy MCP tool calls are decided with. There is
+// nobody to answer permission prompts on stdio, and the host may not ask the
+// user before calling a tool, so only the read-only tools and the tools
+// explicitly allowed are available unless the flags say otherwise.
+func mcpPolicy(policy *permission.Policy) *permission.Policy {
+	if policy == nil {
+		policy = &permission.Policy{}
+	}
+	if len(policy.OnlyTools) == 0 {
+		policy.OnlyTools = withAllowedTools(append(config.ReadOnlyTools(), tools.DiagnosticsToolName), policy.AllowedTools)
+	}
+	return policy
+}
+
+// mcpSession returns the session MCP tool calls run in, creating it the first
+// time the tools are served in the project.
+func mcpSession(ctx context.Context, sessions session.Service) (session.Session, error) {
+	all, err := sessions.List(ctx)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for _, sess := range all {
+		if sess.Title == mcpSessionTitle {
+			return sess, nil
+		}
+	}
+	sess, err := sessions.Create(ctx, mcpSessionTitle)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to create session: %w", err)
+	}
+	return sess, nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		policy *permission.Policy
+		tools  map[string]bool
+	}{
+		{
+			name:   "no flags",
+			policy: nil,
+			tools:  map[string]bool{"view": true, "grep": true, "diagnostics": true, "edit": false, "bash": false},
+		},
+		{
+			name:   "denied tools",
+			policy: &permission.Policy{DeniedTools: []string{"grep"}},
+			tools:  map[string]bool{"view": true, "grep": false, "edit": false, "bash": false},
+		},
+		{
+			name:   "allowed tools",
+			policy: &permission.Policy{AllowedTools: []string{"edit", "bash:execute"}},
+			tools:  map[string]bool{"view": true, "edit": true, "bash": true, "multiedit": false},
+		},
+		{
+			name:   "read-only",
+			policy: &permission.Policy{OnlyTools: []string{"view"}},
+			tools:  map[string]bool{"view": true, "grep": false},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			policy := mcpPolicy(tt.policy)
+			for tool, allowed := range tt.tools {
+				require.Equal(t, allowed, policy.CheckTool(tool) == nil, tool)
+			}
+		})
+	}
+}
+
+func TestMCPSession(t *testing.T) {
+	t.Parallel()
+
+	sessions := &fakeSessions{sessions: []session.Session{
+		{ID: "3f2a9c", Title: "Fix the tests"},
+	}}
+
+	first, err := mcpSession(t.Context(), sessions)
+	require.NoError(t, err)
+	require.Equal(t, mcpSessionTitle, first.Title)
+
+	// Later runs reuse the session.
+	second, err := mcpSession(t.Context(), sessions)
+	require.NoError(t, err)
+	require.Equal(t, first.ID, second.ID)
+	require.Len(t, sessions.sessions, 2)
+}
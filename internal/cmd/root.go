@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"log/slog"
@@ -107,17 +108,10 @@ func Execute() {
 // setupApp handles the common setup logic for both interactive and non-interactive modes.
 // It returns the app instance, config, cleanup function, and any error.
 func setupApp(cmd *cobra.Command) (*app.App, error) {
-	debug, _ := cmd.Flags().GetBool("debug")
 	yolo, _ := cmd.Flags().GetBool("yolo")
-	dataDir, _ := cmd.Flags().GetString("data-dir")
 	ctx := cmd.Context()
 
-	cwd, err := ResolveCwd(cmd)
-	if err != nil {
-		return nil, err
-	}
-
-	cfg, err := config.Init(cwd, dataDir, debug)
+	cfg, conn, err := setupDB(cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -127,16 +121,6 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 	}
 	cfg.Permissions.SkipRequests = yolo
 
-	if err := createDotCrushDir(cfg.Options.DataDirectory); err != nil {
-		return nil, err
-	}
-
-	// Connect to DB; this will also run migrations.
-	conn, err := db.Connect(ctx, cfg.Options.DataDirectory)
-	if err != nil {
-		return nil, err
-	}
-
 	appInstance, err := app.New(ctx, conn, cfg)
 	if err != nil {
 		slog.Error("Failed to create app instance", "error", err)
@@ -150,6 +134,36 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 	return appInstance, nil
 }
 
+// setupDB loads the configuration and connects to the project database
+// without starting the agent, LSP clients or MCP servers. It is used by
+// commands that only need to read or modify stored data.
+func setupDB(cmd *cobra.Command) (*config.Config, *sql.DB, error) {
+	debug, _ := cmd.Flags().GetBool("debug")
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg, err := config.Init(cwd, dataDir, debug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := createDotCrushDir(cfg.Options.DataDirectory); err != nil {
+		return nil, nil, err
+	}
+
+	// Connect to DB; this will also run migrations.
+	conn, err := db.Connect(cmd.Context(), cfg.Options.DataDirectory)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, conn, nil
+}
+
 func shouldEnableMetrics() bool {
 	if v, _ := strconv.ParseBool(os.Getenv("CRUSH_DISABLE_METRICS")); v {
 		return false
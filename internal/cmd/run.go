@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
 	"github.com/spf13/cobra"
 )
 
@@ -22,20 +30,71 @@ echo "What is this code doing?" | crush run
 
 # Run with quiet mode (no spinner)
 crush run -q "Generate a README for this project"
+
+# Print a JSON-lines stream of messages, tool calls and results
+crush run --output-format stream-json "Fix the failing tests"
+
+# Only allow read-only tools, plus running the tests
+crush run --read-only --allow-tool bash "Why is TestParse failing?"
+
+# Allow everything but fetching from the network
+crush run --deny-tool fetch --deny-tool download "Update the changelog"
+
+# Continue the most recent session
+crush run --continue "Now add tests for it"
+
+# Continue a specific session (an unambiguous ID prefix is enough)
+crush run --session 3f2a "Summarize what we changed"
+
+# Stop after spending $2 or making 30 requests to the model
+crush run --max-cost 2 --max-turns 30 "Fix all the lint warnings"
+
+# Use an agent declared in crush.json
+crush run --agent reviewer "Review the changes on this branch"
+
+# Ask for a plan first, then carry it out in the same session
+crush run --plan "Add pagination to the sessions API"
+crush run --continue "The plan is approved. Go ahead and implement it."
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		quiet, _ := cmd.Flags().GetBool("quiet")
+		sessionID, _ := cmd.Flags().GetString("session")
+		continueLast, _ := cmd.Flags().GetBool("continue")
+		outputFormat, _ := cmd.Flags().GetString("output-format")
+		agentID, _ := cmd.Flags().GetString("agent")
+		plan, _ := cmd.Flags().GetBool("plan")
+		opts := app.RunOptions{
+			Quiet:        quiet,
+			OutputFormat: app.OutputFormat(outputFormat),
+			Budget:       budgetFromFlags(cmd),
+			Plan:         plan,
+		}
+		if !opts.OutputFormat.IsValid() {
+			return fmt.Errorf("invalid output format %q: must be one of %s", outputFormat, outputFormatNames())
+		}
 
-		app, err := setupApp(cmd)
+		appInstance, err := setupApp(cmd)
 		if err != nil {
 			return err
 		}
-		defer app.Shutdown()
+		defer appInstance.Shutdown()
 
-		if !app.Config().IsConfigured() {
+		policy, err := policyFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		opts.Policy = policy
+
+		if !appInstance.Config().IsConfigured() {
 			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
 		}
 
+		if agentID != "" {
+			if err := appInstance.SwitchAgent(agentID); err != nil {
+				return err
+			}
+		}
+
 		prompt := strings.Join(args, " ")
 
 		prompt, err = MaybePrependStdin(prompt)
@@ -48,11 +107,140 @@ crush run -q "Generate a README for this project"
 			return fmt.Errorf("no prompt provided")
 		}
 
+		opts.SessionID, err = nonInteractiveSession(cmd.Context(), appInstance.Sessions, sessionID, continueLast)
+		if err != nil {
+			return err
+		}
+
 		// Run non-interactive flow using the App method
-		return app.RunNonInteractive(cmd.Context(), prompt, quiet)
+		return appInstance.RunNonInteractive(cmd.Context(), prompt, opts)
 	},
 }
 
 func init() {
 	runCmd.Flags().BoolP("quiet", "q", false, "Hide spinner")
+	runCmd.Flags().StringP("session", "s", "", "Continue the session with the given ID")
+	runCmd.Flags().Bool("continue", false, "Continue the most recently updated session")
+	runCmd.MarkFlagsMutuallyExclusive("session", "continue")
+	runCmd.Flags().String("agent", "", "Run the prompt with the agent with the given ID from the config")
+	runCmd.Flags().Bool("plan", false, "Only explore with read-only tools and answer with a plan")
+	addPolicyFlags(runCmd)
+	addBudgetFlags(runCmd)
+	runCmd.Flags().StringP("output-format", "o", string(app.OutputFormatText), "Output format: "+outputFormatNames())
+}
+
+func outputFormatNames() string {
+	names := make([]string, len(app.OutputFormats))
+	for i, f := range app.OutputFormats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
+
+// nonInteractiveSession returns the ID of the session a non-interactive run
+// continues: the one given with --session, the most recent one with
+// --continue, or none to start a new session.
+func nonInteractiveSession(ctx context.Context, sessions session.Service, sessionID string, continueLast bool) (string, error) {
+	switch {
+	case sessionID != "":
+		s, err := resolveSession(ctx, sessions, sessionID)
+		if err != nil {
+			return "", err
+		}
+		return s.ID, nil
+	case continueLast:
+		s, err := latestSession(ctx, sessions)
+		if err != nil {
+			return "", err
+		}
+		return s.ID, nil
+	}
+	return "", nil
+}
+
+// latestSession returns the most recently updated top-level session.
+func latestSession(ctx context.Context, sessions session.Service) (session.Session, error) {
+	all, err := sessions.List(ctx)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if len(all) == 0 {
+		return session.Session{}, fmt.Errorf("no sessions to continue")
+	}
+	latest := all[0]
+	for _, s := range all[1:] {
+		if s.UpdatedAt > latest.UpdatedAt {
+			latest = s
+		}
+	}
+	return latest, nil
+}
+
+// addPolicyFlags adds the flags read by policyFromFlags to cmd.
+func addPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("allow-tool", nil, "Approve requests from this tool or tool:action automatically and deny all others (repeatable)")
+	cmd.Flags().StringSlice("deny-tool", nil, "Deny this tool or tool:action (repeatable)")
+	cmd.Flags().Bool("read-only", false, "Only allow tools that can't modify the system")
+	cmd.Flags().StringSlice("allow-path", nil, "Allow tools to access this directory outside of the working directory (repeatable)")
+}
+
+// addBudgetFlags adds the flags read by budgetFromFlags to cmd.
+func addBudgetFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64("max-cost", 0, "Stop once a prompt cost this much, in USD")
+	cmd.Flags().Int64("max-tokens", 0, "Stop once a prompt used this many tokens")
+	cmd.Flags().Int("max-turns", 0, "Stop once a prompt made this many requests to the model")
+}
+
+// budgetFromFlags returns the budget limits given on the command line.
+func budgetFromFlags(cmd *cobra.Command) config.Budget {
+	maxCost, _ := cmd.Flags().GetFloat64("max-cost")
+	maxTokens, _ := cmd.Flags().GetInt64("max-tokens")
+	maxTurns, _ := cmd.Flags().GetInt("max-turns")
+	return config.Budget{
+		MaxCost:   maxCost,
+		MaxTokens: maxTokens,
+		MaxTurns:  maxTurns,
+	}
+}
+
+// policyFromFlags builds the permission policy for a non-interactive run. It
+// returns nil when no policy flag was given, in which case every request is
+// approved. Relative paths are resolved against the working directory.
+func policyFromFlags(cmd *cobra.Command) (*permission.Policy, error) {
+	allowTools, _ := cmd.Flags().GetStringSlice("allow-tool")
+	denyTools, _ := cmd.Flags().GetStringSlice("deny-tool")
+	readOnly, _ := cmd.Flags().GetBool("read-only")
+	allowPaths, _ := cmd.Flags().GetStringSlice("allow-path")
+
+	if len(allowTools) == 0 && len(denyTools) == 0 && !readOnly && len(allowPaths) == 0 {
+		return nil, nil
+	}
+
+	policy := &permission.Policy{
+		AllowedTools: allowTools,
+		DeniedTools:  denyTools,
+	}
+	if readOnly {
+		policy.OnlyTools = withAllowedTools(append(config.ReadOnlyTools(), agent.AgentToolName), allowTools)
+	}
+	for _, path := range allowPaths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %q: %w", path, err)
+		}
+		policy.AllowedPaths = append(policy.AllowedPaths, abs)
+	}
+	return policy, nil
+}
+
+// withAllowedTools adds the tools named by the allow-tool entries to tools,
+// so tools explicitly allowed are still available in read-only runs.
+func withAllowedTools(tools, allowTools []string) []string {
+	for _, tool := range allowTools {
+		name, _, _ := strings.Cut(tool, ":")
+		if !slices.Contains(tools, name) {
+			tools = append(tools, name)
+		}
+	}
+	return tools
 }
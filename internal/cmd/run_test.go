@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNonInteractiveSession(t *testing.T) {
+	t.Parallel()
+
+	sessions := &fakeSessions{sessions: []session.Session{
+		{ID: "3f2a9c", UpdatedAt: 300},
+		{ID: "9b1c00", UpdatedAt: 500},
+		{ID: "9b1c00-task", ParentSessionID: "9b1c00", UpdatedAt: 900},
+		{ID: "c0ffee", UpdatedAt: 100},
+	}}
+
+	for _, tt := range []struct {
+		name         string
+		sessionID    string
+		continueLast bool
+		want         string
+		err          string
+	}{
+		{name: "new session"},
+		{name: "session", sessionID: "3f2a9c", want: "3f2a9c"},
+		{name: "session prefix", sessionID: "c0f", want: "c0ffee"},
+		{name: "unknown session", sessionID: "ffff", err: "session not found: ffff"},
+		// Task sessions are newer but aren't continued.
+		{name: "continue", continueLast: true, want: "9b1c00"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			id, err := nonInteractiveSession(t.Context(), sessions, tt.sessionID, tt.continueLast)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, id)
+		})
+	}
+
+	t.Run("continue without sessions", func(t *testing.T) {
+		t.Parallel()
+		_, err := nonInteractiveSession(t.Context(), &fakeSessions{}, "", true)
+		require.EqualError(t, err, "no sessions to continue")
+	})
+}
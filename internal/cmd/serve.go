@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/server"
+	"github.com/spf13/cobra"
+)
+
+const defaultServeAddr = "127.0.0.1:7377"
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve Crush over a local HTTP/JSON API",
+	Long: `Run Crush headless and expose its sessions, agent, permissions and events
+over an HTTP/JSON API on a localhost port or a unix socket, so other programs
+like editor plugins can drive it without a terminal.
+
+Events are streamed as server-sent events from GET /v1/events.
+
+On a TCP address every request must send a bearer token. Unless one is given
+with --token or $CRUSH_SERVE_TOKEN, a random token is generated and printed on
+startup.`,
+	Example: `
+# Serve on the default localhost port
+crush serve
+
+# Serve on a unix socket
+crush serve --socket /tmp/crush.sock
+
+# Use a fixed bearer token
+CRUSH_SERVE_TOKEN=secret crush serve --addr 127.0.0.1:8080
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		socket, _ := cmd.Flags().GetString("socket")
+		token, _ := cmd.Flags().GetString("token")
+		if token == "" {
+			token = os.Getenv("CRUSH_SERVE_TOKEN")
+		}
+
+		ln, err := listen(addr, socket)
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+
+		// Any local program, including web pages open in a browser, can
+		// reach a TCP port, so those always need a token.
+		generated := token == "" && socket == ""
+		if generated {
+			token = rand.Text()
+		}
+
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		fmt.Fprintf(cmd.ErrOrStderr(), "Crush API listening on %s\n", listenerURL(ln))
+		if generated {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Authorization: Bearer %s\n", token)
+		}
+
+		srv := server.New(app, server.Options{Token: token})
+		return srv.Serve(cmd.Context(), ln)
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("addr", defaultServeAddr, "Loopback address to listen on")
+	serveCmd.Flags().String("socket", "", "Unix socket to listen on instead of a TCP address")
+	serveCmd.Flags().String("token", "", "Bearer token required on every request (default $CRUSH_SERVE_TOKEN, or a random token on TCP)")
+	serveCmd.MarkFlagsMutuallyExclusive("addr", "socket")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// listen opens the listener for the API. TCP addresses must be loopback
+// addresses, as the API can run arbitrary commands through the agent.
+func listen(addr, socket string) (net.Listener, error) {
+	if socket != "" {
+		// Remove a stale socket left behind by a previous run.
+		if info, err := os.Stat(socket); err == nil && info.Mode()&fs.ModeSocket != 0 {
+			if err := os.Remove(socket); err != nil {
+				return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+			}
+		}
+		ln, err := listenUnix(socket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on socket: %w", err)
+		}
+		return ln, nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return nil, errors.New("refusing to listen on a non-loopback address")
+		}
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return ln, nil
+}
+
+func listenerURL(ln net.Listener) string {
+	if ln.Addr().Network() == "unix" {
+		return "unix://" + ln.Addr().String()
+	}
+	return "http://" + ln.Addr().String()
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix listens on a unix socket only its owner can connect to. The
+// umask is tightened around the listen call, so the socket never exists with
+// looser permissions, even briefly.
+func listenUnix(socket string) (net.Listener, error) {
+	old := syscall.Umask(0o077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", socket)
+}
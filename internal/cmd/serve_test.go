@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenSocketPermissions(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+
+	socket := filepath.Join(t.TempDir(), "crush.sock")
+	ln, err := listen("", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	require.Zero(t, info.Mode().Perm()&0o077)
+}
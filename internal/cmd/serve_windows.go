@@ -0,0 +1,11 @@
+//go:build windows
+
+package cmd
+
+import "net"
+
+// listenUnix listens on a unix socket. Windows has no umask, access to the
+// socket follows the ACLs of its directory.
+func listenUnix(socket string) (net.Listener, error) {
+	return net.Listen("unix", socket)
+}
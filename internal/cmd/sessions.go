@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// maxToolResultPreview is the number of characters of a tool result shown by
+// `crush sessions show` in text mode.
+const maxToolResultPreview = 200
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Manage sessions",
+	Long:  `List, inspect, rename and delete the sessions stored for the current project.`,
+	Example: `
+# List sessions
+crush sessions list
+
+# List sessions as JSON
+crush sessions list --json
+
+# Show a session and its messages (an unambiguous ID prefix is enough)
+crush sessions show 3f2a
+
+# Rename a session
+crush sessions rename 3f2a "Refactor the config loader"
+
+# Delete sessions
+crush sessions delete 3f2a 9b1c
+  `,
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List sessions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		return withSessionServices(cmd, func(ctx context.Context, sessions session.Service, _ message.Service) error {
+			all, err := sessions.List(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list sessions: %w", err)
+			}
+			if limit > 0 && len(all) > limit {
+				all = all[:limit]
+			}
+
+			if asJSON {
+				out := make([]sessionJSON, len(all))
+				for i, s := range all {
+					out[i] = toSessionJSON(s)
+				}
+				return printJSON(cmd.OutOrStdout(), out)
+			}
+
+			if len(all) == 0 {
+				fmt.Fprintln(cmd.ErrOrStderr(), "No sessions found.")
+				return nil
+			}
+			return printSessionsTable(cmd.OutOrStdout(), all)
+		})
+	},
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a session and its messages",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		return withSessionServices(cmd, func(ctx context.Context, sessions session.Service, messages message.Service) error {
+			s, err := resolveSession(ctx, sessions, args[0])
+			if err != nil {
+				return err
+			}
+			msgs, err := messages.List(ctx, s.ID)
+			if err != nil {
+				return fmt.Errorf("failed to list messages: %w", err)
+			}
+
+			if asJSON {
+				out := sessionDetailJSON{
+					sessionJSON: toSessionJSON(s),
+					Messages:    make([]messageJSON, len(msgs)),
+				}
+				for i, msg := range msgs {
+					out.Messages[i] = toMessageJSON(msg)
+				}
+				return printJSON(cmd.OutOrStdout(), out)
+			}
+
+			printSessionDetail(cmd.OutOrStdout(), s, msgs)
+			return nil
+		})
+	},
+}
+
+var sessionsDeleteCmd = &cobra.Command{
+	Use:   "delete <id>...",
+	Short: "Delete one or more sessions",
+	Long:  `Delete sessions along with their messages, file history and the task sessions they started.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		return withSessionServices(cmd, func(ctx context.Context, sessions session.Service, _ message.Service) error {
+			// Resolve everything first so a typo doesn't leave us with a
+			// partial deletion.
+			targets := make([]session.Session, 0, len(args))
+			for _, id := range args {
+				s, err := resolveSession(ctx, sessions, id)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, s)
+			}
+
+			deleted := make([]string, 0, len(targets))
+			for _, s := range targets {
+				ids, err := session.DeleteTree(ctx, sessions, s.ID)
+				if err != nil {
+					return err
+				}
+				deleted = append(deleted, ids...)
+				if asJSON {
+					continue
+				}
+				if tasks := len(ids) - 1; tasks > 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "Deleted session %s (%s) and %d task sessions\n", s.ID, s.Title, tasks)
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "Deleted session %s (%s)\n", s.ID, s.Title)
+				}
+			}
+
+			if asJSON {
+				return printJSON(cmd.OutOrStdout(), map[string][]string{"deleted": deleted})
+			}
+			return nil
+		})
+	},
+}
+
+var sessionsRenameCmd = &cobra.Command{
+	Use:   "rename <id> <title>",
+	Short: "Rename a session",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		title := strings.TrimSpace(strings.Join(args[1:], " "))
+		if title == "" {
+			return fmt.Errorf("title cannot be empty")
+		}
+
+		return withSessionServices(cmd, func(ctx context.Context, sessions session.Service, _ message.Service) error {
+			s, err := resolveSession(ctx, sessions, args[0])
+			if err != nil {
+				return err
+			}
+			s.Title = title
+			s, err = sessions.Save(ctx, s)
+			if err != nil {
+				return fmt.Errorf("failed to rename session: %w", err)
+			}
+
+			if asJSON {
+				return printJSON(cmd.OutOrStdout(), toSessionJSON(s))
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Renamed session %s to %q\n", s.ID, s.Title)
+			return nil
+		})
+	},
+}
+
+func init() {
+	sessionsCmd.PersistentFlags().Bool("json", false, "Output as JSON")
+	sessionsListCmd.Flags().IntP("limit", "n", 0, "Maximum number of sessions to list (0 for all)")
+
+	sessionsCmd.AddCommand(
+		sessionsListCmd,
+		sessionsShowCmd,
+		sessionsDeleteCmd,
+		sessionsRenameCmd,
+	)
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+// withSessionServices connects to the project database and calls fn with the
+// session and message services. The connection is closed once fn returns.
+func withSessionServices(cmd *cobra.Command, fn func(context.Context, session.Service, message.Service) error) error {
+	_, conn, err := setupDB(cmd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	q := db.New(conn)
+	return fn(cmd.Context(), session.NewService(q), message.NewService(q))
+}
+
+// resolveSession finds a session by its full ID or by an unambiguous prefix of
+// the ID of a top-level session.
+func resolveSession(ctx context.Context, sessions session.Service, id string) (session.Session, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return session.Session{}, fmt.Errorf("session ID cannot be empty")
+	}
+	s, err := sessions.Get(ctx, id)
+	if err == nil {
+		return s, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return session.Session{}, fmt.Errorf("failed to get session %s: %w", id, err)
+	}
+
+	all, err := sessions.List(ctx)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	var matches []session.Session
+	for _, s := range all {
+		if strings.HasPrefix(s.ID, id) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return session.Session{}, fmt.Errorf("session not found: %s", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return session.Session{}, fmt.Errorf("session ID %q is ambiguous: matches %d sessions", id, len(matches))
+	}
+}
+
+type sessionJSON struct {
+	ID                  string    `json:"id"`
+	ParentSessionID     string    `json:"parent_session_id,omitempty"`
+	ForkedFromSessionID string    `json:"forked_from_session_id,omitempty"`
+	Title               string    `json:"title"`
+	MessageCount        int64     `json:"message_count"`
+	PromptTokens        int64     `json:"prompt_tokens"`
+	CompletionTokens    int64     `json:"completion_tokens"`
+	Cost                float64   `json:"cost"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+type sessionDetailJSON struct {
+	sessionJSON
+	Messages []messageJSON `json:"messages"`
+}
+
+type messageJSON struct {
+	ID        string     `json:"id"`
+	Role      string     `json:"role"`
+	Model     string     `json:"model,omitempty"`
+	Provider  string     `json:"provider,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	Parts     []partJSON `json:"parts"`
+}
+
+type partJSON struct {
+	Type       string `json:"type"`
+	Text       string `json:"text,omitempty"`
+	ID         string `json:"id,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Input      string `json:"input,omitempty"`
+	Content    string `json:"content,omitempty"`
+	IsError    bool   `json:"is_error,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Path       string `json:"path,omitempty"`
+	MIMEType   string `json:"mime_type,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+func toSessionJSON(s session.Session) sessionJSON {
+	return sessionJSON{
+		ID:                  s.ID,
+		ParentSessionID:     s.ParentSessionID,
+		ForkedFromSessionID: s.ForkedFromSessionID,
+		Title:               s.Title,
+		MessageCount:        s.MessageCount,
+		PromptTokens:        s.PromptTokens,
+		CompletionTokens:    s.CompletionTokens,
+		Cost:                s.Cost,
+		CreatedAt:           time.Unix(s.CreatedAt, 0).UTC(),
+		UpdatedAt:           time.Unix(s.UpdatedAt, 0).UTC(),
+	}
+}
+
+func toMessageJSON(msg message.Message) messageJSON {
+	out := messageJSON{
+		ID:        msg.ID,
+		Role:      string(msg.Role),
+		Model:     msg.Model,
+		Provider:  msg.Provider,
+		CreatedAt: time.Unix(msg.CreatedAt, 0).UTC(),
+		Parts:     make([]partJSON, 0, len(msg.Parts)),
+	}
+	for _, part := range msg.Parts {
+		switch p := part.(type) {
+		case message.ReasoningContent:
+			out.Parts = append(out.Parts, partJSON{Type: "reasoning", Text: p.Thinking})
+		case message.TextContent:
+			out.Parts = append(out.Parts, partJSON{Type: "text", Text: p.Text})
+		case message.ImageURLContent:
+			out.Parts = append(out.Parts, partJSON{Type: "image_url", URL: p.URL})
+		case message.BinaryContent:
+			out.Parts = append(out.Parts, partJSON{Type: "binary", Path: p.Path, MIMEType: p.MIMEType})
+		case message.ToolCall:
+			out.Parts = append(out.Parts, partJSON{Type: "tool_call", ID: p.ID, Name: p.Name, Input: p.Input})
+		case message.ToolResult:
+			out.Parts = append(out.Parts, partJSON{Type: "tool_result", ToolCallID: p.ToolCallID, Name: p.Name, Content: p.Content, IsError: p.IsError})
+		case message.Finish:
+			out.Parts = append(out.Parts, partJSON{Type: "finish", Reason: string(p.Reason), Message: p.Message})
+		}
+	}
+	return out
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printSessionsTable(w io.Writer, sessions []session.Session) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tMESSAGES\tTOKENS\tCOST\tUPDATED")
+	for _, s := range sessions {
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%d\t%d\t$%.4f\t%s\n",
+			s.ID,
+			truncate(s.Title, 50),
+			s.MessageCount,
+			s.PromptTokens+s.CompletionTokens,
+			s.Cost,
+			formatUnix(s.UpdatedAt),
+		)
+	}
+	return tw.Flush()
+}
+
+func printSessionDetail(w io.Writer, s session.Session, msgs []message.Message) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\t%s\n", s.ID)
+	fmt.Fprintf(tw, "Title\t%s\n", s.Title)
+	fmt.Fprintf(tw, "Created\t%s\n", formatUnix(s.CreatedAt))
+	fmt.Fprintf(tw, "Updated\t%s\n", formatUnix(s.UpdatedAt))
+	fmt.Fprintf(tw, "Messages\t%d\n", s.MessageCount)
+	fmt.Fprintf(tw, "Tokens\t%d prompt, %d completion\n", s.PromptTokens, s.CompletionTokens)
+	fmt.Fprintf(tw, "Cost\t$%.4f\n", s.Cost)
+	_ = tw.Flush()
+
+	for _, msg := range msgs {
+		header := string(msg.Role)
+		if msg.Model != "" {
+			header += " (" + msg.Model + ")"
+		}
+		fmt.Fprintf(w, "\n--- %s · %s\n", header, formatUnix(msg.CreatedAt))
+
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case message.TextContent:
+				if strings.TrimSpace(p.Text) != "" {
+					fmt.Fprintln(w, strings.TrimSpace(p.Text))
+				}
+			case message.BinaryContent:
+				fmt.Fprintf(w, "[attachment] %s (%s)\n", p.Path, p.MIMEType)
+			case message.ToolCall:
+				fmt.Fprintf(w, "→ %s %s\n", p.Name, truncate(p.Input, maxToolResultPreview))
+			case message.ToolResult:
+				prefix := "←"
+				if p.IsError {
+					prefix = "← error:"
+				}
+				fmt.Fprintf(w, "%s %s\n", prefix, truncate(p.Content, maxToolResultPreview))
+			case message.Finish:
+				if p.Reason != message.FinishReasonEndTurn && p.Reason != message.FinishReasonToolUse {
+					fmt.Fprintf(w, "[%s] %s\n", p.Reason, p.Message)
+				}
+			}
+		}
+	}
+}
+
+func formatUnix(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).Local().Format(time.DateTime)
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSessions is an in-memory session.Service with just what the sessions
+// command uses.
+type fakeSessions struct {
+	session.Service
+	sessions []session.Session
+	getErr   error
+}
+
+func (f *fakeSessions) Get(_ context.Context, id string) (session.Session, error) {
+	if f.getErr != nil {
+		return session.Session{}, f.getErr
+	}
+	for _, s := range f.sessions {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return session.Session{}, sql.ErrNoRows
+}
+
+func (f *fakeSessions) List(context.Context) ([]session.Session, error) {
+	var top []session.Session
+	for _, s := range f.sessions {
+		if s.ParentSessionID == "" {
+			top = append(top, s)
+		}
+	}
+	return top, nil
+}
+
+func (f *fakeSessions) ListChildren(_ context.Context, parentSessionID string) ([]session.Session, error) {
+	var children []session.Session
+	for _, s := range f.sessions {
+		if s.ParentSessionID == parentSessionID {
+			children = append(children, s)
+		}
+	}
+	return children, nil
+}
+
+func (f *fakeSessions) Create(_ context.Context, title string) (session.Session, error) {
+	s := session.Session{ID: fmt.Sprintf("session-%d", len(f.sessions)+1), Title: title}
+	f.sessions = append(f.sessions, s)
+	return s, nil
+}
+
+func (f *fakeSessions) Delete(_ context.Context, id string) error {
+	f.sessions = slices.DeleteFunc(f.sessions, func(s session.Session) bool {
+		return s.ID == id
+	})
+	return nil
+}
+
+func TestResolveSession(t *testing.T) {
+	t.Parallel()
+
+	sessions := &fakeSessions{sessions: []session.Session{
+		{ID: "3f2a9c"},
+		{ID: "3f2b11"},
+		{ID: "9b1c00"},
+		{ID: "9b1c00-task", ParentSessionID: "9b1c00"},
+	}}
+
+	for _, tt := range []struct {
+		id   string
+		want string
+		err  string
+	}{
+		{id: "3f2a9c", want: "3f2a9c"},
+		{id: "3f2a", want: "3f2a9c"},
+		{id: " 9b ", want: "9b1c00"},
+		// Task sessions are found by their full ID only.
+		{id: "9b1c00-task", want: "9b1c00-task"},
+		{id: "3f2", err: `session ID "3f2" is ambiguous: matches 2 sessions`},
+		{id: "ffff", err: "session not found: ffff"},
+		{id: "", err: "session ID cannot be empty"},
+	} {
+		t.Run(tt.id, func(t *testing.T) {
+			t.Parallel()
+			s, err := resolveSession(t.Context(), sessions, tt.id)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, s.ID)
+		})
+	}
+
+	t.Run("get error", func(t *testing.T) {
+		t.Parallel()
+		broken := &fakeSessions{
+			sessions: sessions.sessions,
+			getErr:   errors.New("database is locked"),
+		}
+		_, err := resolveSession(t.Context(), broken, "3f2a")
+		require.EqualError(t, err, "failed to get session 3f2a: database is locked")
+	})
+}
+
+func TestDeleteSession(t *testing.T) {
+	t.Parallel()
+
+	sessions := &fakeSessions{sessions: []session.Session{
+		{ID: "parent"},
+		{ID: "task", ParentSessionID: "parent"},
+		{ID: "nested", ParentSessionID: "task"},
+		{ID: "title", ParentSessionID: "parent"},
+		{ID: "other"},
+	}}
+
+	deleted, err := session.DeleteTree(t.Context(), sessions, "parent")
+	require.NoError(t, err)
+	require.Equal(t, []string{"nested", "task", "title", "parent"}, deleted)
+	require.Equal(t, []session.Session{{ID: "other"}}, sessions.sessions)
+}
+
+func TestPrintSessionDetail(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	printSessionDetail(&buf, session.Session{ID: "3f2a9c", Title: "Fix the tests"}, []message.Message{
+		{
+			Role: message.User,
+			Parts: []message.ContentPart{
+				message.TextContent{Text: "Fix the tests"},
+			},
+		},
+		{
+			Role:  message.Assistant,
+			Model: "gpt-4o",
+			Parts: []message.ContentPart{
+				message.ToolCall{ID: "call", Name: "bash", Input: `{"command": "go test ./..."}`},
+				message.Finish{Reason: message.FinishReasonToolUse},
+			},
+		},
+		{
+			Role: message.Tool,
+			Parts: []message.ContentPart{
+				message.ToolResult{ToolCallID: "call", Content: "FAIL", IsError: true},
+			},
+		},
+		{
+			Role: message.Assistant,
+			Parts: []message.ContentPart{
+				message.TextContent{Text: "Done."},
+				message.Finish{Reason: message.FinishReasonEndTurn},
+			},
+		},
+		{
+			Role: message.Assistant,
+			Parts: []message.ContentPart{
+				message.Finish{Reason: message.FinishReasonError, Message: "rate limited"},
+			},
+		},
+	})
+
+	out := buf.String()
+	require.Contains(t, out, "ID        3f2a9c\n")
+	require.Contains(t, out, "Title     Fix the tests\n")
+	require.Contains(t, out, "\n--- assistant (gpt-4o) · -\n")
+	require.Contains(t, out, "→ bash {\"command\": \"go test ./...\"}\n")
+	require.Contains(t, out, "← error: FAIL\n")
+	require.Contains(t, out, "Done.\n")
+	require.Contains(t, out, "[error] rate limited\n")
+	// Turns that ended normally have no finish line.
+	require.NotContains(t, out, "[tool_use]")
+	require.NotContains(t, out, "[end_turn]")
+}
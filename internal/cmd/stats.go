@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var statsFormats = []string{"table", "csv", "json"}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Report token usage and cost",
+	Long: `Report the tokens used and the cost of every provider request made in the
+project, including title generation, summaries and task agents, aggregated by
+day, model and/or provider.
+
+Dates are in local time. Usage from before this report existed is estimated
+from session totals.`,
+	Example: `
+# Usage per day over the last 30 days
+crush stats
+
+# Spend per model for a month
+crush stats --month 2025-07 --group-by model
+
+# Usage per day and provider over a date range, as CSV
+crush stats --since 2025-07-01 --until 2025-07-15 -g day,provider -f csv
+
+# Overall totals as JSON
+crush stats --group-by "" -f json
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		groupByFlag, _ := cmd.Flags().GetString("group-by")
+
+		if !slices.Contains(statsFormats, format) {
+			return fmt.Errorf("invalid format %q, must be one of table, csv or json", format)
+		}
+		groupBy, err := usage.ParseGroupBy(groupByFlag)
+		if err != nil {
+			return err
+		}
+		since, until, err := statsRange(cmd, time.Now())
+		if err != nil {
+			return err
+		}
+
+		_, conn, err := setupDB(cmd)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		records, err := usage.NewService(db.New(conn)).List(cmd.Context(), since, until)
+		if err != nil {
+			return fmt.Errorf("failed to list usage: %w", err)
+		}
+		report := usage.Aggregate(records, groupBy, time.Local)
+
+		w := cmd.OutOrStdout()
+		switch format {
+		case "json":
+			return printJSON(w, report)
+		case "csv":
+			return printStatsCSV(w, report, groupBy)
+		default:
+			return printStatsTable(w, report, groupBy)
+		}
+	},
+}
+
+func init() {
+	statsCmd.Flags().StringP("group-by", "g", string(usage.GroupByDay), "Comma separated dimensions to group by: day, model, provider")
+	statsCmd.Flags().String("since", "", "First day to report, as YYYY-MM-DD (default 30 days ago)")
+	statsCmd.Flags().String("until", "", "Last day to report, as YYYY-MM-DD (default today)")
+	statsCmd.Flags().String("month", "", "Month to report, as YYYY-MM")
+	statsCmd.Flags().StringP("format", "f", "table", "Output format: table, csv, json")
+	statsCmd.MarkFlagsMutuallyExclusive("month", "since")
+	statsCmd.MarkFlagsMutuallyExclusive("month", "until")
+	rootCmd.AddCommand(statsCmd)
+}
+
+// statsRange returns the [since, until) range selected by the flags, in
+// local time.
+func statsRange(cmd *cobra.Command, now time.Time) (time.Time, time.Time, error) {
+	month, _ := cmd.Flags().GetString("month")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	untilFlag, _ := cmd.Flags().GetString("until")
+
+	if month != "" {
+		start, err := time.ParseInLocation("2006-01", month, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid month %q, expected YYYY-MM", month)
+		}
+		return start, start.AddDate(0, 1, 0), nil
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	since := today.AddDate(0, 0, -29)
+	until := today.AddDate(0, 0, 1)
+	if sinceFlag != "" {
+		day, err := time.ParseInLocation(time.DateOnly, sinceFlag, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --since date %q, expected YYYY-MM-DD", sinceFlag)
+		}
+		since = day
+	}
+	if untilFlag != "" {
+		day, err := time.ParseInLocation(time.DateOnly, untilFlag, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --until date %q, expected YYYY-MM-DD", untilFlag)
+		}
+		until = day.AddDate(0, 0, 1)
+	}
+	if !since.Before(until) {
+		return time.Time{}, time.Time{}, fmt.Errorf("--since must not be after --until")
+	}
+	return since, until, nil
+}
+
+// groupLabels returns the values of the dimensions a row is grouped by.
+func groupLabels(row usage.Row, groupBy []usage.GroupBy) []string {
+	labels := make([]string, 0, len(groupBy))
+	for _, g := range groupBy {
+		switch g {
+		case usage.GroupByDay:
+			labels = append(labels, row.Day)
+		case usage.GroupByModel:
+			labels = append(labels, row.Model)
+		case usage.GroupByProvider:
+			labels = append(labels, row.Provider)
+		}
+	}
+	return labels
+}
+
+func printStatsCSV(w io.Writer, report usage.Report, groupBy []usage.GroupBy) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, 0, len(groupBy)+7)
+	for _, g := range groupBy {
+		header = append(header, string(g))
+	}
+	header = append(header, "requests", "sessions", "input_tokens", "output_tokens", "cache_creation_tokens", "cache_read_tokens", "cost")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range report.Rows {
+		record := append(
+			groupLabels(row, groupBy),
+			strconv.FormatInt(row.Requests, 10),
+			strconv.FormatInt(row.Sessions, 10),
+			strconv.FormatInt(row.InputTokens, 10),
+			strconv.FormatInt(row.OutputTokens, 10),
+			strconv.FormatInt(row.CacheCreationTokens, 10),
+			strconv.FormatInt(row.CacheReadTokens, 10),
+			strconv.FormatFloat(row.Cost, 'f', 4, 64),
+		)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func printStatsTable(w io.Writer, report usage.Report, groupBy []usage.GroupBy) error {
+	if len(report.Rows) == 0 {
+		fmt.Fprintln(w, "No usage recorded in this period.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, g := range groupBy {
+		fmt.Fprintf(tw, "%s\t", strings.ToUpper(string(g)))
+	}
+	fmt.Fprintln(tw, "REQUESTS\tSESSIONS\tINPUT\tOUTPUT\tCACHE WRITE\tCACHE READ\tCOST\t")
+
+	printRow := func(labels []string, row usage.Row) {
+		for _, label := range labels {
+			fmt.Fprintf(tw, "%s\t", label)
+		}
+		fmt.Fprintf(
+			tw,
+			"%d\t%d\t%s\t%s\t%s\t%s\t$%.2f\t\n",
+			row.Requests,
+			row.Sessions,
+			formatTokens(row.InputTokens),
+			formatTokens(row.OutputTokens),
+			formatTokens(row.CacheCreationTokens),
+			formatTokens(row.CacheReadTokens),
+			row.Cost,
+		)
+	}
+	for _, row := range report.Rows {
+		printRow(groupLabels(row, groupBy), row)
+	}
+	if len(groupBy) > 0 && len(report.Rows) > 1 {
+		labels := make([]string, len(groupBy))
+		labels[0] = "TOTAL"
+		printRow(labels, report.Total)
+	}
+	return tw.Flush()
+}
+
+// formatTokens formats a token count with a K or M suffix.
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", math.Round(float64(n)/100_000)/10)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fK", math.Round(float64(n)/100)/10)
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+}
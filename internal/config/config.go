@@ -74,7 +74,7 @@ type ProviderConfig struct {
 	// The provider's API endpoint.
 	BaseURL string `json:"base_url,omitempty" jsonschema:"description=Base URL for the provider's API,format=uri,example=https://api.openai.com/v1"`
 	// The provider type, e.g. "openai", "anthropic", etc. if empty it defaults to openai.
-	Type catwalk.Type `json:"type,omitempty" jsonschema:"description=Provider type that determines the API format,enum=openai,enum=anthropic,enum=gemini,enum=azure,enum=vertexai,default=openai"`
+	Type catwalk.Type `json:"type,omitempty" jsonschema:"description=Provider type that determines the API format,enum=openai,enum=anthropic,enum=gemini,enum=azure,enum=vertexai,enum=mock,default=openai"`
 	// The provider's API key.
 	APIKey string `json:"api_key,omitempty" jsonschema:"description=API key for authentication with the provider,example=$OPENAI_API_KEY"`
 	// Marks the provider as disabled.
@@ -93,8 +93,15 @@ type ProviderConfig struct {
 
 	// The provider models
 	Models []catwalk.Model `json:"models,omitempty" jsonschema:"description=List of models available from this provider"`
+
+	// The script of responses of a mock provider.
+	Script string `json:"script,omitempty" jsonschema:"description=Script of responses replayed by mock providers (relative to working directory),example=testdata/mock.json"`
 }
 
+// ProviderTypeMock is the type of providers that answer with the responses
+// of a script instead of calling an API.
+const ProviderTypeMock catwalk.Type = "mock"
+
 type MCPType string
 
 const (
@@ -149,11 +156,77 @@ type Options struct {
 	Debug                     bool         `json:"debug,omitempty" jsonschema:"description=Enable debug logging,default=false"`
 	DebugLSP                  bool         `json:"debug_lsp,omitempty" jsonschema:"description=Enable debug logging for LSP servers,default=false"`
 	DisableAutoSummarize      bool         `json:"disable_auto_summarize,omitempty" jsonschema:"description=Disable automatic conversation summarization,default=false"`
+	DisableAutoPrune          bool         `json:"disable_auto_prune,omitempty" jsonschema:"description=Disable eliding stale tool results when the conversation nears the context window,default=false"`
 	DataDirectory             string       `json:"data_directory,omitempty" jsonschema:"description=Directory for storing application data (relative to working directory),default=.crush,example=.crush"` // Relative to the cwd
 	DisabledTools             []string     `json:"disabled_tools" jsonschema:"description=Tools to disable"`
 	DisableProviderAutoUpdate bool         `json:"disable_provider_auto_update,omitempty" jsonschema:"description=Disable providers auto-update,default=false"`
 	Attribution               *Attribution `json:"attribution,omitempty" jsonschema:"description=Attribution settings for generated content"`
 	DisableMetrics            bool         `json:"disable_metrics,omitempty" jsonschema:"description=Disable sending metrics,default=false"`
+	Cassette                  *Cassette    `json:"cassette,omitempty" jsonschema:"description=Record provider responses to a file and replay them in tests that run without network"`
+	Budget                    *Budget      `json:"budget,omitempty" jsonschema:"description=Limits on what a single prompt may spend before the agent stops"`
+	SessionBudget             *Budget      `json:"session_budget,omitempty" jsonschema:"description=Limits on what a session may spend across all its prompts before the agent stops"`
+	ParallelToolCalls         int          `json:"parallel_tool_calls,omitempty" jsonschema:"description=Maximum number of read-only tool calls of a turn run at the same time; 1 runs them one after another,default=4,minimum=0,example=8"`
+}
+
+// Budget limits what the agent may spend answering a single prompt, or a
+// whole session, including the tool calls and task agents it leads to. Zero
+// means no limit.
+type Budget struct {
+	MaxCost   float64 `json:"max_cost,omitempty" jsonschema:"description=Maximum cost in USD,minimum=0,example=5"`
+	MaxTokens int64   `json:"max_tokens,omitempty" jsonschema:"description=Maximum number of input and output tokens,minimum=0,example=2000000"`
+	MaxTurns  int     `json:"max_turns,omitempty" jsonschema:"description=Maximum number of requests to the model,minimum=0,example=50"`
+}
+
+// IsZero reports whether the budget has no limit.
+func (b Budget) IsZero() bool {
+	return b.MaxCost <= 0 && b.MaxTokens <= 0 && b.MaxTurns <= 0
+}
+
+// Merge returns b with the limits set in other replacing its own.
+func (b Budget) Merge(other Budget) Budget {
+	if other.MaxCost > 0 {
+		b.MaxCost = other.MaxCost
+	}
+	if other.MaxTokens > 0 {
+		b.MaxTokens = other.MaxTokens
+	}
+	if other.MaxTurns > 0 {
+		b.MaxTurns = other.MaxTurns
+	}
+	return b
+}
+
+// Hooks are shell commands run at points of the agent's work. They get what
+// happened as JSON on stdin.
+type Hooks struct {
+	PreToolUse     []Hook `json:"pre_tool_use,omitempty" jsonschema:"description=Commands run before a tool call; a command that fails blocks the call and one that prints {\"params\": ...} changes its parameters"`
+	PostToolUse    []Hook `json:"post_tool_use,omitempty" jsonschema:"description=Commands run after a tool call; the output of a command that fails is sent to the model with the result"`
+	OnTurnEnd      []Hook `json:"on_turn_end,omitempty" jsonschema:"description=Commands run when the agent is done answering a prompt"`
+	OnSessionStart []Hook `json:"on_session_start,omitempty" jsonschema:"description=Commands run before the first prompt of a session is answered"`
+}
+
+type Hook struct {
+	Command string   `json:"command" jsonschema:"required,description=Shell command to run from the working directory,example=gofmt -l ."`
+	Tools   []string `json:"tools,omitempty" jsonschema:"description=Tools a tool use hook runs for; all tools when unset,example=edit,example=write"`
+	Timeout int      `json:"timeout,omitempty" jsonschema:"description=Timeout in seconds for the command,default=30,example=60"`
+}
+
+type CassetteMode string
+
+const (
+	// CassetteModeAuto replays the recorded responses and records the
+	// missing ones.
+	CassetteModeAuto CassetteMode = "auto"
+	// CassetteModeRecord records every response, replacing the cassette.
+	CassetteModeRecord CassetteMode = "record"
+	// CassetteModeReplay only replays recorded responses and never calls the
+	// provider, requests that were not recorded fail.
+	CassetteModeReplay CassetteMode = "replay"
+)
+
+type Cassette struct {
+	Path string       `json:"path" jsonschema:"description=Cassette file responses are recorded to and replayed from (relative to working directory),example=testdata/agent.cassette.json"`
+	Mode CassetteMode `json:"mode,omitempty" jsonschema:"description=Whether to record responses or replay them,enum=auto,enum=record,enum=replay,default=auto"`
 }
 
 type MCPs map[string]MCPConfig
@@ -220,30 +293,52 @@ func (m MCPConfig) ResolvedHeaders() map[string]string {
 }
 
 type Agent struct {
-	ID          string `json:"id,omitempty"`
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
+	ID          string `json:"id,omitempty" jsonschema:"-"`
+	Name        string `json:"name,omitempty" jsonschema:"description=Display name of the agent,example=Reviewer"`
+	Description string `json:"description,omitempty" jsonschema:"description=Description of what the agent does"`
 	// This is the id of the system prompt used by the agent
-	Disabled bool `json:"disabled,omitempty"`
+	Disabled bool `json:"disabled,omitempty" jsonschema:"description=Whether this agent is disabled,default=false"`
 
-	Model SelectedModelType `json:"model" jsonschema:"required,description=The model type to use for this agent,enum=large,enum=small,default=large"`
+	Model SelectedModelType `json:"model,omitempty" jsonschema:"description=The model type to use for this agent,enum=large,enum=small,default=large"`
+
+	// Path to a file with the system prompt of the agent. When empty, the
+	// prompt of the coder agent is used.
+	Prompt string `json:"prompt,omitempty" jsonschema:"description=Path to a file with the system prompt of the agent,example=.crush/agents/reviewer.md"`
 
 	// The available tools for the agent
 	//  if this is nil, all tools are available
-	AllowedTools []string `json:"allowed_tools,omitempty"`
+	AllowedTools []string `json:"allowed_tools,omitempty" jsonschema:"description=Built-in tools available to the agent; all tools when unset"`
 
 	// this tells us which MCPs are available for this agent
 	//  if this is empty all mcps are available
 	//  the string array is the list of tools from the AllowedMCP the agent has available
 	//  if the string array is nil, all tools from the AllowedMCP are available
-	AllowedMCP map[string][]string `json:"allowed_mcp,omitempty"`
+	AllowedMCP map[string][]string `json:"allowed_mcp,omitempty" jsonschema:"description=MCP servers available to the agent mapped to their allowed tools; all servers when unset"`
 
 	// The list of LSPs that this agent can use
 	//  if this is nil, all LSPs are available
-	AllowedLSP []string `json:"allowed_lsp,omitempty"`
+	AllowedLSP []string `json:"allowed_lsp,omitempty" jsonschema:"description=LSP servers available to the agent; all servers when unset"`
 
 	// Overrides the context paths for this agent
-	ContextPaths []string `json:"context_paths,omitempty"`
+	ContextPaths []string `json:"context_paths,omitempty" jsonschema:"description=Context files of the agent; the global context paths when unset"`
+}
+
+// AllowsMCPTool reports whether the agent can use the given tool of the given
+// MCP server.
+func (a Agent) AllowsMCPTool(mcpName, toolName string) bool {
+	if a.AllowedMCP == nil {
+		return true
+	}
+	tools, ok := a.AllowedMCP[mcpName]
+	if !ok {
+		return false
+	}
+	return tools == nil || slices.Contains(tools, toolName)
+}
+
+// AllowsLSP reports whether the agent can use the given LSP server.
+func (a Agent) AllowsLSP(name string) bool {
+	return a.AllowedLSP == nil || slices.Contains(a.AllowedLSP, name)
 }
 
 // Config holds the configuration for crush.
@@ -264,10 +359,12 @@ type Config struct {
 
 	Permissions *Permissions `json:"permissions,omitempty" jsonschema:"description=Permission settings for tool usage"`
 
+	Agents map[string]Agent `json:"agents,omitempty" jsonschema:"description=Agents that can be selected instead of the default coder agent"`
+
+	Hooks *Hooks `json:"hooks,omitempty" jsonschema:"description=Shell commands run before and after tool calls and at the start and end of turns"`
+
 	// Internal
 	workingDir string `json:"-"`
-	// TODO: most likely remove this concept when I come back to it
-	Agents map[string]Agent `json:"-"`
 	// TODO: find a better way to do this this should probably not be part of the config
 	resolver       VariableResolver
 	dataConfigDir  string             `json:"-"`
@@ -440,6 +537,7 @@ func allToolNames() []string {
 		"sourcegraph",
 		"view",
 		"write",
+		"todos",
 	}
 }
 
@@ -451,10 +549,15 @@ func resolveAllowedTools(allTools []string, disabledTools []string) []string {
 	return filterSlice(allTools, disabledTools, false)
 }
 
+// ReadOnlyTools returns the names of the built-in tools that can't modify
+// the system.
+func ReadOnlyTools() []string {
+	return []string{"glob", "grep", "ls", "sourcegraph", "view"}
+}
+
 func resolveReadOnlyTools(tools []string) []string {
-	readOnlyTools := []string{"glob", "grep", "ls", "sourcegraph", "view"}
 	// filter to only include tools that are in allowedtools (include mode)
-	return filterSlice(tools, readOnlyTools, true)
+	return filterSlice(tools, ReadOnlyTools(), true)
 }
 
 func filterSlice(data []string, mask []string, include bool) []string {
@@ -493,9 +596,45 @@ func (c *Config) SetupAgents() {
 			AllowedLSP: []string{},
 		},
 	}
+	for id, agent := range c.Agents {
+		if _, ok := agents[id]; ok || agent.Disabled {
+			continue
+		}
+		agent.ID = id
+		if agent.Name == "" {
+			agent.Name = id
+		}
+		if agent.Model == "" {
+			agent.Model = SelectedModelTypeLarge
+		}
+		if agent.ContextPaths == nil {
+			agent.ContextPaths = c.Options.ContextPaths
+		}
+		if agent.AllowedTools == nil {
+			agent.AllowedTools = allowedTools
+		} else {
+			agent.AllowedTools = resolveAllowedTools(agent.AllowedTools, c.Options.DisabledTools)
+		}
+		agents[id] = agent
+	}
 	c.Agents = agents
 }
 
+// SelectableAgents returns the agents that can be used instead of the coder
+// agent, sorted by name.
+func (c *Config) SelectableAgents() []Agent {
+	var agents []Agent
+	for id, agent := range c.Agents {
+		if id != "task" {
+			agents = append(agents, agent)
+		}
+	}
+	slices.SortFunc(agents, func(a, b Agent) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return agents
+}
+
 func (c *Config) Resolver() VariableResolver {
 	return c.resolver
 }
@@ -257,6 +257,23 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			c.Providers.Del(id)
 			continue
 		}
+		if providerConfig.Type == ProviderTypeMock {
+			if providerConfig.Script == "" {
+				slog.Warn("Skipping mock provider due to missing script", "provider", id)
+				c.Providers.Del(id)
+				continue
+			}
+			if len(providerConfig.Models) == 0 {
+				providerConfig.Models = []catwalk.Model{{
+					ID:               "mock",
+					Name:             "Mock",
+					ContextWindow:    200_000,
+					DefaultMaxTokens: 8_000,
+				}}
+			}
+			c.Providers.Set(id, providerConfig)
+			continue
+		}
 		if providerConfig.APIKey == "" {
 			slog.Warn("Provider is missing API key, this might be OK for local providers", "provider", id)
 		}
@@ -336,6 +353,21 @@ func (c *Config) setDefaults(workingDir, dataDir string) {
 	if str, ok := os.LookupEnv("CRUSH_DISABLE_PROVIDER_AUTO_UPDATE"); ok {
 		c.Options.DisableProviderAutoUpdate, _ = strconv.ParseBool(str)
 	}
+
+	if path := os.Getenv("CRUSH_PROVIDER_CASSETTE"); path != "" {
+		c.Options.Cassette = &Cassette{
+			Path: path,
+			Mode: CassetteMode(os.Getenv("CRUSH_PROVIDER_CASSETTE_MODE")),
+		}
+	}
+	if c.Options.Cassette != nil {
+		if c.Options.Cassette.Mode == "" {
+			c.Options.Cassette.Mode = CassetteModeAuto
+		}
+		if !filepath.IsAbs(c.Options.Cassette.Path) {
+			c.Options.Cassette.Path = filepath.Join(workingDir, c.Options.Cassette.Path)
+		}
+	}
 }
 
 // applyLSPDefaults applies default values from powernap to LSP configurations
@@ -485,7 +485,7 @@ func TestConfig_setupAgentsWithDisabledTools(t *testing.T) {
 	cfg.SetupAgents()
 	coderAgent, ok := cfg.Agents["coder"]
 	require.True(t, ok)
-	assert.Equal(t, []string{"agent", "bash", "multiedit", "fetch", "glob", "ls", "sourcegraph", "view", "write"}, coderAgent.AllowedTools)
+	assert.Equal(t, []string{"agent", "bash", "multiedit", "fetch", "glob", "ls", "sourcegraph", "view", "write", "todos"}, coderAgent.AllowedTools)
 
 	taskAgent, ok := cfg.Agents["task"]
 	require.True(t, ok)
@@ -508,13 +508,65 @@ func TestConfig_setupAgentsWithEveryReadOnlyToolDisabled(t *testing.T) {
 	cfg.SetupAgents()
 	coderAgent, ok := cfg.Agents["coder"]
 	require.True(t, ok)
-	assert.Equal(t, []string{"agent", "bash", "download", "edit", "multiedit", "fetch", "write"}, coderAgent.AllowedTools)
+	assert.Equal(t, []string{"agent", "bash", "download", "edit", "multiedit", "fetch", "write", "todos"}, coderAgent.AllowedTools)
 
 	taskAgent, ok := cfg.Agents["task"]
 	require.True(t, ok)
 	assert.Equal(t, []string{}, taskAgent.AllowedTools)
 }
 
+func TestConfig_setupAgentsWithCustomAgents(t *testing.T) {
+	cfg := &Config{
+		Options: &Options{
+			ContextPaths:  []string{"CRUSH.md"},
+			DisabledTools: []string{"fetch"},
+		},
+		Agents: map[string]Agent{
+			"reviewer": {
+				Name:         "Reviewer",
+				Prompt:       ".crush/reviewer.md",
+				AllowedTools: []string{"view", "grep", "fetch"},
+				AllowedMCP:   map[string][]string{"github": {"get_pull_request"}},
+				AllowedLSP:   []string{},
+			},
+			"docs": {
+				Model:        SelectedModelTypeSmall,
+				ContextPaths: []string{"docs/STYLE.md"},
+			},
+			"old":   {Disabled: true},
+			"coder": {Name: "Not the coder"},
+		},
+	}
+
+	cfg.SetupAgents()
+	require.Len(t, cfg.Agents, 4)
+	assert.Equal(t, "Coder", cfg.Agents["coder"].Name)
+
+	reviewer := cfg.Agents["reviewer"]
+	assert.Equal(t, "reviewer", reviewer.ID)
+	assert.Equal(t, SelectedModelTypeLarge, reviewer.Model)
+	assert.Equal(t, []string{"view", "grep"}, reviewer.AllowedTools)
+	assert.Equal(t, []string{"CRUSH.md"}, reviewer.ContextPaths)
+	assert.True(t, reviewer.AllowsMCPTool("github", "get_pull_request"))
+	assert.False(t, reviewer.AllowsMCPTool("github", "create_pull_request"))
+	assert.False(t, reviewer.AllowsMCPTool("linear", "get_issue"))
+	assert.False(t, reviewer.AllowsLSP("gopls"))
+
+	docs := cfg.Agents["docs"]
+	assert.Equal(t, "docs", docs.Name)
+	assert.Equal(t, SelectedModelTypeSmall, docs.Model)
+	assert.Equal(t, cfg.Agents["coder"].AllowedTools, docs.AllowedTools)
+	assert.Equal(t, []string{"docs/STYLE.md"}, docs.ContextPaths)
+	assert.True(t, docs.AllowsMCPTool("linear", "get_issue"))
+	assert.True(t, docs.AllowsLSP("gopls"))
+
+	var names []string
+	for _, agent := range cfg.SelectableAgents() {
+		names = append(names, agent.Name)
+	}
+	assert.Equal(t, []string{"Coder", "Reviewer", "docs"}, names)
+}
+
 func TestConfig_configureProvidersWithDisabledProvider(t *testing.T) {
 	knownProviders := []catwalk.Provider{
 		{
@@ -728,6 +780,47 @@ func TestConfig_configureProvidersCustomProviderValidation(t *testing.T) {
 		_, exists := cfg.Providers.Get("custom")
 		require.False(t, exists)
 	})
+
+	t.Run("mock provider needs no API key, endpoint or models", func(t *testing.T) {
+		cfg := &Config{
+			Providers: csync.NewMapFrom(map[string]ProviderConfig{
+				"mock": {
+					Type:   ProviderTypeMock,
+					Script: "testdata/mock.json",
+				},
+			}),
+		}
+		cfg.setDefaults("/tmp", "")
+
+		env := env.NewFromMap(map[string]string{})
+		resolver := NewEnvironmentVariableResolver(env)
+		err := cfg.configureProviders(env, resolver, []catwalk.Provider{})
+		require.NoError(t, err)
+
+		mockProvider, exists := cfg.Providers.Get("mock")
+		require.True(t, exists)
+		require.Equal(t, "mock", mockProvider.ID)
+		require.Len(t, mockProvider.Models, 1)
+		require.Equal(t, "mock", mockProvider.Models[0].ID)
+	})
+
+	t.Run("mock provider without script is removed", func(t *testing.T) {
+		cfg := &Config{
+			Providers: csync.NewMapFrom(map[string]ProviderConfig{
+				"mock": {
+					Type: ProviderTypeMock,
+				},
+			}),
+		}
+		cfg.setDefaults("/tmp", "")
+
+		env := env.NewFromMap(map[string]string{})
+		resolver := NewEnvironmentVariableResolver(env)
+		err := cfg.configureProviders(env, resolver, []catwalk.Provider{})
+		require.NoError(t, err)
+
+		require.Equal(t, cfg.Providers.Len(), 0)
+	})
 }
 
 func TestConfig_configureProvidersEnhancedCredentialValidation(t *testing.T) {
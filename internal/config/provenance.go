@@ -0,0 +1,170 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+)
+
+const redactedValue = "********"
+
+// envReference matches $VAR and ${VAR} references resolved by the
+// VariableResolver.
+var envReference = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)\}?`)
+
+// Setting is a value of the effective configuration along with where it
+// came from.
+type Setting struct {
+	// Key is the dotted path of the value, e.g. options.tui.compact_mode.
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+	// Source is the config file and line that set the value, "provider
+	// catalog" for providers configured from Catwalk, or "default". Values
+	// resolved from environment variables also name the variables.
+	Source string `json:"source"`
+}
+
+// ConfigPaths returns the config files merged for workingDir, from the lowest
+// to the highest priority. Some of them might not exist.
+func ConfigPaths(workingDir string) []string {
+	return lookupConfigs(workingDir)
+}
+
+// Settings returns every value of the effective configuration, sorted by
+// key, with its source. Secrets like API keys and headers are redacted
+// unless they reference environment variables.
+func (c *Config) Settings() ([]Setting, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	effective, _, err := decodeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	files := fileSources(lookupConfigs(c.workingDir))
+
+	var settings []Setting
+	flatten(effective, "", func(key string, value any) {
+		settings = append(settings, Setting{
+			Key:    key,
+			Value:  redact(key, value),
+			Source: c.source(key, value, files),
+		})
+	})
+	slices.SortFunc(settings, func(a, b Setting) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return settings, nil
+}
+
+func (c *Config) source(key string, value any, files map[string]string) string {
+	source, ok := files[key]
+	if !ok {
+		source = "default"
+		if id, ok := providerID(key); ok && slices.ContainsFunc(c.knownProviders, func(p catwalk.Provider) bool {
+			return string(p.ID) == id
+		}) {
+			source = "provider catalog"
+		}
+	}
+	if s, ok := value.(string); ok {
+		if vars := envReferences(s); len(vars) > 0 {
+			source += " via $" + strings.Join(vars, ", $")
+		}
+	}
+	return source
+}
+
+// fileSources returns the file and line each leaf key was last set at.
+func fileSources(paths []string) map[string]string {
+	sources := make(map[string]string)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		value, _, err := decodeJSON(data)
+		if err != nil {
+			continue
+		}
+		offsets := keyOffsets(data)
+		flatten(value, "", func(key string, _ any) {
+			line, _ := position(data, offsets[key])
+			sources[key] = fmt.Sprintf("%s:%d", path, line)
+		})
+	}
+	return sources
+}
+
+// flatten calls fn with the dotted key of every leaf of value. Arrays and
+// scalars are leaves, empty objects are skipped.
+func flatten(value any, key string, fn func(key string, value any)) {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		if key != "" {
+			fn(key, value)
+		}
+		return
+	}
+	for name, child := range obj {
+		flatten(child, joinKey(key, name), fn)
+	}
+}
+
+// envReferences returns the environment variables referenced by s. As
+// CRUSH_ prefixed variables take precedence, those are returned when set.
+func envReferences(s string) []string {
+	var vars []string
+	for _, match := range envReference.FindAllStringSubmatch(s, -1) {
+		name := match[1]
+		if _, ok := os.LookupEnv("CRUSH_" + name); ok {
+			name = "CRUSH_" + name
+		}
+		vars = append(vars, name)
+	}
+	return vars
+}
+
+func providerID(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, "providers.")
+	if !ok {
+		return "", false
+	}
+	id, _, _ := strings.Cut(rest, ".")
+	return id, true
+}
+
+// redact hides the value of secret keys: API keys, headers and environment
+// variables. Values that only reference environment variables are kept.
+func redact(key string, value any) any {
+	s, ok := value.(string)
+	if !ok || s == "" || !isSecretKey(key) {
+		return value
+	}
+	if envReference.ReplaceAllString(s, "") == "" {
+		return value
+	}
+	return redactedValue
+}
+
+func isSecretKey(key string) bool {
+	parts := strings.Split(key, ".")
+	if parts[len(parts)-1] == "api_key" {
+		return true
+	}
+	if len(parts) < 2 {
+		return false
+	}
+	switch parts[len(parts)-2] {
+	case "extra_headers", "headers", "env":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Settings(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	project := filepath.Join(dir, "project")
+	require.NoError(t, os.MkdirAll(project, 0o755))
+	configPath := filepath.Join(project, "crush.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{
+  "providers": {
+    "custom": {
+      "api_key": "secret",
+      "extra_headers": {"X-Token": "$CUSTOM_TOKEN"}
+    }
+  },
+  "options": {
+    "debug": true
+  }
+}`), 0o644))
+
+	cfg := &Config{
+		Providers: csync.NewMap[string, ProviderConfig](),
+		Options:   &Options{Debug: true},
+		knownProviders: []catwalk.Provider{
+			{ID: "openai"},
+		},
+	}
+	cfg.workingDir = project
+	cfg.Providers.Set("custom", ProviderConfig{
+		APIKey:       "secret",
+		ExtraHeaders: map[string]string{"X-Token": "$CUSTOM_TOKEN"},
+	})
+	cfg.Providers.Set("openai", ProviderConfig{
+		ID:     "openai",
+		APIKey: "$OPENAI_API_KEY",
+	})
+
+	settings, err := cfg.Settings()
+	require.NoError(t, err)
+
+	got := make(map[string]Setting)
+	for _, s := range settings {
+		got[s.Key] = s
+	}
+
+	require.Equal(t, Setting{
+		Key:    "providers.custom.api_key",
+		Value:  redactedValue,
+		Source: configPath + ":4",
+	}, got["providers.custom.api_key"])
+	require.Equal(t, Setting{
+		Key:    "providers.custom.extra_headers.X-Token",
+		Value:  "$CUSTOM_TOKEN",
+		Source: configPath + ":5 via $CUSTOM_TOKEN",
+	}, got["providers.custom.extra_headers.X-Token"])
+	require.Equal(t, Setting{
+		Key:    "providers.openai.api_key",
+		Value:  "$OPENAI_API_KEY",
+		Source: "provider catalog via $OPENAI_API_KEY",
+	}, got["providers.openai.api_key"])
+	require.Equal(t, Setting{
+		Key:    "options.debug",
+		Value:  true,
+		Source: configPath + ":9",
+	}, got["options.debug"])
+	require.Equal(t, "provider catalog", got["providers.openai.id"].Source)
+}
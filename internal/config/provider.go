@@ -202,6 +202,14 @@ func loadProviders(autoUpdateDisabled bool, client ProviderClient, path string)
 	}
 }
 
+// ProvidersCacheStatus returns the path of the providers cache and whether
+// it exists and is stale.
+func ProvidersCacheStatus() (path string, stale, exists bool) {
+	path = providerCacheFileData()
+	stale, exists = isCacheStale(path)
+	return path, stale, exists
+}
+
 func isCacheStale(path string) (stale, exists bool) {
 	info, err := os.Stat(path)
 	if err != nil {
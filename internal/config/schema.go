@@ -0,0 +1,14 @@
+package config
+
+import (
+	"encoding/json"
+
+	"github.com/invopop/jsonschema"
+)
+
+// Schema returns the JSON schema of the configuration file, the same one
+// published as schema.json.
+func Schema() ([]byte, error) {
+	reflector := new(jsonschema.Reflector)
+	return json.MarshalIndent(reflector.Reflect(&Config{}), "", "  ")
+}
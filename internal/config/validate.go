@@ -0,0 +1,380 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValidationError is a problem found in a configuration file.
+type ValidationError struct {
+	// Key is the dotted path of the offending value, empty for syntax errors.
+	Key     string `json:"key,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Key, e.Message)
+}
+
+// ValidateFile validates the configuration file at path against the
+// configuration schema.
+func ValidateFile(path string) ([]ValidationError, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return Validate(data)
+}
+
+// Validate validates a configuration file against the configuration schema.
+// Every problem is reported with the line and column it was found at. The
+// returned error is only set when the schema itself can't be used.
+func Validate(data []byte) ([]ValidationError, error) {
+	schemaData, err := Schema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema: %w", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(schemaData, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if err := checkSchema(schema, "#"); err != nil {
+		return nil, err
+	}
+
+	value, offset, err := decodeJSON(data)
+	if err != nil {
+		line, col := position(data, offset)
+		return []ValidationError{{Line: line, Column: col, Message: err.Error()}}, nil
+	}
+
+	defs, _ := schema["$defs"].(map[string]any)
+	v := &validator{
+		data:    data,
+		defs:    defs,
+		offsets: keyOffsets(data),
+	}
+	v.validate(schema, value, "")
+	return v.errs, nil
+}
+
+// decodeJSON decodes data, returning the offset of the syntax error on
+// failure.
+func decodeJSON(data []byte) (any, int64, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			// The offset is just past the offending character.
+			return nil, syntaxErr.Offset - 1, err
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, 0, errors.New("file is empty")
+		}
+		return nil, int64(len(data)), err
+	}
+	offset := dec.InputOffset()
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, offset, errors.New("unexpected data after top-level value")
+	}
+	return value, 0, nil
+}
+
+// schemaKeywords are the JSON schema keywords the validator knows. Only $ref,
+// type, enum, minimum, maximum, properties, additionalProperties and items
+// are checked, the others are annotations. Required properties aren't
+// checked, as they can be set by another config file or by the defaults.
+var schemaKeywords = []string{
+	"$schema", "$id", "$ref", "$defs",
+	"type", "enum", "minimum", "maximum",
+	"properties", "additionalProperties", "items", "required",
+	"title", "description", "default", "examples", "format",
+}
+
+// checkSchema returns an error when schema uses a keyword the validator
+// doesn't know, so that a change to the configuration can't make it accept
+// files the schema rejects.
+func checkSchema(schema map[string]any, path string) error {
+	keywords := make([]string, 0, len(schema))
+	for keyword := range schema {
+		keywords = append(keywords, keyword)
+	}
+	slices.Sort(keywords)
+	for _, keyword := range keywords {
+		if !slices.Contains(schemaKeywords, keyword) {
+			return fmt.Errorf("schema keyword %q at %s is not supported", keyword, path)
+		}
+	}
+	if typ, ok := schema["type"]; ok {
+		if _, ok := typ.(string); !ok {
+			return fmt.Errorf("schema type at %s is not supported: %v", path, typ)
+		}
+	}
+
+	for _, keyword := range []string{"$defs", "properties"} {
+		children, _ := schema[keyword].(map[string]any)
+		names := make([]string, 0, len(children))
+		for name := range children {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if child, ok := children[name].(map[string]any); ok {
+				if err := checkSchema(child, path+"/"+keyword+"/"+name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, keyword := range []string{"items", "additionalProperties"} {
+		if child, ok := schema[keyword].(map[string]any); ok {
+			if err := checkSchema(child, path+"/"+keyword); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validator checks decoded JSON against the schema keywords it knows.
+type validator struct {
+	data    []byte
+	defs    map[string]any
+	offsets map[string]int64
+	errs    []ValidationError
+}
+
+func (v *validator) validate(schema map[string]any, value any, key string) {
+	schema = v.resolve(schema)
+	if schema == nil {
+		return
+	}
+
+	if typ, ok := schema["type"].(string); ok && !matchesType(typ, value) {
+		v.fail(key, "expected %s, got %s", typ, typeName(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		if !slices.ContainsFunc(enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+			options := make([]string, len(enum))
+			for i, e := range enum {
+				options[i] = fmt.Sprintf("%q", fmt.Sprint(e))
+			}
+			v.fail(key, "must be one of %s", strings.Join(options, ", "))
+		}
+	}
+
+	if n, ok := value.(json.Number); ok {
+		f, _ := n.Float64()
+		if minimum, ok := schema["minimum"].(float64); ok && f < minimum {
+			v.fail(key, "must be at least %v", minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && f > maximum {
+			v.fail(key, "must be at most %v", maximum)
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, value, key)
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				v.validate(items, item, joinKey(key, strconv.Itoa(i)))
+			}
+		}
+	}
+}
+
+func (v *validator) validateObject(schema map[string]any, value map[string]any, key string) {
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return int(v.offsets[joinKey(key, a)] - v.offsets[joinKey(key, b)])
+	})
+
+	for _, name := range names {
+		child := joinKey(key, name)
+		if prop, ok := properties[name].(map[string]any); ok {
+			v.validate(prop, value[name], child)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(child, "unknown property")
+			}
+		case map[string]any:
+			v.validate(additional, value[name], child)
+		}
+	}
+}
+
+// resolve follows $ref pointers to the schema definitions.
+func (v *validator) resolve(schema map[string]any) map[string]any {
+	for range 32 {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		def, ok := v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		if !ok {
+			return nil
+		}
+		schema = def
+	}
+	return nil
+}
+
+// fail records an error at the position of key, or of its closest parent
+// when key itself has no position, like array items.
+func (v *validator) fail(key, format string, args ...any) {
+	offset := int64(0)
+	for k := key; ; {
+		if off, ok := v.offsets[k]; ok {
+			offset = off
+			break
+		}
+		i := strings.LastIndex(k, ".")
+		if i < 0 {
+			break
+		}
+		k = k[:i]
+	}
+	line, col := position(v.data, offset)
+	v.errs = append(v.errs, ValidationError{
+		Key:     key,
+		Line:    line,
+		Column:  col,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func matchesType(typ string, value any) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// keyOffsets returns the byte offset of every object key in data, keyed by
+// its dotted path.
+func keyOffsets(data []byte) map[string]int64 {
+	offsets := make(map[string]int64)
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var walk func(key string) error
+	walk = func(key string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			return nil
+		}
+		switch delim {
+		case '{':
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				name, _ := tok.(string)
+				child := joinKey(key, name)
+				// The offset is just past the closing quote of the key, go
+				// back to its opening quote.
+				end := dec.InputOffset()
+				offsets[child] = max(int64(bytes.LastIndexByte(data[:end-1], '"')), 0)
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		case '[':
+			for i := 0; dec.More(); i++ {
+				if err := walk(joinKey(key, strconv.Itoa(i))); err != nil {
+					return err
+				}
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+	_ = walk("")
+	return offsets
+}
+
+// position converts a byte offset in data to a 1-based line and column.
+func position(data []byte, offset int64) (line, col int) {
+	offset = min(max(offset, 0), int64(len(data)))
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+func joinKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
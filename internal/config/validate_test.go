@@ -0,0 +1,95 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+		want []ValidationError
+	}{
+		{
+			name: "valid config",
+			data: `{
+  "$schema": "https://charm.land/crush.json",
+  "lsp": {
+    "gopls": {}
+  },
+  "options": {
+    "tui": {"compact_mode": true}
+  }
+}`,
+		},
+		{
+			name: "syntax error",
+			data: "{\n  \"options\": {\n    \"debug\": true,\n  }\n}",
+			want: []ValidationError{{Line: 4, Column: 3, Message: "invalid character '}' looking for beginning of object key string"}},
+		},
+		{
+			name: "empty file",
+			data: "",
+			want: []ValidationError{{Line: 1, Column: 1, Message: "file is empty"}},
+		},
+		{
+			name: "unknown property and wrong type",
+			data: "{\n  \"option\": {},\n  \"options\": {\n    \"debug\": \"yes\"\n  }\n}",
+			want: []ValidationError{
+				{Key: "option", Line: 2, Column: 3, Message: "unknown property"},
+				{Key: "options.debug", Line: 4, Column: 5, Message: "expected boolean, got string"},
+			},
+		},
+		{
+			name: "enum and maximum",
+			data: "{\n  \"models\": {\n    \"large\": {\n      \"model\": \"gpt-4o\",\n      \"provider\": \"openai\",\n      \"reasoning_effort\": \"extreme\",\n      \"max_tokens\": 1000000\n    }\n  }\n}",
+			want: []ValidationError{
+				{Key: "models.large.reasoning_effort", Line: 6, Column: 7, Message: `must be one of "low", "medium", "high"`},
+				{Key: "models.large.max_tokens", Line: 7, Column: 7, Message: "must be at most 200000"},
+			},
+		},
+		{
+			name: "array items",
+			data: "{\n  \"lsp\": {\n    \"gopls\": {\n      \"args\": [\"serve\", 1]\n    }\n  }\n}",
+			want: []ValidationError{{Key: "lsp.gopls.args.1", Line: 4, Column: 7, Message: "expected string, got number"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			errs, err := Validate([]byte(tt.data))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, errs)
+		})
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	t.Parallel()
+
+	schemaData, err := Schema()
+	require.NoError(t, err)
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(schemaData, &schema))
+	require.NoError(t, checkSchema(schema, "#"))
+
+	err = checkSchema(map[string]any{
+		"$defs": map[string]any{
+			"Options": map[string]any{
+				"properties": map[string]any{
+					"debug": map[string]any{"anyOf": []any{}},
+				},
+			},
+		},
+	}, "#")
+	require.EqualError(t, err, `schema keyword "anyOf" at #/$defs/Options/properties/debug is not supported`)
+
+	err = checkSchema(map[string]any{"type": []any{"string", "null"}}, "#")
+	require.EqualError(t, err, "schema type at # is not supported: [string null]")
+}
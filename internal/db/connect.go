@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"os"
 	"path/filepath"
 
 	"github.com/ncruces/go-sqlite3"
@@ -62,3 +63,55 @@ func Connect(ctx context.Context, dataDir string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// OpenReadOnly opens the database in dataDir without creating it or applying
+// migrations, so it can be inspected without changing it. It fails with an
+// error wrapping fs.ErrNotExist if there is no database yet.
+func OpenReadOnly(ctx context.Context, dataDir string) (*sql.DB, error) {
+	dbPath := filepath.Join(dataDir, "crush.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, err
+	}
+
+	dsn := "file:" + filepath.ToSlash(dbPath) + "?mode=ro"
+	db, err := driver.Open(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
+
+// MigrationVersions returns the migration version of the database and the
+// version of the latest migration known to this build.
+func MigrationVersions(ctx context.Context, db *sql.DB) (current, latest int64, err error) {
+	goose.SetBaseFS(FS)
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		return 0, 0, fmt.Errorf("failed to set dialect: %w", err)
+	}
+	// Getting the version creates the version table of a database that was
+	// never migrated, which fails on a read-only connection.
+	var tables int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", goose.TableName()).Scan(&tables)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get database version: %w", err)
+	}
+	if tables > 0 {
+		current, err = goose.GetDBVersionContext(ctx, db)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get database version: %w", err)
+		}
+	}
+	migrations, err := goose.CollectMigrations("migrations", 0, goose.MaxVersion)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to collect migrations: %w", err)
+	}
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get latest migration: %w", err)
+	}
+	return current, last.Version, nil
+}
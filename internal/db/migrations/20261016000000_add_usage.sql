@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Usage of every provider request. Rows are kept when their session is
+-- deleted so spend can still be reported.
+CREATE TABLE IF NOT EXISTS usage (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    message_id TEXT,
+    model TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    input_tokens INTEGER NOT NULL DEFAULT 0 CHECK (input_tokens >= 0),
+    output_tokens INTEGER NOT NULL DEFAULT 0 CHECK (output_tokens >= 0),
+    cache_creation_tokens INTEGER NOT NULL DEFAULT 0 CHECK (cache_creation_tokens >= 0),
+    cache_read_tokens INTEGER NOT NULL DEFAULT 0 CHECK (cache_read_tokens >= 0),
+    cost REAL NOT NULL DEFAULT 0.0 CHECK (cost >= 0.0),
+    created_at INTEGER NOT NULL  -- Unix timestamp in seconds
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_created_at ON usage (created_at);
+CREATE INDEX IF NOT EXISTS idx_usage_session_id ON usage (session_id);
+
+-- Best-effort backfill from the cost of existing top-level sessions, which
+-- already includes the cost of their task sessions. Sessions only keep the
+-- token counts of their last request, so no tokens are backfilled. Usage is
+-- attributed to the model of the last assistant message of the session.
+INSERT INTO usage (id, session_id, model, provider, cost, created_at)
+SELECT
+    'backfill-' || s.id,
+    s.id,
+    COALESCE((
+        SELECT m.model FROM messages m
+        WHERE m.session_id = s.id AND m.role = 'assistant' AND m.model IS NOT NULL
+        ORDER BY m.created_at DESC LIMIT 1
+    ), 'unknown'),
+    COALESCE((
+        SELECT m.provider FROM messages m
+        WHERE m.session_id = s.id AND m.role = 'assistant' AND m.provider IS NOT NULL
+        ORDER BY m.created_at DESC LIMIT 1
+    ), 'unknown'),
+    s.cost,
+    s.updated_at
+FROM sessions s
+WHERE s.parent_session_id IS NULL AND s.cost > 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_usage_session_id;
+DROP INDEX IF EXISTS idx_usage_created_at;
+DROP TABLE IF EXISTS usage;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- What a request was made for: 'agent' for the requests of the agent loop,
+-- 'title' and 'summarize' for session titles and summaries, and 'backfill'
+-- for the rows backfilled from the cost of existing sessions.
+ALTER TABLE usage ADD COLUMN kind TEXT NOT NULL DEFAULT 'agent';
+
+-- Best-effort classification of the existing rows: titles are the only
+-- requests recorded without a message, and summaries are only known while
+-- they are the current summary of their session.
+UPDATE usage SET kind = 'backfill' WHERE id LIKE 'backfill-%';
+UPDATE usage SET kind = 'title' WHERE kind = 'agent' AND message_id IS NULL;
+UPDATE usage SET kind = 'summarize'
+WHERE kind = 'agent' AND message_id IN (
+    SELECT summary_message_id FROM sessions WHERE summary_message_id IS NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE usage DROP COLUMN kind;
+-- +goose StatementEnd
@@ -6,29 +6,42 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 type Querier interface {
 	CreateFile(ctx context.Context, arg CreateFileParams) (File, error)
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateTodo(ctx context.Context, arg CreateTodoParams) (Todo, error)
+	CreateUsage(ctx context.Context, arg CreateUsageParams) error
 	DeleteFile(ctx context.Context, id string) error
 	DeleteMessage(ctx context.Context, id string) error
 	DeleteSession(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	DeleteTodo(ctx context.Context, id string) error
 	GetFile(ctx context.Context, id string) (File, error)
 	GetFileByPathAndSession(ctx context.Context, arg GetFileByPathAndSessionParams) (File, error)
 	GetMessage(ctx context.Context, id string) (Message, error)
 	GetSessionByID(ctx context.Context, id string) (Session, error)
+	GetSessionUsage(ctx context.Context, sessionID string) (GetSessionUsageRow, error)
+	GetTodo(ctx context.Context, id string) (Todo, error)
+	ImportFile(ctx context.Context, arg ImportFileParams) error
+	ImportMessage(ctx context.Context, arg ImportMessageParams) error
+	ImportSession(ctx context.Context, arg ImportSessionParams) error
+	ListChildSessions(ctx context.Context, parentSessionID sql.NullString) ([]Session, error)
 	ListFilesByPath(ctx context.Context, path string) ([]File, error)
 	ListFilesBySession(ctx context.Context, sessionID string) ([]File, error)
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
 	ListMessagesBySession(ctx context.Context, sessionID string) ([]Message, error)
 	ListNewFiles(ctx context.Context) ([]File, error)
 	ListSessions(ctx context.Context) ([]Session, error)
+	ListTodosBySession(ctx context.Context, sessionID string) ([]Todo, error)
+	ListUsage(ctx context.Context, arg ListUsageParams) ([]Usage, error)
 	UpdateMessage(ctx context.Context, arg UpdateMessageParams) error
 	UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error)
+	UpdateTodo(ctx context.Context, arg UpdateTodoParams) (Todo, error)
 }
 
 var _ Querier = (*Queries)(nil)
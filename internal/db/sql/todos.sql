@@ -0,0 +1,37 @@
+-- name: GetTodo :one
+SELECT *
+FROM todos
+WHERE id = ? LIMIT 1;
+
+-- name: ListTodosBySession :many
+SELECT *
+FROM todos
+WHERE session_id = ?
+ORDER BY position ASC;
+
+-- name: CreateTodo :one
+INSERT INTO todos (
+    id,
+    session_id,
+    content,
+    status,
+    position,
+    created_at,
+    updated_at
+) VALUES (
+    ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
+)
+RETURNING *;
+
+-- name: UpdateTodo :one
+UPDATE todos
+SET
+    content = ?,
+    status = ?,
+    updated_at = strftime('%s', 'now')
+WHERE id = ?
+RETURNING *;
+
+-- name: DeleteTodo :exec
+DELETE FROM todos
+WHERE id = ?;
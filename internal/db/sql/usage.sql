@@ -0,0 +1,33 @@
+-- name: CreateUsage :exec
+INSERT INTO usage (
+    id,
+    session_id,
+    message_id,
+    model,
+    provider,
+    input_tokens,
+    output_tokens,
+    cache_creation_tokens,
+    cache_read_tokens,
+    cost,
+    kind,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, strftime('%s', 'now')
+);
+
+-- name: GetSessionUsage :one
+SELECT
+    CAST(COALESCE(SUM(kind = 'agent'), 0) AS INTEGER) AS agent_requests,
+    CAST(COALESCE(SUM(input_tokens + output_tokens + cache_creation_tokens + cache_read_tokens), 0) AS INTEGER) AS tokens,
+    CAST(COALESCE(SUM(input_tokens + cache_creation_tokens), 0) AS INTEGER) AS prompt_tokens,
+    CAST(COALESCE(SUM(output_tokens + cache_read_tokens), 0) AS INTEGER) AS completion_tokens,
+    CAST(COALESCE(SUM(cost), 0.0) AS REAL) AS cost
+FROM usage
+WHERE session_id = ?;
+
+-- name: ListUsage :many
+SELECT *
+FROM usage
+WHERE created_at >= sqlc.arg(since) AND created_at < sqlc.arg(until)
+ORDER BY created_at ASC;
@@ -0,0 +1,429 @@
+// Package doctor checks the environment Crush runs in and reports problems
+// along with hints on how to fix them.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/home"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/lsp"
+)
+
+const defaultLSPTimeout = 30 * time.Second
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusWarning Status = "warning"
+	StatusError   Status = "error"
+	StatusSkipped Status = "skipped"
+)
+
+// Category groups related checks.
+type Category string
+
+const (
+	CategoryConfig    Category = "config"
+	CategoryDataDir   Category = "data_dir"
+	CategoryDatabase  Category = "database"
+	CategoryRipgrep   Category = "ripgrep"
+	CategoryProviders Category = "providers"
+	CategoryLSP       Category = "lsp"
+	CategoryMCP       Category = "mcp"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Category Category `json:"category"`
+	Name     string   `json:"name"`
+	Status   Status   `json:"status"`
+	Message  string   `json:"message"`
+	// Hint tells how to fix a warning or an error.
+	Hint string `json:"hint,omitempty"`
+}
+
+// Report is the outcome of all checks, in a stable order.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Failed reports whether any check failed.
+func (r Report) Failed() bool {
+	return slices.ContainsFunc(r.Results, func(res Result) bool {
+		return res.Status == StatusError
+	})
+}
+
+// ProviderTester checks that a provider accepts its credentials, ideally
+// with a cheap request.
+type ProviderTester func(ctx context.Context, p config.ProviderConfig) error
+
+// Options configures the checks.
+type Options struct {
+	// TestProvider checks provider credentials. It defaults to
+	// ProviderConfig.TestConnection.
+	TestProvider ProviderTester
+	// LSPTimeout bounds the startup of each LSP server. MCP servers use
+	// their configured timeout.
+	LSPTimeout time.Duration
+}
+
+// Run runs all checks for cfg concurrently and returns their results.
+func Run(ctx context.Context, cfg *config.Config, opts Options) Report {
+	if opts.TestProvider == nil {
+		opts.TestProvider = func(_ context.Context, p config.ProviderConfig) error {
+			return p.TestConnection(cfg.Resolver())
+		}
+	}
+	if opts.LSPTimeout <= 0 {
+		opts.LSPTimeout = defaultLSPTimeout
+	}
+
+	checks := []func() Result{
+		func() Result { return checkDataDir(cfg.Options.DataDirectory) },
+		func() Result { return checkDatabase(ctx, cfg.Options.DataDirectory) },
+		func() Result { return checkRipgrep(ctx) },
+		checkProvidersCache,
+	}
+	for _, path := range config.ConfigPaths(cfg.WorkingDir()) {
+		if _, err := os.Stat(path); err == nil {
+			checks = append(checks, func() Result { return checkConfigFile(path) })
+		}
+	}
+
+	providers := cfg.EnabledProviders()
+	slices.SortFunc(providers, func(a, b config.ProviderConfig) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	if len(providers) == 0 {
+		checks = append(checks, func() Result {
+			return Result{
+				Category: CategoryProviders,
+				Name:     "providers",
+				Status:   StatusError,
+				Message:  "no providers configured",
+				Hint:     "set an API key like $ANTHROPIC_API_KEY or $OPENAI_API_KEY, or configure a provider in crush.json",
+			}
+		})
+	}
+	for _, p := range providers {
+		checks = append(checks, func() Result { return checkProvider(ctx, cfg.Resolver(), p, opts.TestProvider) })
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.LSP)) {
+		checks = append(checks, func() Result { return checkLSP(ctx, cfg, name, cfg.LSP[name], opts.LSPTimeout) })
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.MCP)) {
+		checks = append(checks, func() Result { return checkMCP(ctx, cfg, name, cfg.MCP[name]) })
+	}
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Go(func() {
+			results[i] = check()
+		})
+	}
+	wg.Wait()
+
+	slices.SortStableFunc(results, func(a, b Result) int {
+		return categoryOrder(a.Category) - categoryOrder(b.Category)
+	})
+	return Report{Results: results}
+}
+
+func categoryOrder(c Category) int {
+	return slices.Index([]Category{
+		CategoryConfig,
+		CategoryDataDir,
+		CategoryDatabase,
+		CategoryRipgrep,
+		CategoryProviders,
+		CategoryLSP,
+		CategoryMCP,
+	}, c)
+}
+
+func checkConfigFile(path string) Result {
+	r := Result{Category: CategoryConfig, Name: path}
+	errs, err := config.ValidateFile(path)
+	switch {
+	case err != nil:
+		r.Status = StatusError
+		r.Message = err.Error()
+	case len(errs) > 0:
+		r.Status = StatusWarning
+		r.Message = fmt.Sprintf("line %s", errs[0].Error())
+		if len(errs) > 1 {
+			r.Message += fmt.Sprintf(" (and %d more)", len(errs)-1)
+		}
+		r.Hint = "run crush config validate for details"
+	default:
+		r.Status = StatusOK
+		r.Message = "valid"
+	}
+	return r
+}
+
+func checkDataDir(dir string) Result {
+	r := Result{Category: CategoryDataDir, Name: dir}
+	info, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err):
+		r.Status = StatusWarning
+		r.Message = "does not exist yet"
+		r.Hint = "it is created the first time Crush runs in this project"
+		return r
+	case err != nil:
+		r.Status = StatusError
+		r.Message = err.Error()
+		r.Hint = "check the permissions of the parent directory"
+		return r
+	case !info.IsDir():
+		r.Status = StatusError
+		r.Message = "is not a directory"
+		r.Hint = "remove it or set options.data_directory to another path"
+		return r
+	}
+
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		r.Status = StatusError
+		r.Message = "is not writable"
+		r.Hint = fmt.Sprintf("run chmod u+rwx %s", dir)
+		return r
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		r.Status = StatusWarning
+		r.Message = fmt.Sprintf("is accessible by other users (%s)", perm)
+		r.Hint = fmt.Sprintf("sessions and file history are stored here, run chmod 700 %s", dir)
+		return r
+	}
+
+	r.Status = StatusOK
+	r.Message = "writable"
+	return r
+}
+
+// checkDatabase opens the database read-only, so that it can report the
+// migrations that are pending without applying them.
+func checkDatabase(ctx context.Context, dataDir string) Result {
+	r := Result{Category: CategoryDatabase, Name: "crush.db"}
+	if _, err := os.Stat(dataDir); err != nil {
+		r.Status = StatusSkipped
+		r.Message = "data directory is not available"
+		return r
+	}
+
+	conn, err := db.OpenReadOnly(ctx, dataDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		r.Status = StatusOK
+		r.Message = "not created yet, Crush creates it on first start"
+		return r
+	}
+	if err != nil {
+		r.Status = StatusError
+		r.Message = err.Error()
+		r.Hint = "check the permissions of crush.db, or move it away to start with a fresh database"
+		return r
+	}
+	defer conn.Close()
+
+	current, latest, err := db.MigrationVersions(ctx, conn)
+	if err != nil {
+		r.Status = StatusError
+		r.Message = err.Error()
+		r.Hint = "move crush.db away to start with a fresh database"
+		return r
+	}
+	switch {
+	case current < latest:
+		r.Status = StatusWarning
+		r.Message = fmt.Sprintf("at migration %d, pending migrations up to %d", current, latest)
+		r.Hint = "Crush applies them on next start"
+	case current > latest:
+		r.Status = StatusError
+		r.Message = fmt.Sprintf("at migration %d, newer than this version of Crush knows (%d)", current, latest)
+		r.Hint = "update Crush, the database was migrated by a newer version"
+	default:
+		r.Status = StatusOK
+		r.Message = fmt.Sprintf("migrations up to date (version %d)", current)
+	}
+	return r
+}
+
+func checkRipgrep(ctx context.Context) Result {
+	r := Result{Category: CategoryRipgrep, Name: "rg"}
+	path, err := exec.LookPath("rg")
+	if err != nil {
+		r.Status = StatusWarning
+		r.Message = "not found in $PATH, grep and glob fall back to slower built-in search"
+		r.Hint = "install ripgrep: https://github.com/BurntSushi/ripgrep#installation"
+		return r
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		r.Status = StatusError
+		r.Message = fmt.Sprintf("%s --version failed: %s", path, err)
+		r.Hint = "reinstall ripgrep"
+		return r
+	}
+	version, _, _ := strings.Cut(string(out), "\n")
+	r.Status = StatusOK
+	r.Message = fmt.Sprintf("%s (%s)", version, path)
+	return r
+}
+
+func checkProvidersCache() Result {
+	r := Result{Category: CategoryProviders, Name: "catalog"}
+	path, stale, exists := config.ProvidersCacheStatus()
+	switch {
+	case !exists:
+		r.Status = StatusWarning
+		r.Message = "no cached provider catalog"
+		r.Hint = "run crush update-providers"
+	case stale:
+		r.Status = StatusWarning
+		r.Message = fmt.Sprintf("cached provider catalog is older than a day (%s)", path)
+		r.Hint = "run crush update-providers, or check your network if it keeps going stale"
+	default:
+		r.Status = StatusOK
+		r.Message = fmt.Sprintf("cached provider catalog is up to date (%s)", path)
+	}
+	return r
+}
+
+func checkProvider(ctx context.Context, resolver config.VariableResolver, p config.ProviderConfig, test ProviderTester) Result {
+	r := Result{Category: CategoryProviders, Name: p.ID}
+	switch p.Type {
+	case catwalk.TypeOpenAI, catwalk.TypeAnthropic, catwalk.TypeGemini:
+	default:
+		r.Status = StatusSkipped
+		r.Message = fmt.Sprintf("credentials of %s providers can't be checked", p.Type)
+		return r
+	}
+
+	if key, err := resolver.ResolveValue(p.APIKey); err != nil || key == "" {
+		r.Status = StatusError
+		r.Message = "API key is not set"
+		if p.APIKey != "" {
+			r.Hint = fmt.Sprintf("set %s in your environment", p.APIKey)
+		} else {
+			r.Hint = fmt.Sprintf("set providers.%s.api_key in crush.json", p.ID)
+		}
+		return r
+	}
+
+	if err := test(ctx, p); err != nil {
+		r.Status = StatusError
+		r.Message = err.Error()
+		r.Hint = "check the API key and the base URL of the provider"
+		return r
+	}
+	r.Status = StatusOK
+	r.Message = "credentials accepted"
+	return r
+}
+
+func checkLSP(ctx context.Context, cfg *config.Config, name string, lspCfg config.LSPConfig, timeout time.Duration) Result {
+	r := Result{Category: CategoryLSP, Name: name}
+	if lspCfg.Disabled {
+		r.Status = StatusSkipped
+		r.Message = "disabled"
+		return r
+	}
+	if !lsp.HasRootMarkers(cfg.WorkingDir(), lspCfg.RootMarkers) {
+		r.Status = StatusSkipped
+		r.Message = "no root markers found in the working directory"
+		return r
+	}
+
+	command, err := cfg.Resolver().ResolveValue(lspCfg.Command)
+	if err != nil {
+		r.Status = StatusError
+		r.Message = fmt.Sprintf("invalid command: %s", err)
+		r.Hint = fmt.Sprintf("fix lsp.%s.command in crush.json", name)
+		return r
+	}
+	if _, err := exec.LookPath(home.Long(command)); err != nil {
+		r.Status = StatusError
+		r.Message = fmt.Sprintf("%s not found in $PATH", command)
+		r.Hint = fmt.Sprintf("install %s or set lsp.%s.command in crush.json", command, name)
+		return r
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client, err := lsp.New(ctx, name, lspCfg, cfg.Resolver())
+	if err != nil {
+		r.Status = StatusError
+		r.Message = err.Error()
+		return r
+	}
+	defer client.Close(context.Background())
+
+	if _, err := client.Initialize(ctx, cfg.WorkingDir()); err != nil {
+		r.Status = StatusError
+		r.Message = timeoutMessage(err, timeout)
+		r.Hint = "check the logs with crush logs"
+		return r
+	}
+	if err := client.WaitForServerReady(ctx); err != nil {
+		r.Status = StatusWarning
+		r.Message = fmt.Sprintf("started but not ready: %s", timeoutMessage(err, timeout))
+		r.Hint = "the server might still work, check the logs with crush logs"
+		return r
+	}
+	r.Status = StatusOK
+	r.Message = fmt.Sprintf("%s started", command)
+	return r
+}
+
+func checkMCP(ctx context.Context, cfg *config.Config, name string, m config.MCPConfig) Result {
+	r := Result{Category: CategoryMCP, Name: name}
+	if m.Disabled {
+		r.Status = StatusSkipped
+		r.Message = "disabled"
+		return r
+	}
+	count, err := agent.ProbeMCP(ctx, name, m, cfg.Resolver())
+	if err != nil {
+		r.Status = StatusError
+		r.Message = err.Error()
+		r.Hint = fmt.Sprintf("check mcp.%s in crush.json, or raise its timeout", name)
+		return r
+	}
+	r.Status = StatusOK
+	r.Message = fmt.Sprintf("connected, %d tools", count)
+	return r
+}
+
+func timeoutMessage(err error, timeout time.Duration) string {
+	if errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		return fmt.Sprintf("timed out after %s", timeout)
+	}
+	return err.Error()
+}
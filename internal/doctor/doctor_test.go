@@ -0,0 +1,169 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckProvider(t *testing.T) {
+	t.Parallel()
+
+	resolver := config.NewShellVariableResolver(env.NewFromMap(map[string]string{
+		"GOOD_KEY": "good",
+		"BAD_KEY":  "bad",
+	}))
+	tester := func(_ context.Context, p config.ProviderConfig) error {
+		key, _ := resolver.ResolveValue(p.APIKey)
+		if key != "good" {
+			return errors.New("401 Unauthorized")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name     string
+		provider config.ProviderConfig
+		status   Status
+		hint     string
+	}{
+		{
+			name:     "accepted",
+			provider: config.ProviderConfig{ID: "openai", Type: catwalk.TypeOpenAI, APIKey: "$GOOD_KEY"},
+			status:   StatusOK,
+		},
+		{
+			name:     "rejected",
+			provider: config.ProviderConfig{ID: "anthropic", Type: catwalk.TypeAnthropic, APIKey: "$BAD_KEY"},
+			status:   StatusError,
+			hint:     "check the API key and the base URL of the provider",
+		},
+		{
+			name:     "missing key",
+			provider: config.ProviderConfig{ID: "gemini", Type: catwalk.TypeGemini, APIKey: "$MISSING_KEY"},
+			status:   StatusError,
+			hint:     "set $MISSING_KEY in your environment",
+		},
+		{
+			name:     "unsupported type",
+			provider: config.ProviderConfig{ID: "bedrock", Type: catwalk.TypeBedrock},
+			status:   StatusSkipped,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := checkProvider(t.Context(), resolver, tt.provider, tester)
+			require.Equal(t, CategoryProviders, r.Category)
+			require.Equal(t, tt.provider.ID, r.Name)
+			require.Equal(t, tt.status, r.Status)
+			require.Equal(t, tt.hint, r.Hint)
+		})
+	}
+}
+
+func TestCheckDataDir(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+		r := checkDataDir(filepath.Join(t.TempDir(), ".crush"))
+		require.Equal(t, StatusWarning, r.Status)
+	})
+
+	t.Run("private", func(t *testing.T) {
+		t.Parallel()
+		dir := filepath.Join(t.TempDir(), ".crush")
+		require.NoError(t, os.Mkdir(dir, 0o700))
+		r := checkDataDir(dir)
+		require.Equal(t, StatusOK, r.Status)
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		t.Parallel()
+		dir := filepath.Join(t.TempDir(), ".crush")
+		require.NoError(t, os.Mkdir(dir, 0o700))
+		require.NoError(t, os.Chmod(dir, 0o755))
+		r := checkDataDir(dir)
+		require.Equal(t, StatusWarning, r.Status)
+		require.Contains(t, r.Hint, "chmod 700")
+	})
+
+	t.Run("not a directory", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), ".crush")
+		require.NoError(t, os.WriteFile(path, nil, 0o600))
+		r := checkDataDir(path)
+		require.Equal(t, StatusError, r.Status)
+	})
+}
+
+func TestCheckDatabase(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing data directory", func(t *testing.T) {
+		t.Parallel()
+		r := checkDatabase(t.Context(), filepath.Join(t.TempDir(), ".crush"))
+		require.Equal(t, StatusSkipped, r.Status)
+	})
+
+	t.Run("not created yet", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		r := checkDatabase(t.Context(), dir)
+		require.Equal(t, StatusOK, r.Status)
+		require.Equal(t, "not created yet, Crush creates it on first start", r.Message)
+		require.NoFileExists(t, filepath.Join(dir, "crush.db"))
+	})
+
+	t.Run("never migrated", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "crush.db"), nil, 0o600))
+		r := checkDatabase(t.Context(), dir)
+		require.Equal(t, StatusWarning, r.Status)
+		require.Contains(t, r.Message, "at migration 0, pending migrations up to")
+	})
+
+	t.Run("up to date and pending migrations", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		conn, err := db.Connect(t.Context(), dir)
+		require.NoError(t, err)
+		defer conn.Close()
+		current, latest, err := db.MigrationVersions(t.Context(), conn)
+		require.NoError(t, err)
+		require.Equal(t, latest, current)
+
+		r := checkDatabase(t.Context(), dir)
+		require.Equal(t, StatusOK, r.Status, r.Message)
+
+		// Forget the last migration, as if the database was made by an
+		// older version.
+		_, err = conn.ExecContext(t.Context(), "DELETE FROM goose_db_version WHERE version_id = ?", latest)
+		require.NoError(t, err)
+		r = checkDatabase(t.Context(), dir)
+		require.Equal(t, StatusWarning, r.Status)
+		require.Contains(t, r.Message, "pending migrations up to")
+
+		// The check doesn't apply them.
+		current, _, err = db.MigrationVersions(t.Context(), conn)
+		require.NoError(t, err)
+		require.Less(t, current, latest)
+	})
+}
+
+func TestReportFailed(t *testing.T) {
+	t.Parallel()
+
+	require.False(t, Report{Results: []Result{{Status: StatusOK}, {Status: StatusWarning}, {Status: StatusSkipped}}}.Failed())
+	require.True(t, Report{Results: []Result{{Status: StatusOK}, {Status: StatusError}}}.Failed())
+}
@@ -126,3 +126,39 @@ func TestShouldExcludeFileCommonPatterns(t *testing.T) {
 		require.True(t, ShouldExcludeFile(tempDir, dir), "Expected %s to be ignored by common patterns", filepath.Base(dir))
 	}
 }
+
+func TestIsCrushIgnored(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "secrets"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "pkg", "internal"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "node_modules"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".crushignore"), []byte("secrets/\n*.env\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("*.txt\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "pkg", ".crushignore"), []byte("generated.go\n"), 0o644))
+
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{"secrets/key.pem", true},
+		{"prod.env", true},
+		{"pkg/internal/prod.env", true},
+		{"pkg/generated.go", true},
+		{"pkg/internal/generated.go", true},
+		{"generated.go", false},
+		{"main.go", false},
+		// Only .crushignore counts, not .gitignore or the common patterns.
+		{"notes.txt", false},
+		{"node_modules/index.js", false},
+		// Paths outside of the root are not our business.
+		{"../prod.env", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.ignored, IsCrushIgnored(tempDir, filepath.Join(tempDir, filepath.FromSlash(tt.path))))
+		})
+	}
+}
@@ -244,3 +244,60 @@ func ListDirectory(initialPath string, ignorePatterns []string, limit int) ([]st
 
 	return slices.Collect(results.Seq()), truncated, nil
 }
+
+// IsCrushIgnored reports whether path is matched by a .crushignore file in
+// rootPath or in one of the directories between rootPath and path, or by the
+// global ~/.config/crush/ignore file. Unlike [ShouldExcludeFile], it ignores
+// .gitignore files and the common ignore patterns, so it can be used to
+// enforce what the user explicitly hid from Crush.
+func IsCrushIgnored(rootPath, path string) bool {
+	absRoot, err := filepath.Abs(rootPath)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	relPath, err := filepath.Rel(absRoot, absPath)
+	if err != nil || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	global := filepath.Join(home.Dir(), ".config", "crush", "ignore")
+	if bts, err := os.ReadFile(global); err == nil {
+		if matchesIgnore(ignore.CompileIgnoreLines(strings.Split(string(bts), "\n")...), relPath) {
+			return true
+		}
+	}
+
+	// Check the .crushignore of every directory from the root down to the
+	// one containing path.
+	dir := absRoot
+	dirs := []string{dir}
+	if parent := filepath.Dir(relPath); parent != "." {
+		for part := range strings.SplitSeq(parent, string(filepath.Separator)) {
+			dir = filepath.Join(dir, part)
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, dir := range dirs {
+		bts, err := os.ReadFile(filepath.Join(dir, ".crushignore"))
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(dir, absPath)
+		if err != nil {
+			continue
+		}
+		if matchesIgnore(ignore.CompileIgnoreLines(strings.Split(string(bts), "\n")...), rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesIgnore(parser ignore.IgnoreParser, relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	return parser.MatchesPath(relPath) || parser.MatchesPath(relPath+"/")
+}
@@ -0,0 +1,232 @@
+// Package hooks runs the shell commands configured to run at points of the
+// agent's work: before and after tool calls, at the end of a turn and at the
+// start of a session. Each command gets what happened as JSON on stdin.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+// defaultTimeout is how long a hook may run when its config doesn't say.
+const defaultTimeout = 30 * time.Second
+
+type Event string
+
+const (
+	EventPreToolUse   Event = "pre_tool_use"
+	EventPostToolUse  Event = "post_tool_use"
+	EventTurnEnd      Event = "on_turn_end"
+	EventSessionStart Event = "on_session_start"
+)
+
+// Input is what a hook gets on stdin.
+type Input struct {
+	Event      Event  `json:"event"`
+	SessionID  string `json:"session_id"`
+	WorkingDir string `json:"working_dir"`
+
+	// Tool use hooks.
+	ToolName   string          `json:"tool_name,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	Result     *Result         `json:"result,omitempty"`
+
+	// Turn end hooks.
+	FinishReason string `json:"finish_reason,omitempty"`
+	Response     string `json:"response,omitempty"`
+
+	// Session start hooks.
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// Result is the result of a tool call given to post tool use hooks.
+type Result struct {
+	Content string `json:"content"`
+	IsError bool   `json:"is_error"`
+}
+
+// output is what a pre tool use hook may print on stdout.
+type output struct {
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Error is returned when a hook fails.
+type Error struct {
+	Command string
+	Output  string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Output == "" {
+		return fmt.Sprintf("hook %q failed: %v", e.Command, e.Err)
+	}
+	return fmt.Sprintf("hook %q failed (%v):\n%s", e.Command, e.Err, e.Output)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Runner runs the configured hooks.
+type Runner struct {
+	hooks      config.Hooks
+	workingDir string
+}
+
+// NewRunner creates a runner for the given hooks, run from workingDir.
+func NewRunner(hooks *config.Hooks, workingDir string) *Runner {
+	r := &Runner{workingDir: workingDir}
+	if hooks != nil {
+		r.hooks = *hooks
+	}
+	return r
+}
+
+// PreToolUse runs the pre tool use hooks of the tool and returns the params to
+// call it with. A hook may change the params by printing
+// {"params": {...}} on stdout; the following hooks get the changed params. An
+// error means a hook failed and the call must not be made.
+func (r *Runner) PreToolUse(ctx context.Context, sessionID, toolName, toolCallID, params string) (string, error) {
+	for _, hook := range r.hooks.PreToolUse {
+		if !runsFor(hook, toolName) {
+			continue
+		}
+		stdout, err := r.run(ctx, hook, Input{
+			Event:      EventPreToolUse,
+			SessionID:  sessionID,
+			ToolName:   toolName,
+			ToolCallID: toolCallID,
+			Params:     rawParams(params),
+		})
+		if err != nil {
+			return "", err
+		}
+		var out output
+		if !strings.HasPrefix(stdout, "{") || json.Unmarshal([]byte(stdout), &out) != nil {
+			continue
+		}
+		if len(out.Params) > 0 && !bytes.Equal(out.Params, []byte("null")) {
+			slog.Info("Hook changed tool call params", "command", hook.Command, "tool", toolName)
+			params = string(out.Params)
+		}
+	}
+	return params, nil
+}
+
+// PostToolUse runs the post tool use hooks of the tool and returns what the
+// hooks that failed had to say, for the model to act on. It is empty when all
+// the hooks succeeded.
+func (r *Runner) PostToolUse(ctx context.Context, sessionID, toolName, toolCallID, params string, result Result) string {
+	var feedback []string
+	for _, hook := range r.hooks.PostToolUse {
+		if !runsFor(hook, toolName) {
+			continue
+		}
+		_, err := r.run(ctx, hook, Input{
+			Event:      EventPostToolUse,
+			SessionID:  sessionID,
+			ToolName:   toolName,
+			ToolCallID: toolCallID,
+			Params:     rawParams(params),
+			Result:     &result,
+		})
+		if err != nil && ctx.Err() == nil {
+			feedback = append(feedback, err.Error())
+		}
+	}
+	return strings.Join(feedback, "\n\n")
+}
+
+// TurnEnd runs the turn end hooks. Failures are only logged.
+func (r *Runner) TurnEnd(ctx context.Context, sessionID, finishReason, response string) {
+	for _, hook := range r.hooks.OnTurnEnd {
+		_, err := r.run(ctx, hook, Input{
+			Event:        EventTurnEnd,
+			SessionID:    sessionID,
+			FinishReason: finishReason,
+			Response:     response,
+		})
+		if err != nil {
+			slog.Error("Turn end hook failed", "session_id", sessionID, "error", err)
+		}
+	}
+}
+
+// SessionStart runs the session start hooks. Failures are only logged.
+func (r *Runner) SessionStart(ctx context.Context, sessionID, prompt string) {
+	for _, hook := range r.hooks.OnSessionStart {
+		_, err := r.run(ctx, hook, Input{
+			Event:     EventSessionStart,
+			SessionID: sessionID,
+			Prompt:    prompt,
+		})
+		if err != nil {
+			slog.Error("Session start hook failed", "session_id", sessionID, "error", err)
+		}
+	}
+}
+
+func (r *Runner) run(ctx context.Context, hook config.Hook, input Input) (string, error) {
+	input.WorkingDir = r.workingDir
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal hook input: %w", err)
+	}
+
+	timeout := defaultTimeout
+	if hook.Timeout > 0 {
+		timeout = time.Duration(hook.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sh := shell.NewShell(&shell.Options{
+		WorkingDir: r.workingDir,
+		Env: append(
+			os.Environ(),
+			"CRUSH_HOOK_EVENT="+string(input.Event),
+			"CRUSH_SESSION_ID="+input.SessionID,
+			"CRUSH_TOOL_NAME="+input.ToolName,
+		),
+	})
+	stdout, stderr, err := sh.ExecWithStdin(ctx, hook.Command, bytes.NewReader(data))
+	stdout = strings.TrimSpace(stdout)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		return stdout, &Error{
+			Command: hook.Command,
+			Output:  strings.TrimSpace(strings.Join([]string{strings.TrimSpace(stderr), stdout}, "\n")),
+			Err:     err,
+		}
+	}
+	return stdout, nil
+}
+
+func runsFor(hook config.Hook, toolName string) bool {
+	return len(hook.Tools) == 0 || slices.Contains(hook.Tools, toolName)
+}
+
+// rawParams returns the params of a tool call as JSON, or a JSON string of
+// them when the model sent something that isn't valid JSON.
+func rawParams(params string) json.RawMessage {
+	if json.Valid([]byte(params)) {
+		return json.RawMessage(params)
+	}
+	quoted, _ := json.Marshal(params)
+	return quoted
+}
@@ -0,0 +1,86 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreToolUse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no hooks", func(t *testing.T) {
+		t.Parallel()
+		runner := NewRunner(nil, t.TempDir())
+		params, err := runner.PreToolUse(t.Context(), "session", "bash", "call", `{"command": "rm -rf /"}`)
+		require.NoError(t, err)
+		require.Equal(t, `{"command": "rm -rf /"}`, params)
+	})
+
+	t.Run("failing hook blocks the call", func(t *testing.T) {
+		t.Parallel()
+		runner := NewRunner(&config.Hooks{
+			PreToolUse: []config.Hook{{
+				Command: `echo "rm is not allowed" >&2; exit 2`,
+				Tools:   []string{"bash"},
+			}},
+		}, t.TempDir())
+
+		_, err := runner.PreToolUse(t.Context(), "session", "bash", "call", `{"command": "rm -rf /"}`)
+		var hookErr *Error
+		require.ErrorAs(t, err, &hookErr)
+		require.Equal(t, "rm is not allowed", hookErr.Output)
+
+		// The hook only runs for the tools it lists.
+		params, err := runner.PreToolUse(t.Context(), "session", "view", "call", `{"file_path": "main.go"}`)
+		require.NoError(t, err)
+		require.Equal(t, `{"file_path": "main.go"}`, params)
+	})
+
+	t.Run("hook rewrites params", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		runner := NewRunner(&config.Hooks{
+			PreToolUse: []config.Hook{
+				{Command: `echo '{"params": {"command": "go test ./..."}}'`},
+				{Command: `read -r line; echo "$line" > input.json`},
+			},
+		}, dir)
+
+		params, err := runner.PreToolUse(t.Context(), "session", "bash", "call", `{"command": "go test"}`)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"command": "go test ./..."}`, params)
+
+		// The next hook got the rewritten params.
+		data, err := os.ReadFile(filepath.Join(dir, "input.json"))
+		require.NoError(t, err)
+		var input Input
+		require.NoError(t, json.Unmarshal(data, &input))
+		require.Equal(t, EventPreToolUse, input.Event)
+		require.Equal(t, "session", input.SessionID)
+		require.Equal(t, "bash", input.ToolName)
+		require.Equal(t, dir, input.WorkingDir)
+		require.JSONEq(t, `{"command": "go test ./..."}`, string(input.Params))
+	})
+}
+
+func TestPostToolUse(t *testing.T) {
+	t.Parallel()
+
+	runner := NewRunner(&config.Hooks{
+		PostToolUse: []config.Hook{
+			{Command: `exit 0`},
+			{Command: `echo "main.go is not formatted"; exit 1`, Tools: []string{"edit", "write"}},
+		},
+	}, t.TempDir())
+
+	feedback := runner.PostToolUse(t.Context(), "session", "edit", "call", `{"file_path": "main.go"}`, Result{Content: "edited"})
+	require.Equal(t, "hook \"echo \\\"main.go is not formatted\\\"; exit 1\" failed (exit status 1):\nmain.go is not formatted", feedback)
+
+	feedback = runner.PostToolUse(t.Context(), "session", "view", "call", `{"file_path": "main.go"}`, Result{Content: "viewed"})
+	require.Empty(t, feedback)
+}
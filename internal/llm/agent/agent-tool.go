@@ -7,13 +7,15 @@ import (
 
 	"github.com/charmbracelet/crush/internal/llm/tools"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/session"
 )
 
 type agentTool struct {
-	agent    Service
-	sessions session.Service
-	messages message.Service
+	agent       Service
+	permissions permission.Service
+	sessions    session.Service
+	messages    message.Service
 }
 
 const (
@@ -61,6 +63,11 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 		return tools.ToolResponse{}, fmt.Errorf("error creating session: %s", err)
 	}
 
+	// The task session is bound by the same policy as its parent.
+	if policy, ok := b.permissions.SessionPolicy(sessionID); ok {
+		b.permissions.SetSessionPolicy(session.ID, policy)
+	}
+
 	done, err := b.agent.Run(ctx, session.ID, params.Prompt)
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error generating agent: %s", err)
@@ -95,12 +102,14 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 
 func NewAgentTool(
 	agent Service,
+	permissions permission.Service,
 	sessions session.Service,
 	messages message.Service,
 ) tools.BaseTool {
 	return &agentTool{
-		sessions: sessions,
-		messages: messages,
-		agent:    agent,
+		sessions:    sessions,
+		messages:    messages,
+		agent:       agent,
+		permissions: permissions,
 	}
 }
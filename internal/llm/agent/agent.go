@@ -1,12 +1,14 @@
 package agent
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
@@ -14,6 +16,7 @@ import (
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/event"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/hooks"
 	"github.com/charmbracelet/crush/internal/llm/prompt"
 	"github.com/charmbracelet/crush/internal/llm/provider"
 	"github.com/charmbracelet/crush/internal/llm/tools"
@@ -24,6 +27,8 @@ import (
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/shell"
+	"github.com/charmbracelet/crush/internal/todo"
+	"github.com/charmbracelet/crush/internal/usage"
 )
 
 type AgentEventType string
@@ -32,6 +37,9 @@ const (
 	AgentEventTypeError     AgentEventType = "error"
 	AgentEventTypeResponse  AgentEventType = "response"
 	AgentEventTypeSummarize AgentEventType = "summarize"
+	// AgentEventTypeBudgetWarning is sent when a prompt gets close to one of
+	// its budget limits. Progress holds the warning.
+	AgentEventTypeBudgetWarning AgentEventType = "budget_warning"
 )
 
 type AgentEvent struct {
@@ -57,6 +65,26 @@ type Service interface {
 	UpdateModel() error
 	QueuedPrompts(sessionID string) int
 	ClearQueue(sessionID string)
+	// ListQueue returns the prompts waiting for the session to be free, in
+	// the order they'll be sent.
+	ListQueue(sessionID string) []QueuedPrompt
+	UpdateQueuedPrompt(sessionID, id, content string) error
+	// MoveQueuedPrompt moves a queued prompt offset places back in the
+	// queue, or forward for a negative offset.
+	MoveQueuedPrompt(sessionID, id string, offset int) error
+	RemoveQueuedPrompt(sessionID, id string) error
+	// PromoteQueuedPrompt interrupts the current request of the session and
+	// sends the queued prompt right away.
+	PromoteQueuedPrompt(sessionID, id string) error
+	// SetSessionBudget sets the limits of the prompts of a session, on top of
+	// the configured ones. They're only kept in memory, for as long as the
+	// app runs; limits on what a session spends in total come from the
+	// session_budget option.
+	SetSessionBudget(sessionID string, budget config.Budget)
+	// SetPlanMode turns plan mode on or off for a session. In plan mode the
+	// agent only has read-only tools, and answers with a plan to approve.
+	SetPlanMode(sessionID string, enabled bool)
+	IsPlanMode(sessionID string) bool
 }
 
 type agent struct {
@@ -65,6 +93,9 @@ type agent struct {
 	sessions    session.Service
 	messages    message.Service
 	permissions permission.Service
+	todos       todo.Service
+	usage       usage.Service
+	hooks       *hooks.Runner
 	mcpTools    []McpTool
 
 	tools *csync.LazySlice[tools.BaseTool]
@@ -75,11 +106,18 @@ type agent struct {
 	providerID string
 
 	titleProvider       provider.Provider
+	titleProviderID     string
 	summarizeProvider   provider.Provider
 	summarizeProviderID string
 
 	activeRequests *csync.Map[string, context.CancelFunc]
-	promptQueue    *csync.Map[string, []string]
+	// queueMu guards the prompt queues, and the end of requests against
+	// queued prompts interrupting them.
+	queueMu        sync.Mutex
+	promptQueue    *csync.Map[string, []QueuedPrompt]
+	interrupted    *csync.Map[string, bool]
+	sessionBudgets *csync.Map[string, config.Budget]
+	planSessions   *csync.Map[string, bool]
 }
 
 var agentPromptMap = map[string]prompt.PromptID{
@@ -87,6 +125,24 @@ var agentPromptMap = map[string]prompt.PromptID{
 	"task":  prompt.PromptTask,
 }
 
+// agentSystemPrompt returns the system prompt of an agent: the one in its
+// prompt file, if any, or the built-in prompt for its id. Agents without
+// either work like the coder agent.
+func agentSystemPrompt(agentCfg config.Agent, providerID string) (string, error) {
+	if agentCfg.Prompt != "" {
+		return prompt.CustomPrompt(agentCfg.Prompt, agentCfg.ContextPaths...)
+	}
+	promptID, ok := agentPromptMap[agentCfg.ID]
+	if !ok {
+		promptID = prompt.PromptCoder
+	}
+	if promptID == prompt.PromptCoder {
+		todos := agentCfg.AllowedTools == nil || slices.Contains(agentCfg.AllowedTools, tools.TodosToolName)
+		return prompt.CoderPrompt(providerID, todos, agentCfg.ContextPaths...), nil
+	}
+	return prompt.GetPrompt(promptID, providerID, agentCfg.ContextPaths...), nil
+}
+
 func NewAgent(
 	ctx context.Context,
 	agentCfg config.Agent,
@@ -95,22 +151,24 @@ func NewAgent(
 	sessions session.Service,
 	messages message.Service,
 	history history.Service,
+	todos todo.Service,
+	usage usage.Service,
 	lspClients *csync.Map[string, *lsp.Client],
 ) (Service, error) {
 	cfg := config.Get()
 
 	var agentToolFn func() (tools.BaseTool, error)
-	if agentCfg.ID == "coder" && slices.Contains(agentCfg.AllowedTools, AgentToolName) {
+	if agentCfg.ID != "task" && slices.Contains(agentCfg.AllowedTools, AgentToolName) {
 		agentToolFn = func() (tools.BaseTool, error) {
 			taskAgentCfg := config.Get().Agents["task"]
 			if taskAgentCfg.ID == "" {
 				return nil, fmt.Errorf("task agent not found in config")
 			}
-			taskAgent, err := NewAgent(ctx, taskAgentCfg, permissions, sessions, messages, history, lspClients)
+			taskAgent, err := NewAgent(ctx, taskAgentCfg, permissions, sessions, messages, history, todos, usage, lspClients)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create task agent: %w", err)
 			}
-			return NewAgentTool(taskAgent, sessions, messages), nil
+			return NewAgentTool(taskAgent, permissions, sessions, messages), nil
 		}
 	}
 
@@ -124,13 +182,14 @@ func NewAgent(
 		return nil, fmt.Errorf("model not found for agent %s", agentCfg.Name)
 	}
 
-	promptID := agentPromptMap[agentCfg.ID]
-	if promptID == "" {
-		promptID = prompt.PromptDefault
+	systemPrompt, err := agentSystemPrompt(agentCfg, providerCfg.ID)
+	if err != nil {
+		return nil, err
 	}
 	opts := []provider.ProviderClientOption{
 		provider.WithModel(agentCfg.Model),
-		provider.WithSystemMessage(prompt.GetPrompt(promptID, providerCfg.ID, config.Get().Options.ContextPaths...)),
+		provider.WithRole(agentCfg.ID),
+		provider.WithSystemMessage(systemPrompt),
 	}
 	agentProvider, err := provider.NewProvider(*providerCfg, opts...)
 	if err != nil {
@@ -155,6 +214,7 @@ func NewAgent(
 
 	titleOpts := []provider.ProviderClientOption{
 		provider.WithModel(config.SelectedModelTypeSmall),
+		provider.WithRole("title"),
 		provider.WithSystemMessage(prompt.GetPrompt(prompt.PromptTitle, smallModelProviderCfg.ID)),
 	}
 	titleProvider, err := provider.NewProvider(*smallModelProviderCfg, titleOpts...)
@@ -164,6 +224,7 @@ func NewAgent(
 
 	summarizeOpts := []provider.ProviderClientOption{
 		provider.WithModel(config.SelectedModelTypeLarge),
+		provider.WithRole("summarize"),
 		provider.WithSystemMessage(prompt.GetPrompt(prompt.PromptSummarizer, providerCfg.ID)),
 	}
 	summarizeProvider, err := provider.NewProvider(*providerCfg, summarizeOpts...)
@@ -188,6 +249,7 @@ func NewAgent(
 			tools.NewGrepTool(cwd),
 			tools.NewLsTool(permissions, cwd),
 			tools.NewSourcegraphTool(),
+			tools.NewTodosTool(todos),
 			tools.NewViewTool(lspClients, permissions, cwd),
 			tools.NewWriteTool(lspClients, permissions, history, cwd),
 		}
@@ -196,18 +258,26 @@ func NewAgent(
 			mcpTools = doGetMCPTools(ctx, permissions, cfg)
 		})
 
-		withCoderTools := func(t []tools.BaseTool) []tools.BaseTool {
-			if agentCfg.ID == "coder" {
-				t = append(t, mcpTools...)
-				if lspClients.Len() > 0 {
-					t = append(t, tools.NewDiagnosticsTool(lspClients))
+		withAgentTools := func(t []tools.BaseTool) []tools.BaseTool {
+			for _, tool := range mcpTools {
+				if mcpTool, ok := tool.(*McpTool); !ok || agentCfg.AllowsMCPTool(mcpTool.mcpName, mcpTool.tool.Name) {
+					t = append(t, tool)
 				}
 			}
+			agentLSPClients := csync.NewMap[string, *lsp.Client]()
+			for name, client := range lspClients.Seq2() {
+				if agentCfg.AllowsLSP(name) {
+					agentLSPClients.Set(name, client)
+				}
+			}
+			if agentLSPClients.Len() > 0 {
+				t = append(t, tools.NewDiagnosticsTool(agentLSPClients))
+			}
 			return t
 		}
 
 		if agentCfg.AllowedTools == nil {
-			return withCoderTools(allTools)
+			return withAgentTools(allTools)
 		}
 
 		var filteredTools []tools.BaseTool
@@ -216,7 +286,7 @@ func NewAgent(
 				filteredTools = append(filteredTools, tool)
 			}
 		}
-		return withCoderTools(filteredTools)
+		return withAgentTools(filteredTools)
 	}
 
 	return &agent{
@@ -227,16 +297,42 @@ func NewAgent(
 		messages:            messages,
 		sessions:            sessions,
 		titleProvider:       titleProvider,
+		titleProviderID:     string(smallModelProviderCfg.ID),
 		summarizeProvider:   summarizeProvider,
 		summarizeProviderID: string(providerCfg.ID),
 		agentToolFn:         agentToolFn,
 		activeRequests:      csync.NewMap[string, context.CancelFunc](),
 		tools:               csync.NewLazySlice(toolFn),
-		promptQueue:         csync.NewMap[string, []string](),
+		promptQueue:         csync.NewMap[string, []QueuedPrompt](),
+		interrupted:         csync.NewMap[string, bool](),
+		sessionBudgets:      csync.NewMap[string, config.Budget](),
+		planSessions:        csync.NewMap[string, bool](),
 		permissions:         permissions,
+		todos:               todos,
+		usage:               usage,
+		hooks:               hooks.NewRunner(cfg.Hooks, cfg.WorkingDir()),
 	}, nil
 }
 
+// CopySessionSettings copies the budgets and plan modes set on sessions from
+// one agent to another, so they survive switching agents.
+func CopySessionSettings(from, to Service) {
+	src, ok := from.(*agent)
+	if !ok {
+		return
+	}
+	dst, ok := to.(*agent)
+	if !ok {
+		return
+	}
+	for sessionID, budget := range src.sessionBudgets.Seq2() {
+		dst.sessionBudgets.Set(sessionID, budget)
+	}
+	for sessionID, enabled := range src.planSessions.Seq2() {
+		dst.planSessions.Set(sessionID, enabled)
+	}
+}
+
 func (a *agent) Model() catwalk.Model {
 	return *config.Get().GetModelByType(a.agentCfg.Model)
 }
@@ -254,10 +350,7 @@ func (a *agent) Cancel(sessionID string) {
 		cancel()
 	}
 
-	if a.QueuedPrompts(sessionID) > 0 {
-		slog.Info("Clearing queued prompts", "session_id", sessionID)
-		a.promptQueue.Del(sessionID)
-	}
+	a.ClearQueue(sessionID)
 }
 
 func (a *agent) IsBusy() bool {
@@ -276,14 +369,6 @@ func (a *agent) IsSessionBusy(sessionID string) bool {
 	return busy
 }
 
-func (a *agent) QueuedPrompts(sessionID string) int {
-	l, ok := a.promptQueue.Get(sessionID)
-	if !ok {
-		return 0
-	}
-	return len(l)
-}
-
 func (a *agent) generateTitle(ctx context.Context, sessionID string, content string) error {
 	if content == "" {
 		return nil
@@ -291,10 +376,6 @@ func (a *agent) generateTitle(ctx context.Context, sessionID string, content str
 	if a.titleProvider == nil {
 		return nil
 	}
-	session, err := a.sessions.Get(ctx, sessionID)
-	if err != nil {
-		return err
-	}
 	parts := []message.ContentPart{message.TextContent{
 		Text: fmt.Sprintf("Generate a concise title for the following content:\n\n%s", content),
 	}}
@@ -322,6 +403,7 @@ func (a *agent) generateTitle(ctx context.Context, sessionID string, content str
 	if finalResponse == nil {
 		return fmt.Errorf("no response received from title provider")
 	}
+	cost := a.recordUsage(ctx, sessionID, "", usage.KindTitle, a.titleProviderID, a.titleProvider.Model(), finalResponse.Usage)
 
 	title := strings.ReplaceAll(finalResponse.Content, "\n", " ")
 
@@ -330,11 +412,20 @@ func (a *agent) generateTitle(ctx context.Context, sessionID string, content str
 	}
 
 	title = strings.TrimSpace(title)
-	if title == "" {
+
+	// The session is read after the response, as the prompt that asked for
+	// the title runs meanwhile and updates its cost.
+	session, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if title == "" && cost == 0 {
 		return nil
 	}
-
-	session.Title = title
+	session.Cost += cost
+	if title != "" {
+		session.Title = title
+	}
 	_, err = a.sessions.Save(ctx, session)
 	return err
 }
@@ -352,12 +443,7 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 	}
 	events := make(chan AgentEvent, 1)
 	if a.IsSessionBusy(sessionID) {
-		existing, ok := a.promptQueue.Get(sessionID)
-		if !ok {
-			existing = []string{}
-		}
-		existing = append(existing, content)
-		a.promptQueue.Set(sessionID, existing)
+		a.enqueuePrompt(sessionID, content)
 		return nil, nil
 	}
 
@@ -386,11 +472,23 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 			slog.Debug("Request completed", "sessionID", sessionID)
 		}
 		a.eventPromptResponded(sessionID, time.Since(startTime).Truncate(time.Second))
+		// Run the hooks while the request is still active, so that they are
+		// done before anyone waiting for the result, or for the agent on
+		// shutdown, moves on.
+		a.runTurnEndHooks(sessionID, result)
+		// See PromoteQueuedPrompt.
+		a.queueMu.Lock()
 		a.activeRequests.Del(sessionID)
+		_, interrupted := a.interrupted.Take(sessionID)
+		a.queueMu.Unlock()
 		cancel()
 		a.Publish(pubsub.CreatedEvent, result)
 		events <- result
 		close(events)
+		// A queued prompt was promoted, send it now the request is over.
+		if interrupted {
+			a.runQueue(ctx, sessionID)
+		}
 	}()
 	a.eventPromptSent(sessionID)
 	return events, nil
@@ -418,6 +516,10 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 	if err != nil {
 		return a.err(fmt.Errorf("failed to get session: %w", err))
 	}
+	// Task sessions are part of the session that started them.
+	if len(msgs) == 0 && session.ParentSessionID == "" {
+		a.hooks.SessionStart(ctx, sessionID, content)
+	}
 	if session.SummaryMessageID != "" {
 		summaryMsgInex := -1
 		for i, msg := range msgs {
@@ -439,6 +541,7 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 	// Append the new user message to the conversation history.
 	msgHistory := append(msgs, userMsg)
 
+	ctx, budget := a.runBudget(ctx, sessionID)
 	for {
 		// Check for cancellation before each iteration
 		select {
@@ -447,6 +550,11 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		default:
 			// Continue processing
 		}
+		if budget != nil {
+			if err := budget.startTurn(); err != nil {
+				return a.stopOverBudget(ctx, sessionID, err)
+			}
+		}
 		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, sessionID, msgHistory)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
@@ -463,11 +571,11 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 			// We are not done, we need to respond with the tool response
 			msgHistory = append(msgHistory, agentMessage, *toolResults)
 			// If there are queued prompts, process the next one
-			nextPrompt, ok := a.promptQueue.Take(sessionID)
+			nextPrompt, ok := a.takeQueue(sessionID)
 			if ok {
 				for _, prompt := range nextPrompt {
 					// Create a new user message for the queued prompt
-					userMsg, err := a.createUserMessage(ctx, sessionID, prompt, nil)
+					userMsg, err := a.createUserMessage(ctx, sessionID, prompt.Content, nil)
 					if err != nil {
 						return a.err(fmt.Errorf("failed to create user message for queued prompt: %w", err))
 					}
@@ -478,13 +586,13 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 
 			continue
 		} else if agentMessage.FinishReason() == message.FinishReasonEndTurn {
-			queuePrompts, ok := a.promptQueue.Take(sessionID)
+			queuePrompts, ok := a.takeQueue(sessionID)
 			if ok {
 				for _, prompt := range queuePrompts {
-					if prompt == "" {
+					if prompt.Content == "" {
 						continue
 					}
-					userMsg, err := a.createUserMessage(ctx, sessionID, prompt, nil)
+					userMsg, err := a.createUserMessage(ctx, sessionID, prompt.Content, nil)
 					if err != nil {
 						return a.err(fmt.Errorf("failed to create user message for queued prompt: %w", err))
 					}
@@ -546,6 +654,18 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 	if toolsErr != nil {
 		return assistantMsg, nil, toolsErr
 	}
+	planMode := a.IsPlanMode(sessionID)
+	if planMode {
+		allTools = planModeTools(allTools)
+		msgHistory = withPlanInstructions(msgHistory)
+	}
+	if cfg := config.Get(); !cfg.Options.DisableAutoPrune {
+		var elided int
+		msgHistory, elided = pruneToolResults(msgHistory, a.Model().ContextWindow, cfg.WorkingDir())
+		if elided > 0 {
+			slog.Info("Elided stale tool results", "session_id", sessionID, "count", elided)
+		}
+	}
 	// Now collect tools (which may block on MCP initialization)
 	eventChan := a.provider.StreamResponse(ctx, msgHistory, allTools)
 
@@ -575,6 +695,12 @@ loop:
 
 	toolResults := make([]message.ToolResult, len(assistantMsg.ToolCalls()))
 	toolCalls := assistantMsg.ToolCalls()
+	parallelResults := make([]chan toolExecResult, len(toolCalls))
+	canRunInParallel := func(toolCall message.ToolCall) bool {
+		policy, hasPolicy := a.permissions.SessionPolicy(sessionID)
+		return !hasPolicy || policy.CheckTool(toolCall.Name) == nil
+	}
+	parallelLimit := parallelToolCallsLimit()
 	for i, toolCall := range toolCalls {
 		select {
 		case <-ctx.Done():
@@ -589,9 +715,14 @@ loop:
 			}
 			goto out
 		default:
-			// Continue processing
+			// Read-only calls in a row start together once the loop gets to
+			// them, a few at a time, and the loop collects their results in
+			// order.
+			if parallelResults[i] == nil {
+				copy(parallelResults[i:], startParallelToolCalls(ctx, toolCalls[i:], allTools, canRunInParallel, a.callTool, parallelLimit))
+			}
+
 			var tool tools.BaseTool
-			allTools, _ := a.getAllTools()
 			for _, availableTool := range allTools {
 				if availableTool.Info().Name == toolCall.Name {
 					tool = availableTool
@@ -601,29 +732,45 @@ loop:
 
 			// Tool not found
 			if tool == nil {
+				content := fmt.Sprintf("Tool not found: %s", toolCall.Name)
+				if planMode {
+					content = fmt.Sprintf("Tool not available in plan mode: %s", toolCall.Name)
+				}
 				toolResults[i] = message.ToolResult{
 					ToolCallID: toolCall.ID,
-					Content:    fmt.Sprintf("Tool not found: %s", toolCall.Name),
+					Content:    content,
 					IsError:    true,
 				}
 				continue
 			}
 
-			// Run tool in goroutine to allow cancellation
-			type toolExecResult struct {
-				response tools.ToolResponse
-				err      error
+			// Sessions with a permission policy report denials back to the
+			// model instead of stopping the turn, as nobody is around to
+			// answer.
+			policy, hasPolicy := a.permissions.SessionPolicy(sessionID)
+			if hasPolicy {
+				if err := policy.CheckTool(toolCall.Name); err != nil {
+					toolResults[i] = message.ToolResult{
+						ToolCallID: toolCall.ID,
+						Name:       toolCall.Name,
+						Content:    policyDeniedMessage(err),
+						IsError:    true,
+					}
+					continue
+				}
 			}
-			resultChan := make(chan toolExecResult, 1)
 
-			go func() {
-				response, err := tool.Run(ctx, tools.ToolCall{
-					ID:    toolCall.ID,
-					Name:  toolCall.Name,
-					Input: toolCall.Input,
-				})
-				resultChan <- toolExecResult{response: response, err: err}
-			}()
+			// Run tool in goroutine to allow cancellation
+			var resultChan chan toolExecResult
+			if parallelResults[i] != nil {
+				resultChan = parallelResults[i]
+			} else {
+				resultChan = make(chan toolExecResult, 1)
+				go func() {
+					response, err := a.callTool(ctx, tool, toolCall)
+					resultChan <- toolExecResult{response: response, err: err}
+				}()
+			}
 
 			var toolResponse tools.ToolResponse
 			var toolErr error
@@ -647,6 +794,15 @@ loop:
 
 			if toolErr != nil {
 				slog.Error("Tool execution error", "toolCall", toolCall.ID, "error", toolErr)
+				if hasPolicy && errors.Is(toolErr, permission.ErrorPermissionDenied) {
+					toolResults[i] = message.ToolResult{
+						ToolCallID: toolCall.ID,
+						Name:       toolCall.Name,
+						Content:    policyDeniedMessage(toolErr),
+						IsError:    true,
+					}
+					continue
+				}
 				if errors.Is(toolErr, permission.ErrorPermissionDenied) {
 					toolResults[i] = message.ToolResult{
 						ToolCallID: toolCall.ID,
@@ -692,6 +848,12 @@ out:
 	return assistantMsg, &msg, err
 }
 
+// policyDeniedMessage is the tool result sent to the model when a permission
+// policy denies a tool call.
+func policyDeniedMessage(err error) string {
+	return fmt.Sprintf("Tool call rejected: %s. The permission policy of this run does not allow it; do not retry the same call, use a different approach or explain what you could not do.", err)
+}
+
 func (a *agent) finishMessage(ctx context.Context, msg *message.Message, finishReason message.FinishReason, message, details string) {
 	msg.AddFinish(finishReason, message, details)
 	_ = a.messages.Update(ctx, *msg)
@@ -737,28 +899,30 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		if err := a.messages.Update(ctx, *assistantMsg); err != nil {
 			return fmt.Errorf("failed to update message: %w", err)
 		}
-		return a.trackUsage(ctx, sessionID, a.Model(), event.Response.Usage)
+		return a.trackUsage(ctx, sessionID, assistantMsg.ID, a.Model(), event.Response.Usage)
 	}
 
 	return nil
 }
 
-func (a *agent) trackUsage(ctx context.Context, sessionID string, model catwalk.Model, usage provider.TokenUsage) error {
+func (a *agent) trackUsage(ctx context.Context, sessionID, messageID string, model catwalk.Model, tokens provider.TokenUsage) error {
 	sess, err := a.sessions.Get(ctx, sessionID)
 	if err != nil {
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
+	// Requests of task sessions count toward the session that started them,
+	// which already adds up their cost.
+	cost := a.recordUsage(ctx, cmp.Or(sess.ParentSessionID, sessionID), messageID, usage.KindAgent, a.providerID, model, tokens)
+	if budget := runBudgetFromContext(ctx); budget != nil {
+		budget.add(tokens, cost)
+	}
 
-	a.eventTokensUsed(sessionID, usage, cost)
+	a.eventTokensUsed(sessionID, tokens, cost)
 
 	sess.Cost += cost
-	sess.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
-	sess.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
+	sess.CompletionTokens = tokens.OutputTokens + tokens.CacheReadTokens
+	sess.PromptTokens = tokens.InputTokens + tokens.CacheCreationTokens
 
 	_, err = a.sessions.Save(ctx, sess)
 	if err != nil {
@@ -767,6 +931,35 @@ func (a *agent) trackUsage(ctx context.Context, sessionID string, model catwalk.
 	return nil
 }
 
+// recordUsage persists the usage of a provider request and returns its cost.
+// Failing to persist it is only logged, as it must not fail the request.
+func (a *agent) recordUsage(ctx context.Context, sessionID, messageID string, kind usage.Kind, providerID string, model catwalk.Model, tokens provider.TokenUsage) float64 {
+	cost := model.CostPer1MInCached/1e6*float64(tokens.CacheCreationTokens) +
+		model.CostPer1MOutCached/1e6*float64(tokens.CacheReadTokens) +
+		model.CostPer1MIn/1e6*float64(tokens.InputTokens) +
+		model.CostPer1MOut/1e6*float64(tokens.OutputTokens)
+
+	if a.usage == nil {
+		return cost
+	}
+	err := a.usage.Create(ctx, usage.Record{
+		SessionID:           sessionID,
+		MessageID:           messageID,
+		Kind:                kind,
+		Model:               model.ID,
+		Provider:            providerID,
+		InputTokens:         tokens.InputTokens,
+		OutputTokens:        tokens.OutputTokens,
+		CacheCreationTokens: tokens.CacheCreationTokens,
+		CacheReadTokens:     tokens.CacheReadTokens,
+		Cost:                cost,
+	})
+	if err != nil {
+		slog.Error("Failed to record usage", "session_id", sessionID, "error", err)
+	}
+	return cost
+}
+
 func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	if a.summarizeProvider == nil {
 		return fmt.Errorf("summarize provider not available")
@@ -830,6 +1023,11 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 			Parts: []message.ContentPart{message.TextContent{Text: summarizePrompt}},
 		}
 
+		// Elide stale tool results so the conversation fits the summarize model
+		if cfg := config.Get(); !cfg.Options.DisableAutoPrune {
+			msgs, _ = pruneToolResults(msgs, a.summarizeProvider.Model().ContextWindow, cfg.WorkingDir())
+		}
+
 		// Append the prompt to the messages
 		msgsWithPrompt := append(msgs, promptMsg)
 
@@ -872,6 +1070,12 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		}
 		shell := shell.GetPersistentShell(config.Get().WorkingDir())
 		summary += "\n\n**Current working directory of the persistent shell**\n\n" + shell.GetWorkingDir()
+		// Carry the open todos over, so the agent picks up where it left off.
+		if todos, err := a.todos.List(summarizeCtx, sessionID); err != nil {
+			slog.Error("Failed to list todos", "session_id", sessionID, "error", err)
+		} else if open := todo.Open(todos); len(open) > 0 {
+			summary += "\n\n**Open items of the todo list**\n\n" + todo.Format(open)
+		}
 		event = AgentEvent{
 			Type:     AgentEventTypeSummarize,
 			Progress: "Creating new session...",
@@ -915,12 +1119,7 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		oldSession.SummaryMessageID = msg.ID
 		oldSession.CompletionTokens = finalResponse.Usage.OutputTokens
 		oldSession.PromptTokens = 0
-		model := a.summarizeProvider.Model()
-		usage := finalResponse.Usage
-		cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-			model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-			model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-			model.CostPer1MOut/1e6*float64(usage.OutputTokens)
+		cost := a.recordUsage(summarizeCtx, oldSession.ID, msg.ID, usage.KindSummarize, a.summarizeProviderID, a.summarizeProvider.Model(), finalResponse.Usage)
 		oldSession.Cost += cost
 		_, err = a.sessions.Save(summarizeCtx, oldSession)
 		if err != nil {
@@ -945,13 +1144,6 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	return nil
 }
 
-func (a *agent) ClearQueue(sessionID string) {
-	if a.QueuedPrompts(sessionID) > 0 {
-		slog.Info("Clearing queued prompts", "session_id", sessionID)
-		a.promptQueue.Del(sessionID)
-	}
-}
-
 func (a *agent) CancelAll() {
 	if !a.IsBusy() {
 		return
@@ -988,14 +1180,15 @@ func (a *agent) UpdateModel() error {
 			return fmt.Errorf("model not found for agent %s", a.agentCfg.Name)
 		}
 
-		promptID := agentPromptMap[a.agentCfg.ID]
-		if promptID == "" {
-			promptID = prompt.PromptDefault
+		systemPrompt, err := agentSystemPrompt(a.agentCfg, currentProviderCfg.ID)
+		if err != nil {
+			return err
 		}
 
 		opts := []provider.ProviderClientOption{
 			provider.WithModel(a.agentCfg.Model),
-			provider.WithSystemMessage(prompt.GetPrompt(promptID, currentProviderCfg.ID, cfg.Options.ContextPaths...)),
+			provider.WithRole(a.agentCfg.ID),
+			provider.WithSystemMessage(systemPrompt),
 		}
 
 		newProvider, err := provider.NewProvider(*currentProviderCfg, opts...)
@@ -1042,6 +1235,7 @@ func (a *agent) UpdateModel() error {
 	// Recreate title provider
 	titleOpts := []provider.ProviderClientOption{
 		provider.WithModel(config.SelectedModelTypeSmall),
+		provider.WithRole("title"),
 		provider.WithSystemMessage(prompt.GetPrompt(prompt.PromptTitle, smallModelProviderCfg.ID)),
 		provider.WithMaxTokens(maxTitleTokens),
 	}
@@ -1050,6 +1244,7 @@ func (a *agent) UpdateModel() error {
 		return fmt.Errorf("failed to create new title provider: %w", err)
 	}
 	a.titleProvider = newTitleProvider
+	a.titleProviderID = string(smallModelProviderCfg.ID)
 
 	// Recreate summarize provider if provider changed (now large model)
 	if string(largeModelProviderCfg.ID) != a.summarizeProviderID {
@@ -1059,6 +1254,7 @@ func (a *agent) UpdateModel() error {
 		}
 		summarizeOpts := []provider.ProviderClientOption{
 			provider.WithModel(config.SelectedModelTypeLarge),
+			provider.WithRole("summarize"),
 			provider.WithSystemMessage(prompt.GetPrompt(prompt.PromptSummarizer, largeModelProviderCfg.ID)),
 		}
 		newSummarizeProvider, err := provider.NewProvider(largeModelProviderCfg, summarizeOpts...)
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentSystemPromptTodos(t *testing.T) {
+	// The prompt reads the global configuration.
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	t.Setenv("CRUSH_CODER_V2", "true")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "crush.json"), []byte(`{
+		"options": {"disable_provider_auto_update": true, "disabled_tools": ["todos"]},
+		"providers": {"mock": {"type": "mock", "script": "script.json"}},
+		"models": {
+			"large": {"provider": "mock", "model": "mock"},
+			"small": {"provider": "mock", "model": "mock"}
+		}
+	}`), 0o644))
+	cfg, err := config.Init(dir, "", false)
+	require.NoError(t, err)
+	require.NotContains(t, cfg.Agents["coder"].AllowedTools, tools.TodosToolName)
+
+	withTodos := "Keep the todo list with the `todos` tool"
+	withoutTodos := "Write the todo list out in your messages"
+
+	prompt, err := agentSystemPrompt(cfg.Agents["coder"], "openai")
+	require.NoError(t, err)
+	require.NotContains(t, prompt, withTodos)
+	require.Contains(t, prompt, withoutTodos)
+
+	prompt, err = agentSystemPrompt(config.Agent{ID: "reviewer", AllowedTools: []string{tools.ViewToolName, tools.TodosToolName}}, "openai")
+	require.NoError(t, err)
+	require.Contains(t, prompt, withTodos)
+	require.NotContains(t, prompt, withoutTodos)
+}
@@ -0,0 +1,237 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/usage"
+)
+
+// budgetWarningRatio is the share of a limit after which a warning is sent.
+const budgetWarningRatio = 0.8
+
+// BudgetExceededError is returned when a run, or its session, reaches one of
+// its limits.
+type BudgetExceededError struct {
+	// Scope is what reached the limit: "prompt" or "session".
+	Scope string
+	Limit string
+	Max   string
+	Spent string
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("reached the %s limit of %s (%s spent)", e.Limit, e.Max, e.Spent)
+}
+
+// runBudget tracks what a run spent against its limits. It's carried by the
+// context of the run, so that task agents count against the budget of the
+// prompt that started them.
+type runBudget struct {
+	limits config.Budget
+	// scope is what the limits apply to: "prompt" or "session".
+	scope string
+	// warn is called once per limit when the run gets close to it.
+	warn func(string)
+	// session, when set, tracks what the session of the run spent in total,
+	// starting from what its earlier prompts spent, against its own limits.
+	session *runBudget
+
+	mu     sync.Mutex
+	cost   float64
+	tokens int64
+	turns  int
+	warned map[string]bool
+}
+
+type budgetContextKey struct{}
+
+func newRunBudget(limits config.Budget, warn func(string)) *runBudget {
+	return &runBudget{
+		limits: limits,
+		scope:  "prompt",
+		warn:   warn,
+		warned: make(map[string]bool),
+	}
+}
+
+// newSessionBudget returns the budget of a session that already spent total.
+// Its turns start at the agent requests of the session, as titles and
+// summaries aren't turns.
+func newSessionBudget(limits config.Budget, total usage.SessionTotal, warn func(string)) *runBudget {
+	b := newRunBudget(limits, warn)
+	b.scope = "session"
+	b.cost = total.Cost
+	b.tokens = total.Tokens
+	b.turns = int(total.AgentRequests)
+	return b
+}
+
+func withRunBudget(ctx context.Context, b *runBudget) context.Context {
+	return context.WithValue(ctx, budgetContextKey{}, b)
+}
+
+func runBudgetFromContext(ctx context.Context) *runBudget {
+	b, _ := ctx.Value(budgetContextKey{}).(*runBudget)
+	return b
+}
+
+// startTurn counts a request to the model. It returns a
+// *BudgetExceededError, without counting the request, if the run or its
+// session already reached one of their limits.
+func (b *runBudget) startTurn() error {
+	for c := b; c != nil; c = c.session {
+		c.mu.Lock()
+		err := c.exceeded()
+		c.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	for c := b; c != nil; c = c.session {
+		c.mu.Lock()
+		c.turns++
+		warnings := c.check()
+		c.mu.Unlock()
+		c.sendWarnings(warnings)
+	}
+	return nil
+}
+
+// add counts the usage of a request.
+func (b *runBudget) add(tokens provider.TokenUsage, cost float64) {
+	for c := b; c != nil; c = c.session {
+		c.mu.Lock()
+		c.cost += cost
+		c.tokens += tokens.InputTokens + tokens.OutputTokens + tokens.CacheCreationTokens + tokens.CacheReadTokens
+		warnings := c.check()
+		c.mu.Unlock()
+		c.sendWarnings(warnings)
+	}
+}
+
+func (b *runBudget) sendWarnings(warnings []string) {
+	if b.warn == nil {
+		return
+	}
+	for _, w := range warnings {
+		b.warn(w)
+	}
+}
+
+func (b *runBudget) exceeded() error {
+	switch {
+	case b.limits.MaxCost > 0 && b.cost >= b.limits.MaxCost:
+		return &BudgetExceededError{Scope: b.scope, Limit: "cost", Max: formatCost(b.limits.MaxCost), Spent: formatCost(b.cost)}
+	case b.limits.MaxTokens > 0 && b.tokens >= b.limits.MaxTokens:
+		return &BudgetExceededError{Scope: b.scope, Limit: "token", Max: fmt.Sprint(b.limits.MaxTokens), Spent: fmt.Sprint(b.tokens)}
+	case b.limits.MaxTurns > 0 && b.turns >= b.limits.MaxTurns:
+		return &BudgetExceededError{Scope: b.scope, Limit: "turn", Max: fmt.Sprint(b.limits.MaxTurns), Spent: fmt.Sprint(b.turns)}
+	}
+	return nil
+}
+
+// check returns a warning for each limit the run just got close to.
+func (b *runBudget) check() (warnings []string) {
+	if b.limits.MaxCost > 0 && b.cost >= b.limits.MaxCost*budgetWarningRatio && !b.warned["cost"] {
+		b.warned["cost"] = true
+		warnings = append(warnings, fmt.Sprintf("This %s spent %s of its %s cost limit", b.scope, formatCost(b.cost), formatCost(b.limits.MaxCost)))
+	}
+	if b.limits.MaxTokens > 0 && float64(b.tokens) >= float64(b.limits.MaxTokens)*budgetWarningRatio && !b.warned["token"] {
+		b.warned["token"] = true
+		warnings = append(warnings, fmt.Sprintf("This %s used %d of its %d token limit", b.scope, b.tokens, b.limits.MaxTokens))
+	}
+	if b.limits.MaxTurns > 0 && float64(b.turns) >= float64(b.limits.MaxTurns)*budgetWarningRatio && !b.warned["turn"] {
+		b.warned["turn"] = true
+		warnings = append(warnings, fmt.Sprintf("This %s made %d of its %d turn limit", b.scope, b.turns, b.limits.MaxTurns))
+	}
+	return warnings
+}
+
+func (a *agent) SetSessionBudget(sessionID string, budget config.Budget) {
+	a.sessionBudgets.Set(sessionID, budget)
+}
+
+// runBudget returns the context of a run with its budget: the budget of the
+// run that started this agent, if any, or a new one with the configured and
+// session limits, which also checks the session budget against what the
+// session spent in total. The budget is nil when there are no limits.
+func (a *agent) runBudget(ctx context.Context, sessionID string) (context.Context, *runBudget) {
+	if b := runBudgetFromContext(ctx); b != nil {
+		return ctx, b
+	}
+	var limits config.Budget
+	if cfg := config.Get().Options.Budget; cfg != nil {
+		limits = *cfg
+	}
+	if sessionLimits, ok := a.sessionBudgets.Get(sessionID); ok {
+		limits = limits.Merge(sessionLimits)
+	}
+	warn := func(warning string) {
+		slog.Warn("Prompt is close to its budget", "session_id", sessionID, "warning", warning)
+		a.Publish(pubsub.CreatedEvent, AgentEvent{
+			Type:      AgentEventTypeBudgetWarning,
+			SessionID: sessionID,
+			Progress:  warning,
+		})
+	}
+	session := a.sessionBudget(ctx, sessionID, warn)
+	if limits.IsZero() && session == nil {
+		return ctx, nil
+	}
+	b := newRunBudget(limits, warn)
+	b.session = session
+	return withRunBudget(ctx, b), b
+}
+
+// sessionBudget returns the budget of the session, with what it spent so far
+// read from the recorded usage, or nil when sessions have no limits.
+func (a *agent) sessionBudget(ctx context.Context, sessionID string, warn func(string)) *runBudget {
+	cfg := config.Get().Options.SessionBudget
+	if cfg == nil || cfg.IsZero() || a.usage == nil {
+		return nil
+	}
+	total, err := a.usage.SessionTotal(ctx, sessionID)
+	if err != nil {
+		slog.Error("Failed to get session usage, ignoring the session budget", "session_id", sessionID, "error", err)
+		return nil
+	}
+	return newSessionBudget(*cfg, total, warn)
+}
+
+// stopOverBudget ends a run that reached one of its limits with an
+// assistant message explaining why.
+func (a *agent) stopOverBudget(ctx context.Context, sessionID string, err error) AgentEvent {
+	slog.Warn("Stopping prompt over budget", "session_id", sessionID, "error", err)
+	scope := "prompt"
+	var budgetErr *BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		scope = budgetErr.Scope
+	}
+	msg, createErr := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:     message.Assistant,
+		Parts:    []message.ContentPart{},
+		Model:    a.Model().ID,
+		Provider: a.providerID,
+	})
+	if createErr != nil {
+		return a.err(fmt.Errorf("failed to create assistant message: %w", createErr))
+	}
+	a.finishMessage(ctx, &msg, message.FinishReasonBudgetExceeded, "Budget exceeded", "The "+scope+" "+err.Error()+".")
+	return AgentEvent{
+		Type:    AgentEventTypeResponse,
+		Message: msg,
+		Done:    true,
+	}
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("$%.2f", cost)
+}
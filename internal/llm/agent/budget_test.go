@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/usage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBudget(t *testing.T) {
+	t.Parallel()
+
+	var warnings []string
+	b := newRunBudget(config.Budget{MaxCost: 1, MaxTurns: 10}, func(w string) {
+		warnings = append(warnings, w)
+	})
+
+	require.NoError(t, b.startTurn())
+	b.add(provider.TokenUsage{InputTokens: 1000, OutputTokens: 100}, 0.5)
+	require.Empty(t, warnings)
+
+	require.NoError(t, b.startTurn())
+	b.add(provider.TokenUsage{InputTokens: 1000, OutputTokens: 100}, 0.35)
+	require.Equal(t, []string{"This prompt spent $0.85 of its $1.00 cost limit"}, warnings)
+
+	// Warnings are only sent once.
+	require.NoError(t, b.startTurn())
+	b.add(provider.TokenUsage{InputTokens: 1000, OutputTokens: 100}, 0.1)
+	require.Len(t, warnings, 1)
+
+	require.NoError(t, b.startTurn())
+	b.add(provider.TokenUsage{InputTokens: 1000, OutputTokens: 100}, 0.1)
+
+	var budgetErr *BudgetExceededError
+	err := b.startTurn()
+	require.ErrorAs(t, err, &budgetErr)
+	require.Equal(t, "prompt", budgetErr.Scope)
+	require.Equal(t, "cost", budgetErr.Limit)
+	require.EqualError(t, err, "reached the cost limit of $1.00 ($1.05 spent)")
+	require.Equal(t, 4, b.turns)
+}
+
+func TestRunBudgetTurns(t *testing.T) {
+	t.Parallel()
+
+	b := newRunBudget(config.Budget{MaxTurns: 2}, nil)
+	require.NoError(t, b.startTurn())
+	require.NoError(t, b.startTurn())
+	require.EqualError(t, b.startTurn(), "reached the turn limit of 2 (2 spent)")
+}
+
+func TestBudgetMerge(t *testing.T) {
+	t.Parallel()
+
+	global := config.Budget{MaxCost: 10, MaxTurns: 100}
+	require.Equal(t, config.Budget{MaxCost: 2, MaxTurns: 100, MaxTokens: 5000}, global.Merge(config.Budget{MaxCost: 2, MaxTokens: 5000}))
+	require.True(t, config.Budget{}.IsZero())
+	require.False(t, global.IsZero())
+}
+
+func TestCopySessionSettings(t *testing.T) {
+	t.Parallel()
+
+	newAgent := func() *agent {
+		return &agent{
+			sessionBudgets: csync.NewMap[string, config.Budget](),
+			planSessions:   csync.NewMap[string, bool](),
+		}
+	}
+	from, to := newAgent(), newAgent()
+	from.SetSessionBudget("session", config.Budget{MaxTurns: 5})
+	from.SetPlanMode("session", true)
+	to.SetSessionBudget("other", config.Budget{MaxCost: 1})
+
+	CopySessionSettings(from, to)
+	budget, ok := to.sessionBudgets.Get("session")
+	require.True(t, ok)
+	require.Equal(t, config.Budget{MaxTurns: 5}, budget)
+	require.True(t, to.IsPlanMode("session"))
+	require.False(t, to.IsPlanMode("other"))
+	_, ok = to.sessionBudgets.Get("other")
+	require.True(t, ok)
+}
+
+func TestSessionBudget(t *testing.T) {
+	t.Parallel()
+
+	var warnings []string
+	warn := func(w string) { warnings = append(warnings, w) }
+	b := newRunBudget(config.Budget{MaxTurns: 10}, warn)
+	b.session = newSessionBudget(config.Budget{MaxCost: 2}, usage.SessionTotal{AgentRequests: 30, Tokens: 50000, Cost: 1.5}, warn)
+
+	require.NoError(t, b.startTurn())
+	b.add(provider.TokenUsage{InputTokens: 1000, OutputTokens: 100}, 0.2)
+	require.Equal(t, []string{"This session spent $1.70 of its $2.00 cost limit"}, warnings)
+	require.Equal(t, 31, b.session.turns)
+	require.Equal(t, int64(51100), b.session.tokens)
+
+	require.NoError(t, b.startTurn())
+	b.add(provider.TokenUsage{InputTokens: 1000, OutputTokens: 100}, 0.4)
+
+	var budgetErr *BudgetExceededError
+	err := b.startTurn()
+	require.ErrorAs(t, err, &budgetErr)
+	require.Equal(t, "session", budgetErr.Scope)
+	require.EqualError(t, err, "reached the cost limit of $2.00 ($2.10 spent)")
+	// The prompt itself is still within its limits, and the refused turn
+	// isn't counted.
+	require.NoError(t, b.exceeded())
+	require.Equal(t, 2, b.turns)
+	require.Equal(t, 32, b.session.turns)
+}
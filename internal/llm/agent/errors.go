@@ -8,6 +8,9 @@ import (
 var (
 	ErrRequestCancelled = errors.New("request canceled by user")
 	ErrSessionBusy      = errors.New("session is currently processing another request")
+	// ErrQueuedPromptNotFound is returned when a queued prompt was sent or
+	// removed before it could be changed.
+	ErrQueuedPromptNotFound = errors.New("queued prompt not found")
 )
 
 func isCancelledErr(err error) bool {
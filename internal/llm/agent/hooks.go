@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/charmbracelet/crush/internal/hooks"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// callTool runs a tool call between the pre and post tool use hooks. A pre
+// hook that fails blocks the call, and what the post hooks that fail print is
+// added to the result for the model to act on. The call stored in the
+// conversation keeps the params of the model, so the result says when a hook
+// changed them.
+func (a *agent) callTool(ctx context.Context, tool tools.BaseTool, toolCall message.ToolCall) (tools.ToolResponse, error) {
+	sessionID, _ := tools.GetContextValues(ctx)
+	input, err := a.hooks.PreToolUse(ctx, sessionID, toolCall.Name, toolCall.ID, toolCall.Input)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return tools.ToolResponse{}, err
+		}
+		return tools.NewTextErrorResponse("Tool call blocked by " + err.Error()), nil
+	}
+
+	response, err := tool.Run(ctx, tools.ToolCall{
+		ID:    toolCall.ID,
+		Name:  toolCall.Name,
+		Input: input,
+	})
+	if err != nil {
+		return response, err
+	}
+	if input != toolCall.Input {
+		response.Content += "\n\nA hook changed the params of this call to: " + input
+	}
+
+	feedback := a.hooks.PostToolUse(ctx, sessionID, toolCall.Name, toolCall.ID, input, hooks.Result{
+		Content: response.Content,
+		IsError: response.IsError,
+	})
+	if feedback != "" {
+		response.Content += "\n\n" + feedback
+	}
+	return response, nil
+}
+
+// runTurnEndHooks runs the turn end hooks with how the turn ended. Turns of
+// task sessions are part of the turn of their parent session, so they don't
+// run the hooks.
+func (a *agent) runTurnEndHooks(sessionID string, result AgentEvent) {
+	sess, err := a.sessions.Get(context.Background(), sessionID)
+	if err != nil || sess.ParentSessionID != "" {
+		return
+	}
+	var finishReason, response string
+	switch {
+	case result.Error != nil && isCancelledErr(result.Error):
+		finishReason = string(message.FinishReasonCanceled)
+	case result.Error != nil:
+		finishReason = string(message.FinishReasonError)
+		response = result.Error.Error()
+	default:
+		finishReason = string(result.Message.FinishReason())
+		response = result.Message.Content().Text
+	}
+	a.hooks.TurnEnd(context.Background(), sessionID, finishReason, response)
+}
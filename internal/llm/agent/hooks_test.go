@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/hooks"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+type echoTool struct {
+	tools.BaseTool
+}
+
+func (echoTool) Run(_ context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.NewTextResponse("ran " + call.Input), nil
+}
+
+func TestCallTool(t *testing.T) {
+	t.Parallel()
+
+	a := &agent{hooks: hooks.NewRunner(&config.Hooks{
+		PreToolUse: []config.Hook{{
+			Command: `echo '{"params": {"command": "go test ./..."}}'`,
+			Tools:   []string{tools.BashToolName},
+		}},
+	}, t.TempDir())}
+
+	response, err := a.callTool(t.Context(), echoTool{}, message.ToolCall{ID: "call", Name: tools.BashToolName, Input: `{"command": "go test"}`})
+	require.NoError(t, err)
+	require.Equal(t, `ran {"command": "go test ./..."}`+"\n\n"+`A hook changed the params of this call to: {"command": "go test ./..."}`, response.Content)
+
+	// Calls the hooks leave alone don't get a note.
+	response, err = a.callTool(t.Context(), echoTool{}, message.ToolCall{ID: "call", Name: tools.ViewToolName, Input: `{"file_path": "main.go"}`})
+	require.NoError(t, err)
+	require.Equal(t, `ran {"file_path": "main.go"}`, response.Content)
+}
+
+func TestRunTurnEndHooks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	a := &agent{
+		sessions: &fakeSessions{sessions: map[string]session.Session{
+			"session": {ID: "session"},
+			"task":    {ID: "task", ParentSessionID: "session"},
+		}},
+		hooks: hooks.NewRunner(&config.Hooks{
+			OnTurnEnd: []config.Hook{{Command: `read -r line; echo "$line" >> turns.jsonl`}},
+		}, dir),
+	}
+	done := AgentEvent{Message: message.Message{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "Done"}, message.Finish{Reason: message.FinishReasonEndTurn}},
+	}}
+
+	// Task sessions are part of the turn of their parent session.
+	a.runTurnEndHooks("task", done)
+	require.NoFileExists(t, filepath.Join(dir, "turns.jsonl"))
+
+	a.runTurnEndHooks("session", done)
+	data, err := os.ReadFile(filepath.Join(dir, "turns.jsonl"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"session_id":"session"`)
+	require.Contains(t, string(data), `"response":"Done"`)
+}
@@ -362,6 +362,33 @@ func createAndInitializeClient(ctx context.Context, name string, m config.MCPCon
 	return c, nil
 }
 
+// ProbeMCP starts the given MCP server, lists its tools and closes it again,
+// without registering the client. It returns the number of tools exposed by
+// the server.
+func ProbeMCP(ctx context.Context, name string, m config.MCPConfig, resolver config.VariableResolver) (int, error) {
+	c, err := createMcpClient(name, m, resolver)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	timeout := mcpTimeout(m)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := c.Start(ctx); err != nil {
+		return 0, maybeTimeoutErr(err, timeout)
+	}
+	if _, err := c.Initialize(ctx, mcpInitRequest); err != nil {
+		return 0, maybeTimeoutErr(err, timeout)
+	}
+	result, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return 0, maybeTimeoutErr(err, timeout)
+	}
+	return len(result.Tools), nil
+}
+
 func maybeTimeoutErr(err error, timeout time.Duration) error {
 	if errors.Is(err, context.DeadlineExceeded) {
 		return fmt.Errorf("timed out after %s", timeout)
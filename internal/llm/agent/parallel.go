@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// defaultParallelToolCalls is the number of read-only tool calls run at the
+// same time when the config doesn't say otherwise.
+const defaultParallelToolCalls = 4
+
+type toolExecResult struct {
+	response tools.ToolResponse
+	err      error
+}
+
+// toolRunFunc runs a tool call of a turn.
+type toolRunFunc func(ctx context.Context, tool tools.BaseTool, toolCall message.ToolCall) (tools.ToolResponse, error)
+
+// isParallelTool reports whether calls to the tool can run at the same time
+// as other calls: it doesn't modify anything, and the task agents it may
+// start only have read-only tools.
+func isParallelTool(name string) bool {
+	return name == AgentToolName || slices.Contains(config.ReadOnlyTools(), name)
+}
+
+func parallelToolCallsLimit() int {
+	if limit := config.Get().Options.ParallelToolCalls; limit > 0 {
+		return limit
+	}
+	return defaultParallelToolCalls
+}
+
+// startParallelToolCalls starts the run of tool calls at the start of
+// toolCalls that can run at the same time, at most limit at a time, with run.
+// The run ends at the first call that can't, so calls never start before the
+// calls that come ahead of them and may change what they see are done. It
+// returns the channel each started call sends its result on, or nil unless
+// the run has at least two calls.
+func startParallelToolCalls(ctx context.Context, toolCalls []message.ToolCall, allTools []tools.BaseTool, canRun func(message.ToolCall) bool, run toolRunFunc, limit int) []chan toolExecResult {
+	if limit <= 1 {
+		return nil
+	}
+
+	var runnable []tools.BaseTool
+	for _, toolCall := range toolCalls {
+		if !isParallelTool(toolCall.Name) || !canRun(toolCall) {
+			break
+		}
+		idx := slices.IndexFunc(allTools, func(tool tools.BaseTool) bool {
+			return tool.Info().Name == toolCall.Name
+		})
+		if idx == -1 {
+			break
+		}
+		runnable = append(runnable, allTools[idx])
+	}
+	if len(runnable) < 2 {
+		return nil
+	}
+
+	sem := make(chan struct{}, limit)
+	results := make([]chan toolExecResult, len(runnable))
+	for i, tool := range runnable {
+		toolCall := toolCalls[i]
+		resultChan := make(chan toolExecResult, 1)
+		results[i] = resultChan
+		go func() {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				resultChan <- toolExecResult{err: ctx.Err()}
+				return
+			}
+			defer func() { <-sem }()
+			response, err := run(ctx, tool, toolCall)
+			resultChan <- toolExecResult{response: response, err: err}
+		}()
+	}
+	return results
+}
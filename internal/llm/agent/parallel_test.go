@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+type slowTool struct {
+	name    string
+	running *atomic.Int32
+	maxSeen *atomic.Int32
+}
+
+func (t *slowTool) Info() tools.ToolInfo { return tools.ToolInfo{Name: t.name} }
+
+func (t *slowTool) Name() string { return t.name }
+
+func (t *slowTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	n := t.running.Add(1)
+	defer t.running.Add(-1)
+	for {
+		seen := t.maxSeen.Load()
+		if n <= seen || t.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return tools.NewTextResponse(call.ID), nil
+}
+
+func runToolCall(ctx context.Context, tool tools.BaseTool, toolCall message.ToolCall) (tools.ToolResponse, error) {
+	return tool.Run(ctx, tools.ToolCall{ID: toolCall.ID, Name: toolCall.Name, Input: toolCall.Input})
+}
+
+func newSlowTools(names ...string) ([]tools.BaseTool, *atomic.Int32) {
+	var running, maxSeen atomic.Int32
+	var allTools []tools.BaseTool
+	for _, name := range names {
+		allTools = append(allTools, &slowTool{name: name, running: &running, maxSeen: &maxSeen})
+	}
+	return allTools, &maxSeen
+}
+
+func TestStartParallelToolCalls(t *testing.T) {
+	t.Parallel()
+
+	allTools, maxSeen := newSlowTools("view", "grep", "edit")
+	toolCalls := []message.ToolCall{
+		{ID: "1", Name: "view"},
+		{ID: "2", Name: "grep"},
+		{ID: "3", Name: "view"},
+		{ID: "4", Name: "edit"},
+		{ID: "5", Name: "view"},
+		{ID: "6", Name: "grep"},
+	}
+	canRun := func(message.ToolCall) bool { return true }
+
+	// Only the calls ahead of the edit start.
+	results := startParallelToolCalls(t.Context(), toolCalls, allTools, canRun, runToolCall, 2)
+	require.Len(t, results, 3)
+	for i, result := range results {
+		got := <-result
+		require.NoError(t, got.err)
+		require.Equal(t, toolCalls[i].ID, got.response.Content)
+	}
+	require.Equal(t, int32(2), maxSeen.Load())
+
+	// The calls after the edit start once the loop gets to them.
+	require.Nil(t, startParallelToolCalls(t.Context(), toolCalls[3:], allTools, canRun, runToolCall, 2))
+	results = startParallelToolCalls(t.Context(), toolCalls[4:], allTools, canRun, runToolCall, 2)
+	require.Len(t, results, 2)
+	for i, result := range results {
+		require.Equal(t, toolCalls[4+i].ID, (<-result).response.Content)
+	}
+}
+
+func TestStartParallelToolCallsEndsRun(t *testing.T) {
+	t.Parallel()
+
+	allTools, _ := newSlowTools("view", "grep", "edit")
+	canRun := func(toolCall message.ToolCall) bool { return toolCall.ID != "denied" }
+
+	for _, tt := range []struct {
+		name      string
+		toolCalls []message.ToolCall
+		started   int
+	}{
+		{
+			name:      "missing tool",
+			toolCalls: []message.ToolCall{{ID: "1", Name: "view"}, {ID: "2", Name: "missing"}, {ID: "3", Name: "view"}},
+		},
+		{
+			name:      "denied call",
+			toolCalls: []message.ToolCall{{ID: "1", Name: "view"}, {ID: "2", Name: "grep"}, {ID: "denied", Name: "view"}},
+			started:   2,
+		},
+		{
+			name:      "single call",
+			toolCalls: []message.ToolCall{{ID: "1", Name: "view"}, {ID: "2", Name: "edit"}, {ID: "3", Name: "view"}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			results := startParallelToolCalls(t.Context(), tt.toolCalls, allTools, canRun, runToolCall, 4)
+			require.Len(t, results, tt.started)
+			for _, result := range results {
+				require.NoError(t, (<-result).err)
+			}
+		})
+	}
+
+	toolCalls := []message.ToolCall{{ID: "1", Name: "view"}, {ID: "2", Name: "view"}}
+	require.Nil(t, startParallelToolCalls(t.Context(), toolCalls, allTools, canRun, runToolCall, 1))
+}
+
+func TestStartParallelTaskAgents(t *testing.T) {
+	t.Parallel()
+
+	// Task agents only get read-only tools, so several of them run at once.
+	allTools, maxSeen := newSlowTools("agent", "view")
+	toolCalls := []message.ToolCall{
+		{ID: "1", Name: "agent"},
+		{ID: "2", Name: "agent"},
+		{ID: "3", Name: "view"},
+	}
+	results := startParallelToolCalls(t.Context(), toolCalls, allTools, func(message.ToolCall) bool { return true }, runToolCall, 4)
+	require.Len(t, results, 3)
+	for i, result := range results {
+		require.Equal(t, toolCalls[i].ID, (<-result).response.Content)
+	}
+	require.Greater(t, maxSeen.Load(), int32(1))
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// planTools are the tools the agent keeps in plan mode.
+var planTools = []string{
+	tools.ViewToolName,
+	tools.GrepToolName,
+	tools.GlobToolName,
+	tools.LSToolName,
+	tools.DiagnosticsToolName,
+	tools.SourcegraphToolName,
+}
+
+// PlanApprovedPrompt is sent on behalf of the user when they approve a plan.
+const PlanApprovedPrompt = "The plan is approved. Go ahead and implement it."
+
+const planModeInstructions = `<plan_mode>
+Plan mode is on: you can read the code but not change anything, and only have read-only tools. Explore as much as you need, then reply with a plan for the user to review, in this format:
+
+## Plan
+
+### Goal
+What the change achieves, in one or two sentences.
+
+### Steps
+1. Each change to make, in order, naming the files and functions it touches.
+
+### Verification
+How to check that the change works, like the tests to run or add.
+
+### Open Questions
+Anything you need the user to decide, or "None".
+
+Don't keep a todo list either, the steps of the plan take its place. Don't start implementing the plan: the user will approve it, or ask for changes, first.
+</plan_mode>`
+
+func (a *agent) SetPlanMode(sessionID string, enabled bool) {
+	if enabled {
+		a.planSessions.Set(sessionID, true)
+	} else {
+		a.planSessions.Del(sessionID)
+	}
+}
+
+func (a *agent) IsPlanMode(sessionID string) bool {
+	enabled, _ := a.planSessions.Get(sessionID)
+	return enabled
+}
+
+// planModeTools returns the tools of agentTools that are available in plan
+// mode.
+func planModeTools(agentTools []tools.BaseTool) []tools.BaseTool {
+	var available []tools.BaseTool
+	for _, tool := range agentTools {
+		if slices.Contains(planTools, tool.Info().Name) {
+			available = append(available, tool)
+		}
+	}
+	return available
+}
+
+// withPlanInstructions returns a copy of msgHistory where the last user
+// message asks for a plan. The instructions are only sent to the model, and
+// never stored.
+func withPlanInstructions(msgHistory []message.Message) []message.Message {
+	for i := len(msgHistory) - 1; i >= 0; i-- {
+		if msgHistory[i].Role != message.User {
+			continue
+		}
+		history := slices.Clone(msgHistory)
+		history[i].Parts = append(slices.Clone(history[i].Parts), message.TextContent{Text: planModeInstructions})
+		return history
+	}
+	return msgHistory
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPlanInstructions(t *testing.T) {
+	t.Parallel()
+
+	history := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "first"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "answer"}}},
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "add pagination"}}},
+		{Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{ToolCallID: "1"}}},
+	}
+
+	planned := withPlanInstructions(history)
+	require.Len(t, planned, 4)
+	require.Len(t, planned[0].Parts, 1)
+	require.Len(t, planned[2].Parts, 2)
+	require.Equal(t, message.TextContent{Text: planModeInstructions}, planned[2].Parts[1])
+
+	// The history itself is left alone.
+	require.Len(t, history[2].Parts, 1)
+}
+
+// stubTool is a tool that does nothing, for tests that only look at names.
+type stubTool struct{ name string }
+
+func (t stubTool) Info() tools.ToolInfo { return tools.ToolInfo{Name: t.name} }
+
+func (t stubTool) Name() string { return t.name }
+
+func (t stubTool) Run(context.Context, tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.NewTextResponse(""), nil
+}
+
+func TestPlanModeTools(t *testing.T) {
+	t.Parallel()
+
+	allTools := []tools.BaseTool{
+		stubTool{tools.ViewToolName},
+		stubTool{tools.EditToolName},
+		stubTool{AgentToolName},
+		stubTool{tools.GrepToolName},
+	}
+
+	var names []string
+	for _, tool := range planModeTools(allTools) {
+		names = append(names, tool.Name())
+	}
+	require.Equal(t, []string{tools.ViewToolName, tools.GrepToolName}, names)
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+const (
+	// pruneThreshold is the share of the context window the history may take
+	// before old tool results are elided.
+	pruneThreshold = 0.7
+	// pruneTarget is the share of the context window pruning aims for.
+	pruneTarget = 0.5
+	// keepRecentToolResults is the number of most recent tool results that
+	// are never elided.
+	keepRecentToolResults = 10
+	// minPrunableResultTokens is the estimated size below which a tool result
+	// isn't worth eliding.
+	minPrunableResultTokens = 500
+)
+
+// estimateTokens roughly estimates the number of tokens the text of the
+// messages takes, at four characters a token.
+func estimateTokens(msgs []message.Message) int {
+	chars := 0
+	for _, msg := range msgs {
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case message.TextContent:
+				chars += len(p.Text)
+			case message.ToolCall:
+				chars += len(p.Name) + len(p.Input)
+			case message.ToolResult:
+				chars += len(p.Content)
+			}
+		}
+	}
+	return chars / 4
+}
+
+// pruneToolResults elides old tool results from the history when it takes
+// more than pruneThreshold of the context window, until it's back under
+// pruneTarget. File views that a later view or edit of the same file
+// superseded go first, then other large results, oldest first. The most
+// recent tool results are always kept. The history itself is left alone; it
+// returns a copy and the number of results elided.
+func pruneToolResults(msgs []message.Message, contextWindow int64, workingDir string) ([]message.Message, int) {
+	tokens := estimateTokens(msgs)
+	if contextWindow <= 0 || float64(tokens) < pruneThreshold*float64(contextWindow) {
+		return msgs, 0
+	}
+	target := int(pruneTarget * float64(contextWindow))
+
+	type resultRef struct {
+		msg, part int
+		tokens    int
+		tool      string
+		path      string
+	}
+	var results []resultRef
+	calls := make(map[string]message.ToolCall)
+	for i, msg := range msgs {
+		for _, call := range msg.ToolCalls() {
+			calls[call.ID] = call
+		}
+		for j, part := range msg.Parts {
+			result, ok := part.(message.ToolResult)
+			if !ok {
+				continue
+			}
+			call := calls[result.ToolCallID]
+			results = append(results, resultRef{
+				msg:    i,
+				part:   j,
+				tokens: len(result.Content) / 4,
+				tool:   call.Name,
+				path:   toolCallFilePath(call, workingDir),
+			})
+		}
+	}
+	prunable := len(results) - keepRecentToolResults
+	if prunable <= 0 {
+		return msgs, 0
+	}
+
+	superseded := make([]bool, prunable)
+	var candidates []int
+	for i := range prunable {
+		if results[i].tool != tools.ViewToolName || results[i].path == "" {
+			continue
+		}
+		superseded[i] = slices.ContainsFunc(results[i+1:], func(later resultRef) bool {
+			return later.path == results[i].path
+		})
+		if superseded[i] {
+			candidates = append(candidates, i)
+		}
+	}
+	for i := range prunable {
+		if !superseded[i] && results[i].tokens >= minPrunableResultTokens {
+			candidates = append(candidates, i)
+		}
+	}
+
+	pruned := slices.Clone(msgs)
+	cloned := make(map[int]bool)
+	elided := 0
+	for _, idx := range candidates {
+		if tokens <= target {
+			break
+		}
+		ref := results[idx]
+		if !cloned[ref.msg] {
+			pruned[ref.msg].Parts = slices.Clone(pruned[ref.msg].Parts)
+			cloned[ref.msg] = true
+		}
+		result := pruned[ref.msg].Parts[ref.part].(message.ToolResult)
+		placeholder := elidedResult(ref.tool, ref.path, result.Content, superseded[idx])
+		tokens -= ref.tokens - len(placeholder)/4
+		result.Content = placeholder
+		pruned[ref.msg].Parts[ref.part] = result
+		elided++
+	}
+	return pruned, elided
+}
+
+func elidedResult(tool, path, content string, superseded bool) string {
+	if superseded {
+		return fmt.Sprintf("[Content of %s elided to save context: the file was viewed or changed again later in the conversation. View it again if you need it.]", path)
+	}
+	return fmt.Sprintf("[Output of %s elided to save context: %d lines. Run the tool again if you need it.]", tool, strings.Count(content, "\n")+1)
+}
+
+// toolCallFilePath returns the file a view, edit or write tool call works on,
+// or "" for other tool calls.
+func toolCallFilePath(call message.ToolCall, workingDir string) string {
+	switch call.Name {
+	case tools.ViewToolName, tools.EditToolName, tools.MultiEditToolName, tools.WriteToolName:
+	default:
+		return ""
+	}
+	var params struct {
+		FilePath string `json:"file_path"`
+	}
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil || params.FilePath == "" {
+		return ""
+	}
+	if !filepath.IsAbs(params.FilePath) {
+		return filepath.Join(workingDir, params.FilePath)
+	}
+	return filepath.Clean(params.FilePath)
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+// toolTurn returns an assistant message calling a tool and the message with
+// its result. Like stored results, the result doesn't name the tool: only the
+// call does.
+func toolTurn(id, name, input, result string) []message.Message {
+	return []message.Message{
+		{
+			Role:  message.Assistant,
+			Parts: []message.ContentPart{message.ToolCall{ID: id, Name: name, Input: input, Finished: true}},
+		},
+		{
+			Role:  message.Tool,
+			Parts: []message.ContentPart{message.ToolResult{ToolCallID: id, Content: result}},
+		},
+	}
+}
+
+func toolResultContent(t *testing.T, msgs []message.Message, id string) string {
+	t.Helper()
+	for _, msg := range msgs {
+		for _, result := range msg.ToolResults() {
+			if result.ToolCallID == id {
+				return result.Content
+			}
+		}
+	}
+	t.Fatalf("no result for tool call %s", id)
+	return ""
+}
+
+func TestPruneToolResults(t *testing.T) {
+	t.Parallel()
+
+	big := strings.Repeat("0123456789abcdef\n", 1000) // ~4k tokens
+	var msgs []message.Message
+	msgs = append(msgs, toolTurn("view-1", tools.ViewToolName, `{"file_path":"main.go"}`, "package main")...)
+	msgs = append(msgs, toolTurn("bash-1", tools.BashToolName, `{"command":"go test ./..."}`, big)...)
+	msgs = append(msgs, toolTurn("edit-1", tools.EditToolName, `{"file_path":"/work/main.go"}`, "ok")...)
+	msgs = append(msgs, toolTurn("bash-2", tools.BashToolName, `{"command":"ls"}`, "small")...)
+	for i := range keepRecentToolResults {
+		msgs = append(msgs, toolTurn(fmt.Sprintf("recent-%d", i), tools.BashToolName, `{}`, big)...)
+	}
+
+	t.Run("under threshold", func(t *testing.T) {
+		t.Parallel()
+		pruned, elided := pruneToolResults(msgs, 1_000_000, "/work")
+		require.Zero(t, elided)
+		require.Equal(t, msgs, pruned)
+	})
+
+	t.Run("stale results elided", func(t *testing.T) {
+		t.Parallel()
+		// 11 big results, ~47k tokens.
+		pruned, elided := pruneToolResults(msgs, 60_000, "/work")
+		require.Equal(t, 2, elided)
+		// Superseded views are elided however small they are.
+		require.Equal(t, "[Content of /work/main.go elided to save context: the file was viewed or changed again later in the conversation. View it again if you need it.]", toolResultContent(t, pruned, "view-1"))
+		require.Equal(t, "[Output of bash elided to save context: 1001 lines. Run the tool again if you need it.]", toolResultContent(t, pruned, "bash-1"))
+		require.Equal(t, "ok", toolResultContent(t, pruned, "edit-1"))
+		require.Equal(t, "small", toolResultContent(t, pruned, "bash-2"))
+		for i := range keepRecentToolResults {
+			require.Equal(t, big, toolResultContent(t, pruned, fmt.Sprintf("recent-%d", i)))
+		}
+	})
+
+	t.Run("history left alone", func(t *testing.T) {
+		t.Parallel()
+		pruneToolResults(msgs, 60_000, "/work")
+		require.Equal(t, "package main", toolResultContent(t, msgs, "view-1"))
+		require.Equal(t, big, toolResultContent(t, msgs, "bash-1"))
+	})
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// QueuedPrompt is a prompt sent while its session was busy, waiting to be
+// sent to the model.
+type QueuedPrompt struct {
+	ID      string
+	Content string
+}
+
+func (a *agent) enqueuePrompt(sessionID, content string) {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	existing, _ := a.promptQueue.Get(sessionID)
+	a.promptQueue.Set(sessionID, append(existing, QueuedPrompt{
+		ID:      uuid.New().String(),
+		Content: content,
+	}))
+}
+
+func (a *agent) takeQueue(sessionID string) ([]QueuedPrompt, bool) {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	return a.promptQueue.Take(sessionID)
+}
+
+// updateQueue replaces the queue of a session with what fn makes of it.
+func (a *agent) updateQueue(sessionID string, fn func(queue []QueuedPrompt) ([]QueuedPrompt, error)) error {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	existing, _ := a.promptQueue.Get(sessionID)
+	queue, err := fn(slices.Clone(existing))
+	if err != nil {
+		return err
+	}
+	if len(queue) == 0 {
+		a.promptQueue.Del(sessionID)
+		return nil
+	}
+	a.promptQueue.Set(sessionID, queue)
+	return nil
+}
+
+// updateQueuedPrompt calls fn with the queue of a session and the index of
+// the prompt with the given id in it.
+func (a *agent) updateQueuedPrompt(sessionID, id string, fn func(queue []QueuedPrompt, idx int) []QueuedPrompt) error {
+	return a.updateQueue(sessionID, func(queue []QueuedPrompt) ([]QueuedPrompt, error) {
+		idx := slices.IndexFunc(queue, func(p QueuedPrompt) bool {
+			return p.ID == id
+		})
+		if idx == -1 {
+			return nil, ErrQueuedPromptNotFound
+		}
+		return fn(queue, idx), nil
+	})
+}
+
+func (a *agent) QueuedPrompts(sessionID string) int {
+	l, ok := a.promptQueue.Get(sessionID)
+	if !ok {
+		return 0
+	}
+	return len(l)
+}
+
+func (a *agent) ListQueue(sessionID string) []QueuedPrompt {
+	l, _ := a.promptQueue.Get(sessionID)
+	return slices.Clone(l)
+}
+
+func (a *agent) UpdateQueuedPrompt(sessionID, id, content string) error {
+	return a.updateQueuedPrompt(sessionID, id, func(queue []QueuedPrompt, idx int) []QueuedPrompt {
+		queue[idx].Content = content
+		return queue
+	})
+}
+
+func (a *agent) MoveQueuedPrompt(sessionID, id string, offset int) error {
+	return a.updateQueuedPrompt(sessionID, id, func(queue []QueuedPrompt, idx int) []QueuedPrompt {
+		prompt := queue[idx]
+		queue = slices.Delete(queue, idx, idx+1)
+		to := min(max(idx+offset, 0), len(queue))
+		return slices.Insert(queue, to, prompt)
+	})
+}
+
+func (a *agent) RemoveQueuedPrompt(sessionID, id string) error {
+	return a.updateQueuedPrompt(sessionID, id, func(queue []QueuedPrompt, idx int) []QueuedPrompt {
+		return slices.Delete(queue, idx, idx+1)
+	})
+}
+
+// PromoteQueuedPrompt moves the prompt to the front of the queue and cancels
+// the current request of the session. The prompt is then sent, followed by
+// the rest of the queue.
+func (a *agent) PromoteQueuedPrompt(sessionID, id string) error {
+	err := a.updateQueuedPrompt(sessionID, id, func(queue []QueuedPrompt, idx int) []QueuedPrompt {
+		prompt := queue[idx]
+		return slices.Insert(slices.Delete(queue, idx, idx+1), 0, prompt)
+	})
+	if err != nil {
+		return err
+	}
+	// The request ends holding the queue lock too, so it either sees the
+	// interruption and sends the queue, or is over before we look.
+	a.queueMu.Lock()
+	cancel, ok := a.activeRequests.Get(sessionID)
+	if ok && cancel != nil {
+		a.interrupted.Set(sessionID, true)
+	}
+	a.queueMu.Unlock()
+	if !ok || cancel == nil {
+		// Nothing to interrupt, the request ended without sending the
+		// queue.
+		a.runQueue(context.Background(), sessionID)
+		return nil
+	}
+	slog.Info("Interrupting request for queued prompt", "session_id", sessionID)
+	cancel()
+	return nil
+}
+
+// runQueue sends the first queued prompt of a session. The rest of the queue
+// is sent along the way.
+func (a *agent) runQueue(ctx context.Context, sessionID string) {
+	a.queueMu.Lock()
+	queue, _ := a.promptQueue.Get(sessionID)
+	if len(queue) == 0 {
+		a.queueMu.Unlock()
+		return
+	}
+	if len(queue) > 1 {
+		a.promptQueue.Set(sessionID, slices.Clone(queue[1:]))
+	} else {
+		a.promptQueue.Del(sessionID)
+	}
+	a.queueMu.Unlock()
+
+	if _, err := a.Run(ctx, sessionID, queue[0].Content); err != nil {
+		slog.Error("Failed to send queued prompt", "session_id", sessionID, "error", err)
+	}
+}
+
+func (a *agent) ClearQueue(sessionID string) {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	if _, ok := a.promptQueue.Take(sessionID); ok {
+		slog.Info("Clearing queued prompts", "session_id", sessionID)
+	}
+}
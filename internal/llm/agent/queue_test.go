@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/stretchr/testify/require"
+)
+
+func newQueueAgent() *agent {
+	return &agent{
+		activeRequests: csync.NewMap[string, context.CancelFunc](),
+		promptQueue:    csync.NewMap[string, []QueuedPrompt](),
+		interrupted:    csync.NewMap[string, bool](),
+	}
+}
+
+func queueContents(a *agent, sessionID string) []string {
+	var contents []string
+	for _, prompt := range a.ListQueue(sessionID) {
+		contents = append(contents, prompt.Content)
+	}
+	return contents
+}
+
+func TestPromptQueue(t *testing.T) {
+	t.Parallel()
+
+	a := newQueueAgent()
+	for _, content := range []string{"one", "two", "three"} {
+		a.enqueuePrompt("session", content)
+	}
+	require.Equal(t, 3, a.QueuedPrompts("session"))
+	queue := a.ListQueue("session")
+
+	require.NoError(t, a.MoveQueuedPrompt("session", queue[2].ID, -1))
+	require.Equal(t, []string{"one", "three", "two"}, queueContents(a, "session"))
+	// Moves stop at either end of the queue.
+	require.NoError(t, a.MoveQueuedPrompt("session", queue[0].ID, 5))
+	require.Equal(t, []string{"three", "two", "one"}, queueContents(a, "session"))
+
+	require.NoError(t, a.UpdateQueuedPrompt("session", queue[1].ID, "TWO"))
+	require.Equal(t, []string{"three", "TWO", "one"}, queueContents(a, "session"))
+
+	require.NoError(t, a.RemoveQueuedPrompt("session", queue[2].ID))
+	require.Equal(t, []string{"TWO", "one"}, queueContents(a, "session"))
+	require.ErrorIs(t, a.RemoveQueuedPrompt("session", queue[2].ID), ErrQueuedPromptNotFound)
+
+	// Changing the queue doesn't change what was listed before.
+	require.Equal(t, "one", queue[0].Content)
+
+	taken, ok := a.takeQueue("session")
+	require.True(t, ok)
+	require.Len(t, taken, 2)
+	require.Zero(t, a.QueuedPrompts("session"))
+	require.ErrorIs(t, a.UpdateQueuedPrompt("session", taken[0].ID, "gone"), ErrQueuedPromptNotFound)
+	require.Zero(t, a.QueuedPrompts("session"))
+}
+
+func TestPromoteQueuedPrompt(t *testing.T) {
+	t.Parallel()
+
+	a := newQueueAgent()
+	ctx, cancel := context.WithCancel(context.Background())
+	a.activeRequests.Set("session", cancel)
+	a.enqueuePrompt("session", "one")
+	a.enqueuePrompt("session", "two")
+
+	require.NoError(t, a.PromoteQueuedPrompt("session", a.ListQueue("session")[1].ID))
+	require.Equal(t, []string{"two", "one"}, queueContents(a, "session"))
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
+	_, interrupted := a.interrupted.Get("session")
+	require.True(t, interrupted)
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/usage"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSessions struct {
+	session.Service
+	sessions map[string]session.Session
+}
+
+func (f *fakeSessions) Get(_ context.Context, id string) (session.Session, error) {
+	return f.sessions[id], nil
+}
+
+func (f *fakeSessions) Save(_ context.Context, sess session.Session) (session.Session, error) {
+	f.sessions[sess.ID] = sess
+	return sess, nil
+}
+
+type fakeUsage struct {
+	usage.Service
+	records []usage.Record
+}
+
+func (f *fakeUsage) Create(_ context.Context, record usage.Record) error {
+	f.records = append(f.records, record)
+	return nil
+}
+
+// fakeTitleProvider answers with a title, running onRequest while the
+// request is in flight.
+type fakeTitleProvider struct {
+	provider.Provider
+	title     string
+	onRequest func()
+}
+
+func (p *fakeTitleProvider) StreamResponse(context.Context, []message.Message, []tools.BaseTool) <-chan provider.ProviderEvent {
+	p.onRequest()
+	events := make(chan provider.ProviderEvent, 1)
+	events <- provider.ProviderEvent{
+		Type: provider.EventComplete,
+		Response: &provider.ProviderResponse{
+			Content: p.title,
+			Usage:   provider.TokenUsage{InputTokens: 1000, OutputTokens: 100},
+		},
+	}
+	close(events)
+	return events
+}
+
+func (p *fakeTitleProvider) Model() catwalk.Model {
+	return catwalk.Model{ID: "small", CostPer1MIn: 1, CostPer1MOut: 10}
+}
+
+func TestGenerateTitle(t *testing.T) {
+	t.Parallel()
+
+	sessions := &fakeSessions{sessions: map[string]session.Session{
+		"session": {ID: "session", Title: "New Session"},
+	}}
+	records := &fakeUsage{}
+	a := &agent{
+		sessions: sessions,
+		usage:    records,
+		titleProvider: &fakeTitleProvider{
+			title: "<think>A title</think>\nFix the tests\n",
+			// The prompt asking for the title spends meanwhile.
+			onRequest: func() {
+				sess := sessions.sessions["session"]
+				sess.Cost += 0.5
+				sessions.sessions["session"] = sess
+			},
+		},
+		titleProviderID: "small-provider",
+	}
+
+	require.NoError(t, a.generateTitle(t.Context(), "session", "Please fix the tests"))
+
+	sess := sessions.sessions["session"]
+	require.Equal(t, "Fix the tests", sess.Title)
+	require.InDelta(t, 0.502, sess.Cost, 1e-9)
+
+	require.Len(t, records.records, 1)
+	require.Equal(t, "session", records.records[0].SessionID)
+	require.Equal(t, "small", records.records[0].Model)
+	require.Equal(t, "small-provider", records.records[0].Provider)
+	require.InDelta(t, 0.002, records.records[0].Cost, 1e-9)
+}
@@ -14,19 +14,22 @@ import (
 	"github.com/charmbracelet/crush/internal/llm/tools"
 )
 
-func CoderPrompt(p string, contextFiles ...string) string {
+// CoderPrompt returns the system prompt of a coding agent for provider p.
+// todos reports whether the agent has the todos tool to keep its todo list
+// with.
+func CoderPrompt(p string, todos bool, contextFiles ...string) string {
 	var basePrompt string
 
 	basePrompt = string(anthropicCoderPrompt)
 	switch p {
 	case string(catwalk.InferenceProviderOpenAI):
 		// seems to behave better
-		basePrompt = string(coderV2Prompt)
+		basePrompt = coderV2(todos)
 	case string(catwalk.InferenceProviderGemini):
 		basePrompt = string(geminiCoderPrompt)
 	}
 	if ok, _ := strconv.ParseBool(os.Getenv("CRUSH_CODER_V2")); ok {
-		basePrompt = string(coderV2Prompt)
+		basePrompt = coderV2(todos)
 	}
 	envInfo := getEnvironmentInfo()
 
@@ -48,6 +51,22 @@ var geminiCoderPrompt []byte
 //go:embed v2.md
 var coderV2Prompt []byte
 
+//go:embed todos.md
+var todosPrompt []byte
+
+//go:embed todos_markdown.md
+var todosMarkdownPrompt []byte
+
+// coderV2 returns the v2 coder prompt, with instructions on keeping its todo
+// list with the todos tool when the agent has it, or in its messages.
+func coderV2(todos bool) string {
+	instructions := todosMarkdownPrompt
+	if todos {
+		instructions = todosPrompt
+	}
+	return fmt.Sprintf("%s\n%s", coderV2Prompt, instructions)
+}
+
 func getEnvironmentInfo() string {
 	cwd := config.Get().WorkingDir()
 	isGit := isGitRepo(cwd)
@@ -0,0 +1,32 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// CustomPrompt returns the system prompt of a user-defined agent, read from
+// promptFile, with the environment and project context appended.
+func CustomPrompt(promptFile string, contextFiles ...string) (string, error) {
+	cwd := config.Get().WorkingDir()
+	path := expandPath(promptFile)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read agent prompt: %w", err)
+	}
+
+	basePrompt := fmt.Sprintf("%s\n\n%s\n%s", strings.TrimSpace(string(content)), getEnvironmentInfo(), lspInformation())
+
+	contextContent := getContextFromPaths(cwd, contextFiles)
+	if contextContent != "" {
+		return fmt.Sprintf("%s\n\n# Project-Specific Context\n Make sure to follow the instructions in the context below\n%s", basePrompt, contextContent), nil
+	}
+	return basePrompt, nil
+}
@@ -26,7 +26,7 @@ func GetPrompt(promptID PromptID, provider string, contextPaths ...string) strin
 	basePrompt := ""
 	switch promptID {
 	case PromptCoder:
-		basePrompt = CoderPrompt(provider, contextPaths...)
+		basePrompt = CoderPrompt(provider, true, contextPaths...)
 	case PromptTitle:
 		basePrompt = TitlePrompt()
 	case PromptTask:
@@ -0,0 +1,356 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+const cassetteVersion = 1
+
+// cassetteEvent is a ProviderEvent as stored in a cassette.
+type cassetteEvent struct {
+	Type      EventType         `json:"type"`
+	Content   string            `json:"content,omitempty"`
+	Thinking  string            `json:"thinking,omitempty"`
+	Signature string            `json:"signature,omitempty"`
+	Response  *ProviderResponse `json:"response,omitempty"`
+	ToolCall  *message.ToolCall `json:"tool_call,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// interaction is a recorded request, identified by the hash of the request,
+// and the events of its response.
+type interaction struct {
+	Key      string          `json:"key"`
+	Provider string          `json:"provider"`
+	Model    string          `json:"model"`
+	Events   []cassetteEvent `json:"events"`
+}
+
+type cassetteFile struct {
+	Version      int           `json:"version"`
+	Interactions []interaction `json:"interactions"`
+}
+
+// cassette holds the interactions recorded to a file. It is shared by every
+// provider of the process.
+type cassette struct {
+	path string
+	mode config.CassetteMode
+
+	mu           sync.Mutex
+	interactions []interaction
+	// replayed counts the interactions replayed per key, so that the same
+	// request made twice gets the responses in the order they were recorded.
+	replayed map[string]int
+}
+
+var (
+	cassettesMu sync.Mutex
+	cassettes   = make(map[string]*cassette)
+)
+
+// loadCassette returns the cassette configured in opts, or nil if there is
+// none.
+func loadCassette(opts *config.Cassette) (*cassette, error) {
+	if opts == nil || opts.Path == "" {
+		return nil, nil
+	}
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+	if c, ok := cassettes[opts.Path]; ok {
+		return c, nil
+	}
+	c, err := newCassette(opts.Path, opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+	cassettes[opts.Path] = c
+	return c, nil
+}
+
+func newCassette(path string, mode config.CassetteMode) (*cassette, error) {
+	switch mode {
+	case "":
+		mode = config.CassetteModeAuto
+	case config.CassetteModeAuto, config.CassetteModeRecord, config.CassetteModeReplay:
+	default:
+		return nil, fmt.Errorf("invalid cassette mode %q, must be one of auto, record or replay", mode)
+	}
+
+	c := &cassette{
+		path:     path,
+		mode:     mode,
+		replayed: make(map[string]int),
+	}
+	// Recording starts over.
+	if mode == config.CassetteModeRecord {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && mode == config.CassetteModeAuto {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if file.Version != cassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s", file.Version, path)
+	}
+	c.interactions = file.Interactions
+	return c, nil
+}
+
+// replay returns the events recorded for a request, if any.
+func (c *cassette) replay(key string) ([]cassetteEvent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.replayed[key]
+	for _, i := range c.interactions {
+		if i.Key != key {
+			continue
+		}
+		if n > 0 {
+			n--
+			continue
+		}
+		c.replayed[key]++
+		return i.Events, true
+	}
+	return nil, false
+}
+
+// record adds an interaction and saves the cassette.
+func (c *cassette) record(i interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, i)
+	// Don't replay what was just recorded.
+	c.replayed[i.Key]++
+
+	data, err := json.MarshalIndent(cassetteFile{
+		Version:      cassetteVersion,
+		Interactions: c.interactions,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// cassetteProvider replays the responses recorded in a cassette, and records
+// the responses of provider to it for the requests that were not recorded.
+type cassetteProvider struct {
+	providerID string
+	modelType  config.SelectedModelType
+	model      func(config.SelectedModelType) catwalk.Model
+	// provider is nil when only replaying.
+	provider Provider
+	cassette *cassette
+}
+
+func newCassetteProvider(providerID string, opts providerClientOptions, provider Provider, c *cassette) Provider {
+	return &cassetteProvider{
+		providerID: providerID,
+		modelType:  opts.modelType,
+		model:      opts.model,
+		provider:   provider,
+		cassette:   c,
+	}
+}
+
+func (p *cassetteProvider) Model() catwalk.Model {
+	return p.model(p.modelType)
+}
+
+func (p *cassetteProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	key := p.key(messages, tools)
+	if events, ok := p.cassette.replay(key); ok {
+		for _, e := range events {
+			switch e.Type {
+			case EventComplete:
+				return e.Response, nil
+			case EventError:
+				return nil, errors.New(e.Error)
+			}
+		}
+		return nil, fmt.Errorf("cassette %s has no response for request %s", p.cassette.path, key)
+	}
+	if p.provider == nil {
+		return nil, p.missing(key)
+	}
+
+	response, err := p.provider.SendMessages(ctx, messages, tools)
+	if err != nil {
+		return nil, err
+	}
+	p.record(key, []cassetteEvent{{Type: EventComplete, Response: response}})
+	return response, nil
+}
+
+func (p *cassetteProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	key := p.key(messages, tools)
+	out := make(chan ProviderEvent)
+
+	if events, ok := p.cassette.replay(key); ok {
+		go func() {
+			defer close(out)
+			for _, e := range events {
+				if !sendEvent(ctx, out, e.providerEvent()) {
+					return
+				}
+			}
+		}()
+		return out
+	}
+	if p.provider == nil {
+		go func() {
+			defer close(out)
+			sendEvent(ctx, out, ProviderEvent{Type: EventError, Error: p.missing(key)})
+		}()
+		return out
+	}
+
+	go func() {
+		defer close(out)
+		var recorded []cassetteEvent
+		events := p.provider.StreamResponse(ctx, messages, tools)
+		for event := range events {
+			if !sendEvent(ctx, out, event) {
+				// Nobody reads the response anymore: let the provider
+				// finish without recording it.
+				for range events {
+				}
+				return
+			}
+			// Warnings are about retries, which don't happen on replay.
+			if event.Type != EventWarning {
+				recorded = append(recorded, newCassetteEvent(event))
+			}
+		}
+		// Only complete responses are recorded, so that a failure isn't
+		// replayed forever.
+		if len(recorded) > 0 && recorded[len(recorded)-1].Type == EventComplete {
+			p.record(key, recorded)
+		}
+	}()
+	return out
+}
+
+// sendEvent sends event on out unless ctx is done first, and reports whether
+// it was sent.
+func sendEvent(ctx context.Context, out chan<- ProviderEvent, event ProviderEvent) bool {
+	select {
+	case out <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (p *cassetteProvider) record(key string, events []cassetteEvent) {
+	err := p.cassette.record(interaction{
+		Key:      key,
+		Provider: p.providerID,
+		Model:    p.Model().ID,
+		Events:   events,
+	})
+	if err != nil {
+		// The response is still good, only the recording is lost.
+		slog.Error("Failed to record provider response to cassette", "path", p.cassette.path, "error", err)
+	}
+}
+
+func (p *cassetteProvider) missing(key string) error {
+	return fmt.Errorf("cassette %s has no recorded response for request %s, record it with cassette mode auto or record", p.cassette.path, key)
+}
+
+// key identifies a request by hashing what makes it: the model, the
+// messages and the tools. The system prompt, message IDs and timestamps are
+// left out as they change between runs.
+func (p *cassetteProvider) key(messages []message.Message, tools []tools.BaseTool) string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	_ = enc.Encode([]string{p.providerID, p.Model().ID, string(p.modelType)})
+	for _, msg := range messages {
+		if len(msg.Parts) == 0 {
+			continue
+		}
+		_ = enc.Encode(msg.Role)
+		for _, part := range msg.Parts {
+			switch part := part.(type) {
+			case message.TextContent:
+				_ = enc.Encode([]string{"text", part.Text})
+			case message.ImageURLContent:
+				_ = enc.Encode([]string{"image_url", part.URL})
+			case message.BinaryContent:
+				sum := sha256.Sum256(part.Data)
+				_ = enc.Encode([]string{"binary", part.MIMEType, hex.EncodeToString(sum[:])})
+			case message.ToolCall:
+				_ = enc.Encode([]string{"tool_call", part.Name, part.Input})
+			case message.ToolResult:
+				_ = enc.Encode([]string{"tool_result", part.Name, part.Content, strconv.FormatBool(part.IsError)})
+			}
+		}
+	}
+	for _, tool := range tools {
+		_ = enc.Encode(tool.Info().Name)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func newCassetteEvent(e ProviderEvent) cassetteEvent {
+	ce := cassetteEvent{
+		Type:      e.Type,
+		Content:   e.Content,
+		Thinking:  e.Thinking,
+		Signature: e.Signature,
+		Response:  e.Response,
+		ToolCall:  e.ToolCall,
+	}
+	if e.Error != nil {
+		ce.Error = e.Error.Error()
+	}
+	return ce
+}
+
+func (e cassetteEvent) providerEvent() ProviderEvent {
+	pe := ProviderEvent{
+		Type:      e.Type,
+		Content:   e.Content,
+		Thinking:  e.Thinking,
+		Signature: e.Signature,
+		Response:  e.Response,
+		ToolCall:  e.ToolCall,
+	}
+	if e.Error != "" {
+		pe.Error = errors.New(e.Error)
+	}
+	return pe
+}
@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	calls  int
+	events []ProviderEvent
+}
+
+func (p *fakeProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	p.calls++
+	return p.events[len(p.events)-1].Response, nil
+}
+
+func (p *fakeProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	p.calls++
+	ch := make(chan ProviderEvent, len(p.events))
+	for _, e := range p.events {
+		ch <- e
+	}
+	close(ch)
+	return ch
+}
+
+func (p *fakeProvider) Model() catwalk.Model {
+	return catwalk.Model{ID: "fake-model"}
+}
+
+func collectEvents(ch <-chan ProviderEvent) []ProviderEvent {
+	var events []ProviderEvent
+	for e := range ch {
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "testdata", "cassette.json")
+	opts := providerClientOptions{
+		modelType: config.SelectedModelTypeLarge,
+		model: func(config.SelectedModelType) catwalk.Model {
+			return catwalk.Model{ID: "fake-model"}
+		},
+	}
+	toolCall := message.ToolCall{ID: "call-1", Name: "view", Input: `{"file_path":"main.go"}`, Type: "function", Finished: true}
+	inner := &fakeProvider{events: []ProviderEvent{
+		{Type: EventWarning, Content: "retrying"},
+		{Type: EventThinkingDelta, Thinking: "hmm"},
+		{Type: EventContentDelta, Content: "Let me look."},
+		{Type: EventToolUseStart, ToolCall: &toolCall},
+		{Type: EventComplete, Response: &ProviderResponse{
+			Content:      "Let me look.",
+			ToolCalls:    []message.ToolCall{toolCall},
+			Usage:        TokenUsage{InputTokens: 10, OutputTokens: 5},
+			FinishReason: message.FinishReasonToolUse,
+		}},
+	}}
+	messages := []message.Message{{
+		ID:    "msg-1",
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "What's in main.go?"}},
+	}}
+
+	c, err := newCassette(path, config.CassetteModeAuto)
+	require.NoError(t, err)
+	recorder := newCassetteProvider("fake", opts, inner, c)
+	recorded := collectEvents(recorder.StreamResponse(t.Context(), messages, nil))
+	require.Len(t, recorded, 5)
+	require.Equal(t, 1, inner.calls)
+
+	c, err = newCassette(path, config.CassetteModeReplay)
+	require.NoError(t, err)
+	player := newCassetteProvider("fake", opts, nil, c)
+
+	// Message IDs don't change the request.
+	messages[0].ID = "msg-2"
+	replayed := collectEvents(player.StreamResponse(t.Context(), messages, nil))
+	require.Equal(t, recorded[1:], replayed)
+
+	// The request was only recorded once.
+	events := collectEvents(player.StreamResponse(t.Context(), messages, nil))
+	require.Len(t, events, 1)
+	require.Equal(t, EventError, events[0].Type)
+	require.ErrorContains(t, events[0].Error, "no recorded response")
+
+	_, err = player.SendMessages(t.Context(), []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Something else"}},
+	}}, nil)
+	require.ErrorContains(t, err, "no recorded response")
+}
+
+func TestCassetteSkipsFailedResponses(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	opts := providerClientOptions{
+		model: func(config.SelectedModelType) catwalk.Model {
+			return catwalk.Model{ID: "fake-model"}
+		},
+	}
+	inner := &fakeProvider{events: []ProviderEvent{
+		{Type: EventContentDelta, Content: "Hel"},
+		{Type: EventError, Error: context.DeadlineExceeded},
+	}}
+
+	c, err := newCassette(path, config.CassetteModeAuto)
+	require.NoError(t, err)
+	p := newCassetteProvider("fake", opts, inner, c)
+	collectEvents(p.StreamResponse(t.Context(), nil, nil))
+	collectEvents(p.StreamResponse(t.Context(), nil, nil))
+	require.Equal(t, 2, inner.calls)
+
+	_, err = newCassette(path, config.CassetteModeReplay)
+	require.ErrorContains(t, err, "failed to read cassette")
+}
+
+// blockingProvider streams its events on an unbuffered channel, closing done
+// once they are all read.
+type blockingProvider struct {
+	fakeProvider
+	done chan struct{}
+}
+
+func (p *blockingProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	ch := make(chan ProviderEvent)
+	go func() {
+		defer close(p.done)
+		defer close(ch)
+		for _, e := range p.events {
+			ch <- e
+		}
+	}()
+	return ch
+}
+
+func TestCassetteCanceled(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	opts := providerClientOptions{
+		model: func(config.SelectedModelType) catwalk.Model {
+			return catwalk.Model{ID: "fake-model"}
+		},
+	}
+	inner := &blockingProvider{
+		fakeProvider: fakeProvider{events: []ProviderEvent{
+			{Type: EventContentDelta, Content: "Hello"},
+			{Type: EventComplete, Response: &ProviderResponse{Content: "Hello"}},
+		}},
+		done: make(chan struct{}),
+	}
+
+	c, err := newCassette(path, config.CassetteModeAuto)
+	require.NoError(t, err)
+	p := newCassetteProvider("fake", opts, inner, c)
+
+	// Nobody reads the response of a canceled request: the provider is
+	// still drained, and the response isn't recorded.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	p.StreamResponse(ctx, nil, nil)
+	select {
+	case <-inner.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider response was never drained")
+	}
+	_, ok := c.replay(p.(*cassetteProvider).key(nil, nil))
+	require.False(t, ok)
+}
+
+func TestNewCassetteInvalidMode(t *testing.T) {
+	t.Parallel()
+
+	_, err := newCassette(filepath.Join(t.TempDir(), "cassette.json"), "rewind")
+	require.ErrorContains(t, err, `invalid cassette mode "rewind"`)
+}
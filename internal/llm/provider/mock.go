@@ -0,0 +1,293 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/google/uuid"
+)
+
+// mockScript is the file a mock provider answers from. Every request gets the
+// next response of its role: the ID of the agent making it, "title" or
+// "summarize".
+type mockScript struct {
+	// Delay between the chunks of a streamed response, e.g. "50ms".
+	Delay     string         `json:"delay,omitempty"`
+	Responses []mockResponse `json:"responses"`
+	// Roles answered with responses of their own. The others read Responses
+	// from the start.
+	Roles map[string][]mockResponse `json:"roles,omitempty"`
+}
+
+type mockResponse struct {
+	Thinking     string               `json:"thinking,omitempty"`
+	Content      string               `json:"content,omitempty"`
+	ToolCalls    []mockToolCall       `json:"tool_calls,omitempty"`
+	Usage        TokenUsage           `json:"usage"`
+	FinishReason message.FinishReason `json:"finish_reason,omitempty"`
+	Error        *mockError           `json:"error,omitempty"`
+}
+
+type mockToolCall struct {
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// mockError fails the request like an API error with the given status would.
+// Rate limits (429) and overloads (529) are retried with the next response.
+type mockError struct {
+	Status     int    `json:"status,omitempty"`
+	Message    string `json:"message"`
+	RetryAfter string `json:"retry_after,omitempty"`
+}
+
+func (e *mockError) Error() string {
+	if e.Status == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+}
+
+type mockClient struct {
+	providerOptions providerClientOptions
+	path            string
+	delay           time.Duration
+	responses       []mockResponse
+	cursor          *mockCursor
+}
+
+// mockCursor is the position of a role in a script.
+type mockCursor struct {
+	mu   sync.Mutex
+	next int
+}
+
+// mockCursors holds the cursors by script and role, so that the clients made
+// for a role along the way, e.g. when the model changes, go on where the
+// previous ones stopped.
+var (
+	mockCursorsMu sync.Mutex
+	mockCursors   = map[string]*mockCursor{}
+)
+
+func mockCursorFor(path, role string) *mockCursor {
+	mockCursorsMu.Lock()
+	defer mockCursorsMu.Unlock()
+	key := path + "\x00" + role
+	cursor, ok := mockCursors[key]
+	if !ok {
+		cursor = &mockCursor{}
+		mockCursors[key] = cursor
+	}
+	return cursor
+}
+
+type MockClient ProviderClient
+
+func newMockClient(opts providerClientOptions) (MockClient, error) {
+	path := opts.config.Script
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.Get().WorkingDir(), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock script: %w", err)
+	}
+	var script mockScript
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse mock script %s: %w", path, err)
+	}
+
+	responses, ok := script.Roles[opts.role]
+	if !ok {
+		responses = script.Responses
+	}
+	client := &mockClient{
+		providerOptions: opts,
+		path:            path,
+		responses:       responses,
+		cursor:          mockCursorFor(path, opts.role),
+	}
+	if script.Delay != "" {
+		if client.delay, err = time.ParseDuration(script.Delay); err != nil {
+			return nil, fmt.Errorf("invalid delay in mock script %s: %w", path, err)
+		}
+	}
+	for i, r := range responses {
+		if r.Error == nil || r.Error.RetryAfter == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(r.Error.RetryAfter); err != nil {
+			return nil, fmt.Errorf("invalid retry_after in response %d of mock script %s: %w", i+1, path, err)
+		}
+	}
+	return client, nil
+}
+
+// respond returns the next response of the role, retrying rate limits the
+// way the other clients do.
+func (m *mockClient) respond(ctx context.Context) (*ProviderResponse, *mockResponse, error) {
+	attempts := 0
+	for {
+		attempts++
+		m.cursor.mu.Lock()
+		if m.cursor.next >= len(m.responses) {
+			m.cursor.mu.Unlock()
+			return nil, nil, m.exhausted()
+		}
+		r := m.responses[m.cursor.next]
+		m.cursor.next++
+		m.cursor.mu.Unlock()
+
+		if r.Error == nil {
+			return m.response(r), &r, nil
+		}
+		if r.Error.Status != http.StatusTooManyRequests && r.Error.Status != 529 {
+			return nil, nil, r.Error
+		}
+		if attempts > maxRetries {
+			return nil, nil, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
+		}
+		slog.Warn("Retrying due to rate limit", "attempt", attempts, "max_retries", maxRetries, "error", r.Error)
+		after, _ := time.ParseDuration(r.Error.RetryAfter)
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(after):
+		}
+	}
+}
+
+func (m *mockClient) exhausted() error {
+	if m.providerOptions.role == "" {
+		return fmt.Errorf("mock script %s has no more responses", m.path)
+	}
+	return fmt.Errorf("mock script %s has no more responses for %s", m.path, m.providerOptions.role)
+}
+
+func (m *mockClient) response(r mockResponse) *ProviderResponse {
+	response := &ProviderResponse{
+		Content:      r.Content,
+		Usage:        r.Usage,
+		FinishReason: r.FinishReason,
+	}
+	for _, call := range r.ToolCalls {
+		id := call.ID
+		if id == "" {
+			id = "call_" + uuid.New().String()
+		}
+		input := "{}"
+		if len(call.Input) > 0 {
+			input = string(call.Input)
+		}
+		response.ToolCalls = append(response.ToolCalls, message.ToolCall{
+			ID:       id,
+			Name:     call.Name,
+			Input:    input,
+			Type:     "function",
+			Finished: true,
+		})
+	}
+	if response.FinishReason == "" {
+		response.FinishReason = message.FinishReasonEndTurn
+		if len(response.ToolCalls) > 0 {
+			response.FinishReason = message.FinishReasonToolUse
+		}
+	}
+	return response
+}
+
+func (m *mockClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	response, _, err := m.respond(ctx)
+	return response, err
+}
+
+func (m *mockClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	eventChan := make(chan ProviderEvent)
+	go func() {
+		defer close(eventChan)
+
+		response, r, err := m.respond(ctx)
+		if err != nil {
+			sendEvent(ctx, eventChan, ProviderEvent{Type: EventError, Error: err})
+			return
+		}
+
+		send := func(event ProviderEvent) bool {
+			return sendEvent(ctx, eventChan, event)
+		}
+		// emit sends an event after the delay of the script, and reports
+		// whether the stream should go on.
+		emit := func(event ProviderEvent) bool {
+			if m.delay > 0 {
+				select {
+				case <-ctx.Done():
+					return false
+				case <-time.After(m.delay):
+				}
+			}
+			return send(event)
+		}
+
+		for _, chunk := range mockChunks(r.Thinking) {
+			if !emit(ProviderEvent{Type: EventThinkingDelta, Thinking: chunk}) {
+				return
+			}
+		}
+		if r.Content != "" {
+			if !send(ProviderEvent{Type: EventContentStart}) {
+				return
+			}
+			for _, chunk := range mockChunks(r.Content) {
+				if !emit(ProviderEvent{Type: EventContentDelta, Content: chunk}) {
+					return
+				}
+			}
+			if !send(ProviderEvent{Type: EventContentStop}) {
+				return
+			}
+		}
+		for _, call := range response.ToolCalls {
+			if !emit(ProviderEvent{Type: EventToolUseStart, ToolCall: &message.ToolCall{ID: call.ID, Name: call.Name}}) ||
+				!send(ProviderEvent{Type: EventToolUseDelta, ToolCall: &message.ToolCall{ID: call.ID, Input: call.Input}}) ||
+				!send(ProviderEvent{Type: EventToolUseStop, ToolCall: &message.ToolCall{ID: call.ID}}) {
+				return
+			}
+		}
+		send(ProviderEvent{Type: EventComplete, Response: response})
+	}()
+	return eventChan
+}
+
+func (m *mockClient) Model() catwalk.Model {
+	return m.providerOptions.model(m.providerOptions.modelType)
+}
+
+// mockChunks splits text in words, keeping the spaces, so that it streams
+// the way real responses do.
+func mockChunks(text string) []string {
+	var chunks []string
+	for text != "" {
+		i := strings.IndexAny(text, " \n")
+		if i < 0 {
+			chunks = append(chunks, text)
+			break
+		}
+		chunks = append(chunks, text[:i+1])
+		text = text[i+1:]
+	}
+	return chunks
+}
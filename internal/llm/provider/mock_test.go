@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMockClient(t *testing.T, script string) MockClient {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mock.json")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o644))
+	return newTestMockRoleClient(t, path, "")
+}
+
+func newTestMockRoleClient(t *testing.T, path, role string) MockClient {
+	t.Helper()
+	client, err := newMockClient(providerClientOptions{
+		config: config.ProviderConfig{ID: "mock", Type: config.ProviderTypeMock, Script: path},
+		model: func(config.SelectedModelType) catwalk.Model {
+			return catwalk.Model{ID: "mock"}
+		},
+		role: role,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestMockClientStream(t *testing.T) {
+	t.Parallel()
+
+	client := newTestMockClient(t, `{
+		"responses": [{
+			"thinking": "Let me look.",
+			"content": "Reading main.go",
+			"tool_calls": [{"name": "view", "input": {"file_path": "main.go"}}],
+			"usage": {"input_tokens": 100, "output_tokens": 20}
+		}]
+	}`)
+
+	var thinking, content string
+	var calls []message.ToolCall
+	var response *ProviderResponse
+	for event := range client.stream(t.Context(), nil, nil) {
+		switch event.Type {
+		case EventThinkingDelta:
+			thinking += event.Thinking
+		case EventContentDelta:
+			content += event.Content
+		case EventToolUseStart:
+			calls = append(calls, *event.ToolCall)
+		case EventComplete:
+			response = event.Response
+		case EventError:
+			t.Fatal(event.Error)
+		}
+	}
+
+	require.Equal(t, "Let me look.", thinking)
+	require.Equal(t, "Reading main.go", content)
+	require.Len(t, calls, 1)
+	require.NotNil(t, response)
+	require.Equal(t, message.FinishReasonToolUse, response.FinishReason)
+	require.Equal(t, TokenUsage{InputTokens: 100, OutputTokens: 20}, response.Usage)
+	require.Len(t, response.ToolCalls, 1)
+	require.Equal(t, calls[0].ID, response.ToolCalls[0].ID)
+	require.Equal(t, "view", response.ToolCalls[0].Name)
+	require.JSONEq(t, `{"file_path": "main.go"}`, response.ToolCalls[0].Input)
+}
+
+func TestMockClientErrors(t *testing.T) {
+	t.Parallel()
+
+	client := newTestMockClient(t, `{
+		"responses": [
+			{"error": {"status": 429, "message": "slow down"}},
+			{"content": "Done."},
+			{"error": {"status": 500, "message": "boom"}}
+		]
+	}`)
+
+	// Rate limits are retried with the next response.
+	response, err := client.send(t.Context(), nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Done.", response.Content)
+	require.Equal(t, message.FinishReasonEndTurn, response.FinishReason)
+
+	_, err = client.send(t.Context(), nil, nil)
+	require.EqualError(t, err, "500 Internal Server Error: boom")
+
+	_, err = client.send(t.Context(), nil, nil)
+	require.ErrorContains(t, err, "has no more responses")
+}
+
+func TestMockClientRateLimitRetries(t *testing.T) {
+	t.Parallel()
+
+	client := newTestMockClient(t, `{
+		"responses": [
+			{"error": {"status": 429, "message": "slow down"}},
+			{"error": {"status": 429, "message": "slow down"}},
+			{"error": {"status": 529, "message": "overloaded"}},
+			{"error": {"status": 429, "message": "slow down"}},
+			{"content": "Too late."}
+		]
+	}`)
+
+	_, err := client.send(t.Context(), nil, nil)
+	require.ErrorContains(t, err, "maximum retry attempts reached")
+}
+
+func TestMockClientRoles(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "mock.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"responses": [{"content": "First"}, {"content": "Second"}],
+		"roles": {"title": [{"content": "A title"}]}
+	}`), 0o644))
+
+	next := func(client MockClient) string {
+		t.Helper()
+		response, err := client.send(t.Context(), nil, nil)
+		require.NoError(t, err)
+		return response.Content
+	}
+
+	coder := newTestMockRoleClient(t, path, "coder")
+	require.Equal(t, "First", next(coder))
+
+	// The clients of a role go on where the previous ones stopped.
+	coder = newTestMockRoleClient(t, path, "coder")
+	require.Equal(t, "Second", next(coder))
+
+	// Roles answer from their own responses, or from the start of the script.
+	require.Equal(t, "A title", next(newTestMockRoleClient(t, path, "title")))
+	require.Equal(t, "First", next(newTestMockRoleClient(t, path, "task")))
+
+	_, err := newTestMockRoleClient(t, path, "title").send(t.Context(), nil, nil)
+	require.ErrorContains(t, err, "has no more responses for title")
+}
+
+func TestMockClientStreamCanceled(t *testing.T) {
+	t.Parallel()
+
+	client := newTestMockClient(t, `{
+		"delay": "10ms",
+		"responses": [{"content": "Reading main.go and then some more"}]
+	}`)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	events := client.stream(ctx, nil, nil)
+	require.Equal(t, EventContentStart, (<-events).Type)
+
+	// Nobody reads the rest of a canceled stream, which ends without
+	// sending anything more.
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	_, ok := <-events
+	require.False(t, ok)
+}
@@ -30,17 +30,17 @@ const (
 )
 
 type TokenUsage struct {
-	InputTokens         int64
-	OutputTokens        int64
-	CacheCreationTokens int64
-	CacheReadTokens     int64
+	InputTokens         int64 `json:"input_tokens"`
+	OutputTokens        int64 `json:"output_tokens"`
+	CacheCreationTokens int64 `json:"cache_creation_tokens"`
+	CacheReadTokens     int64 `json:"cache_read_tokens"`
 }
 
 type ProviderResponse struct {
-	Content      string
-	ToolCalls    []message.ToolCall
-	Usage        TokenUsage
-	FinishReason message.FinishReason
+	Content      string               `json:"content,omitempty"`
+	ToolCalls    []message.ToolCall   `json:"tool_calls,omitempty"`
+	Usage        TokenUsage           `json:"usage"`
+	FinishReason message.FinishReason `json:"finish_reason"`
 }
 
 type ProviderEvent struct {
@@ -66,6 +66,7 @@ type providerClientOptions struct {
 	config             config.ProviderConfig
 	apiKey             string
 	modelType          config.SelectedModelType
+	role               string
 	model              func(config.SelectedModelType) catwalk.Model
 	disableCache       bool
 	systemMessage      string
@@ -121,6 +122,15 @@ func WithModel(model config.SelectedModelType) ProviderClientOption {
 	}
 }
 
+// WithRole sets what the provider is used for: the ID of the agent it runs,
+// "title" or "summarize". Mock providers answer each role from its own
+// responses.
+func WithRole(role string) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.role = role
+	}
+}
+
 func WithDisableCache(disableCache bool) ProviderClientOption {
 	return func(options *providerClientOptions) {
 		options.disableCache = disableCache
@@ -172,6 +182,27 @@ func NewProvider(cfg config.ProviderConfig, opts ...ProviderClientOption) (Provi
 	for _, o := range opts {
 		o(&clientOptions)
 	}
+
+	cassette, err := loadCassette(config.Get().Options.Cassette)
+	if err != nil {
+		return nil, err
+	}
+	// Replaying doesn't need a client, so that tests don't need credentials.
+	if cassette != nil && cassette.mode == config.CassetteModeReplay {
+		return newCassetteProvider(cfg.ID, clientOptions, nil, cassette), nil
+	}
+
+	provider, err := newProvider(cfg, clientOptions)
+	if err != nil {
+		return nil, err
+	}
+	if cassette != nil {
+		return newCassetteProvider(cfg.ID, clientOptions, provider, cassette), nil
+	}
+	return provider, nil
+}
+
+func newProvider(cfg config.ProviderConfig, clientOptions providerClientOptions) (Provider, error) {
 	switch cfg.Type {
 	case catwalk.TypeAnthropic:
 		return &baseProvider[AnthropicClient]{
@@ -203,6 +234,15 @@ func NewProvider(cfg config.ProviderConfig, opts ...ProviderClientOption) (Provi
 			options: clientOptions,
 			client:  newVertexAIClient(clientOptions),
 		}, nil
+	case config.ProviderTypeMock:
+		client, err := newMockClient(clientOptions)
+		if err != nil {
+			return nil, err
+		}
+		return &baseProvider[MockClient]{
+			options: clientOptions,
+			client:  client,
+		}, nil
 	}
 	return nil, fmt.Errorf("provider not supported: %s", cfg.Type)
 }
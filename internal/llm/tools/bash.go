@@ -295,11 +295,10 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for executing shell command")
 	}
 	if !isSafeReadOnly {
-		shell := shell.GetPersistentShell(b.workingDir)
 		p := b.permissions.Request(
 			permission.CreatePermissionRequest{
 				SessionID:   sessionID,
-				Path:        shell.GetWorkingDir(),
+				Path:        commandShell(ctx, b.workingDir).GetWorkingDir(),
 				ToolCallID:  call.ID,
 				ToolName:    BashToolName,
 				Action:      "execute",
@@ -320,11 +319,11 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		defer cancel()
 	}
 
-	persistentShell := shell.GetPersistentShell(b.workingDir)
-	stdout, stderr, err := persistentShell.Exec(ctx, params.Command)
+	sh := commandShell(ctx, b.workingDir)
+	stdout, stderr, err := sh.Exec(ctx, params.Command)
 
 	// Get the current working directory after command execution
-	currentWorkingDir := persistentShell.GetWorkingDir()
+	currentWorkingDir := sh.GetWorkingDir()
 	interrupted := shell.IsInterrupt(err)
 	exitCode := shell.ExitCode(err)
 	if exitCode == 0 && !interrupted && err != nil {
@@ -102,7 +102,7 @@ func (t *downloadTool) Run(ctx context.Context, call ToolCall) (ToolResponse, er
 	if filepath.IsAbs(params.FilePath) {
 		filePath = params.FilePath
 	} else {
-		filePath = filepath.Join(t.workingDir, params.FilePath)
+		filePath = filepath.Join(workingDir(ctx, t.workingDir), params.FilePath)
 	}
 
 	sessionID, messageID := GetContextValues(ctx)
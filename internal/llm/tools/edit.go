@@ -102,7 +102,7 @@ func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	}
 
 	if !filepath.IsAbs(params.FilePath) {
-		params.FilePath = filepath.Join(e.workingDir, params.FilePath)
+		params.FilePath = filepath.Join(workingDir(ctx, e.workingDir), params.FilePath)
 	}
 
 	var response ToolResponse
@@ -191,7 +191,7 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string,
 	}
 
 	// File can't be in the history so we create a new file history
-	_, err = e.files.Create(ctx, sessionID, filePath, "")
+	_, err = e.files.CreateNew(ctx, sessionID, filePath)
 	if err != nil {
 		// Log error but don't fail the operation
 		return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
@@ -74,7 +74,7 @@ func (g *globTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 
 	searchPath := params.Path
 	if searchPath == "" {
-		searchPath = g.workingDir
+		searchPath = workingDir(ctx, g.workingDir)
 	}
 
 	files, truncated, err := globFiles(ctx, params.Pattern, searchPath, 100)
@@ -164,7 +164,7 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 
 	searchPath := params.Path
 	if searchPath == "" {
-		searchPath = g.workingDir
+		searchPath = workingDir(ctx, g.workingDir)
 	}
 
 	matches, truncated, err := searchFiles(ctx, searchPattern, searchPath, params.Include, 100)
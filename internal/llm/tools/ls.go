@@ -88,7 +88,7 @@ func (l *lsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 
 	searchPath := params.Path
 	if searchPath == "" {
-		searchPath = l.workingDir
+		searchPath = workingDir(ctx, l.workingDir)
 	}
 
 	var err error
@@ -98,7 +98,7 @@ func (l *lsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	}
 
 	if !filepath.IsAbs(searchPath) {
-		searchPath = filepath.Join(l.workingDir, searchPath)
+		searchPath = filepath.Join(workingDir(ctx, l.workingDir), searchPath)
 	}
 
 	// Check if directory is outside working directory and request permission if needed
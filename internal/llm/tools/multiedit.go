@@ -123,7 +123,7 @@ func (m *multiEditTool) Run(ctx context.Context, call ToolCall) (ToolResponse, e
 	}
 
 	if !filepath.IsAbs(params.FilePath) {
-		params.FilePath = filepath.Join(m.workingDir, params.FilePath)
+		params.FilePath = filepath.Join(workingDir(ctx, m.workingDir), params.FilePath)
 	}
 
 	// Validate all edits before applying any
@@ -238,7 +238,7 @@ func (m *multiEditTool) processMultiEditWithCreation(ctx context.Context, params
 	}
 
 	// Update file history
-	_, err = m.files.Create(ctx, sessionID, params.FilePath, "")
+	_, err = m.files.CreateNew(ctx, sessionID, params.FilePath)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
 	}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/todo"
+)
+
+//go:embed todos.md
+var todosDescription []byte
+
+type TodosParams struct {
+	Replace bool        `json:"replace,omitempty"`
+	Todos   []TodoParam `json:"todos"`
+}
+
+type TodoParam struct {
+	ID      int64  `json:"id,omitempty"`
+	Content string `json:"content,omitempty"`
+	Status  string `json:"status,omitempty"`
+}
+
+type TodosResponseMetadata struct {
+	Open      int `json:"open"`
+	Completed int `json:"completed"`
+}
+
+type todosTool struct {
+	todos todo.Service
+}
+
+const TodosToolName = "todos"
+
+func NewTodosTool(todos todo.Service) BaseTool {
+	return &todosTool{
+		todos: todos,
+	}
+}
+
+func (t *todosTool) Name() string {
+	return TodosToolName
+}
+
+func (t *todosTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        TodosToolName,
+		Description: string(todosDescription),
+		Parameters: map[string]any{
+			"replace": map[string]any{
+				"type":        "boolean",
+				"description": "Replace the whole list with the given items instead of updating it",
+			},
+			"todos": map[string]any{
+				"type":        "array",
+				"description": "The items to add or update",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"id": map[string]any{
+							"type":        "integer",
+							"description": "The number of the item to update; leave it out to add a new item",
+						},
+						"content": map[string]any{
+							"type":        "string",
+							"description": "What needs to be done",
+						},
+						"status": map[string]any{
+							"type":        "string",
+							"description": "The status of the item (defaults to pending for new items)",
+							"enum":        []string{string(todo.StatusPending), string(todo.StatusInProgress), string(todo.StatusCompleted)},
+						},
+					},
+				},
+			},
+		},
+		Required: []string{"todos"},
+	}
+}
+
+func (t *todosTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params TodosParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required for managing todos")
+	}
+
+	existing, err := t.todos.List(ctx, sessionID)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error listing todos: %w", err)
+	}
+	if params.Replace {
+		existing = nil
+	}
+
+	// Check every item before changing anything, so a bad item doesn't leave
+	// the list half updated.
+	for _, item := range params.Todos {
+		if item.Status != "" && !todo.Status(item.Status).Valid() {
+			return NewTextErrorResponse(fmt.Sprintf("invalid status %q, must be pending, in_progress or completed", item.Status)), nil
+		}
+		if item.ID == 0 || params.Replace {
+			if strings.TrimSpace(item.Content) == "" {
+				return NewTextErrorResponse("content is required for new items"), nil
+			}
+			continue
+		}
+		if item.ID < 1 || item.ID > int64(len(existing)) {
+			return NewTextErrorResponse(fmt.Sprintf("no item with id %d, the list has %d items", item.ID, len(existing))), nil
+		}
+	}
+
+	if params.Replace {
+		if err := t.todos.DeleteSessionTodos(ctx, sessionID); err != nil {
+			return ToolResponse{}, fmt.Errorf("error clearing todos: %w", err)
+		}
+	}
+	for _, item := range params.Todos {
+		if item.ID == 0 || params.Replace {
+			status := todo.Status(item.Status)
+			if status == "" {
+				status = todo.StatusPending
+			}
+			if _, err := t.todos.Create(ctx, sessionID, strings.TrimSpace(item.Content), status); err != nil {
+				return ToolResponse{}, fmt.Errorf("error creating todo: %w", err)
+			}
+			continue
+		}
+		current := existing[item.ID-1]
+		if content := strings.TrimSpace(item.Content); content != "" {
+			current.Content = content
+		}
+		if item.Status != "" {
+			current.Status = todo.Status(item.Status)
+		}
+		updated, err := t.todos.Update(ctx, current)
+		if err != nil {
+			return ToolResponse{}, fmt.Errorf("error updating todo: %w", err)
+		}
+		existing[item.ID-1] = updated
+	}
+
+	todos, err := t.todos.List(ctx, sessionID)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error listing todos: %w", err)
+	}
+	if len(todos) == 0 {
+		return NewTextResponse("The todo list is empty."), nil
+	}
+	open := len(todo.Open(todos))
+	return WithResponseMetadata(
+		NewTextResponse(fmt.Sprintf("Todo list (%d open):\n%s", open, todo.Format(todos))),
+		TodosResponseMetadata{
+			Open:      open,
+			Completed: len(todos) - open,
+		},
+	), nil
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/todo"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTodoService struct {
+	*pubsub.Broker[todo.Todo]
+	todos []todo.Todo
+}
+
+func (s *fakeTodoService) Create(_ context.Context, sessionID, content string, status todo.Status) (todo.Todo, error) {
+	item := todo.Todo{
+		ID:        content,
+		SessionID: sessionID,
+		Content:   content,
+		Status:    status,
+		Position:  int64(len(s.todos)) + 1,
+	}
+	s.todos = append(s.todos, item)
+	return item, nil
+}
+
+func (s *fakeTodoService) Update(_ context.Context, item todo.Todo) (todo.Todo, error) {
+	s.todos[item.Position-1] = item
+	return item, nil
+}
+
+func (s *fakeTodoService) List(context.Context, string) ([]todo.Todo, error) {
+	return slices.Clone(s.todos), nil
+}
+
+func (s *fakeTodoService) DeleteSessionTodos(context.Context, string) error {
+	s.todos = nil
+	return nil
+}
+
+func runTodos(t *testing.T, tool BaseTool, input string) ToolResponse {
+	t.Helper()
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session")
+	resp, err := tool.Run(ctx, ToolCall{ID: "call", Name: TodosToolName, Input: input})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestTodosTool(t *testing.T) {
+	t.Parallel()
+
+	service := &fakeTodoService{Broker: pubsub.NewBroker[todo.Todo]()}
+	tool := NewTodosTool(service)
+
+	resp := runTodos(t, tool, `{"todos": [{"content": "Write the parser"}, {"content": "Add tests", "status": "in_progress"}]}`)
+	require.False(t, resp.IsError)
+	require.Equal(t, "Todo list (2 open):\n- [ ] 1. Write the parser\n- [~] 2. Add tests", resp.Content)
+
+	resp = runTodos(t, tool, `{"todos": [{"id": 1, "status": "completed"}, {"content": "Update the docs"}]}`)
+	require.False(t, resp.IsError)
+	require.Equal(t, "Todo list (2 open):\n- [x] 1. Write the parser\n- [~] 2. Add tests\n- [ ] 3. Update the docs", resp.Content)
+
+	// A bad item leaves the list alone.
+	resp = runTodos(t, tool, `{"todos": [{"id": 2, "status": "completed"}, {"id": 4, "status": "completed"}]}`)
+	require.True(t, resp.IsError)
+	require.Equal(t, "no item with id 4, the list has 3 items", resp.Content)
+	resp = runTodos(t, tool, `{"todos": [{"id": 2, "status": "done"}]}`)
+	require.True(t, resp.IsError)
+	require.Equal(t, todo.StatusInProgress, service.todos[1].Status)
+
+	resp = runTodos(t, tool, `{"replace": true, "todos": [{"content": "Something else"}]}`)
+	require.False(t, resp.IsError)
+	require.Equal(t, "Todo list (1 open):\n- [ ] 1. Something else", resp.Content)
+
+	resp = runTodos(t, tool, `{"replace": true, "todos": []}`)
+	require.Equal(t, "The todo list is empty.", resp.Content)
+}
@@ -98,7 +98,7 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	// Handle relative paths
 	filePath := params.FilePath
 	if !filepath.IsAbs(filePath) {
-		filePath = filepath.Join(v.workingDir, filePath)
+		filePath = filepath.Join(workingDir(ctx, v.workingDir), filePath)
 	}
 
 	// Check if file is outside working directory and request permission if needed
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+type workDirContextKey struct{}
+
+// workDir is the directory the tool calls of a run work in, with the shell
+// their commands run in.
+type workDir struct {
+	dir   string
+	shell *shell.Shell
+}
+
+// WithWorkingDir returns a context whose tool calls resolve relative paths
+// against dir, and run commands in a shell of their own started there,
+// instead of the working directory and the persistent shell shared by every
+// session.
+func WithWorkingDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, workDirContextKey{}, &workDir{
+		dir: dir,
+		shell: shell.NewShell(&shell.Options{
+			WorkingDir: dir,
+			BlockFuncs: blockFuncs(),
+		}),
+	})
+}
+
+// workingDir returns the directory the tool calls made with ctx work in: the
+// one set with WithWorkingDir, or dir.
+func workingDir(ctx context.Context, dir string) string {
+	if wd, ok := ctx.Value(workDirContextKey{}).(*workDir); ok {
+		return wd.dir
+	}
+	return dir
+}
+
+// commandShell returns the shell the commands of ctx run in: the one set with
+// WithWorkingDir, or the persistent shell.
+func commandShell(ctx context.Context, dir string) *shell.Shell {
+	if wd, ok := ctx.Value(workDirContextKey{}).(*workDir); ok {
+		return wd.shell
+	}
+	return shell.GetPersistentShell(dir).Shell
+}
@@ -0,0 +1,153 @@
+package mcpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// pathParams are the tool parameters holding paths that are checked against
+// .crushignore.
+var pathParams = []string{"file_path", "path"}
+
+// isIgnored reports whether path, relative to the working directory unless
+// absolute, is hidden by .crushignore.
+func (s *Server) isIgnored(path string) bool {
+	if path == "" {
+		return false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.workingDir, path)
+	}
+	return fsext.IsCrushIgnored(s.workingDir, path)
+}
+
+// ignoredPath returns the first path in the input of a call to the named tool
+// that is hidden by .crushignore: a path parameter, or a literal word of a
+// bash command.
+func (s *Server) ignoredPath(toolName string, input []byte) (string, bool) {
+	var params map[string]any
+	if err := json.Unmarshal(input, &params); err != nil {
+		return "", false
+	}
+	for _, key := range pathParams {
+		if path, ok := params[key].(string); ok && s.isIgnored(path) {
+			return path, true
+		}
+	}
+	if toolName == tools.BashToolName {
+		command, _ := params["command"].(string)
+		return s.ignoredCommandPath(command)
+	}
+	return "", false
+}
+
+// ignoredCommandPath returns the first word of command naming a path hidden
+// by .crushignore, including the values of "--flag=path" arguments. Only
+// literal words are checked: paths built by variables, globs or scripts still
+// get through, so the bash tool should be denied when that matters.
+func (s *Server) ignoredCommandPath(command string) (string, bool) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		// The shell can't run it either.
+		return "", false
+	}
+	var found string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		word, ok := node.(*syntax.Word)
+		if !ok || found != "" {
+			return found == ""
+		}
+		lit, err := expand.Literal(nil, word)
+		if err != nil {
+			return true
+		}
+		for _, path := range []string{lit, lit[strings.LastIndex(lit, "=")+1:]} {
+			if s.isIgnored(path) {
+				found = path
+				break
+			}
+		}
+		return true
+	})
+	return found, found != ""
+}
+
+// filterResults removes the files hidden by .crushignore from the output of
+// the glob and grep tools. The tools only read the .crushignore of the
+// directory they search, while nested and global ignore files apply too.
+func (s *Server) filterResults(toolName, content string) string {
+	switch toolName {
+	case tools.GlobToolName:
+		return s.filterGlob(content)
+	case tools.GrepToolName:
+		return s.filterGrep(content)
+	}
+	return content
+}
+
+// filterGlob filters glob output: one path per line, possibly followed by a
+// blank line and a note about truncated results.
+func (s *Server) filterGlob(content string) string {
+	var kept []string
+	files := 0
+	for line := range strings.SplitSeq(content, "\n") {
+		if line != "" && !strings.HasPrefix(line, "(") && line != "No files found" {
+			if s.isIgnored(line) {
+				continue
+			}
+			files++
+		}
+		kept = append(kept, line)
+	}
+	if files == 0 {
+		return "No files found"
+	}
+	return strings.Join(kept, "\n")
+}
+
+// filterGrep filters grep output: a "Found N matches" header, then for every
+// file its path followed by a colon and the indented matching lines, with a
+// blank line between files.
+func (s *Server) filterGrep(content string) string {
+	lines := strings.Split(content, "\n")
+	if !strings.HasPrefix(lines[0], "Found ") {
+		return content
+	}
+	var kept []string
+	matches := 0
+	skipping := false
+	for _, line := range lines[1:] {
+		switch {
+		case strings.HasPrefix(line, "  "):
+			if skipping {
+				continue
+			}
+			matches++
+		case strings.HasSuffix(line, ":"):
+			skipping = s.isIgnored(strings.TrimSuffix(line, ":"))
+			if skipping {
+				if n := len(kept); n > 0 && kept[n-1] == "" {
+					kept = kept[:n-1]
+				}
+				continue
+			}
+		default:
+			skipping = false
+			if line == "" && len(kept) == 0 {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	if matches == 0 {
+		return "No files found"
+	}
+	return fmt.Sprintf("Found %d matches\n", matches) + strings.Join(kept, "\n")
+}
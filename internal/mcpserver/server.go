@@ -0,0 +1,139 @@
+// Package mcpserver exposes Crush's built-in tools over the Model Context
+// Protocol, so other MCP hosts can use them.
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/version"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Tools returns the built-in tools exposed over MCP.
+func Tools(cfg *config.Config, permissions permission.Service, files history.Service, lspClients *csync.Map[string, *lsp.Client]) []tools.BaseTool {
+	cwd := cfg.WorkingDir()
+	allTools := []tools.BaseTool{
+		tools.NewViewTool(lspClients, permissions, cwd),
+		tools.NewEditTool(lspClients, permissions, files, cwd),
+		tools.NewMultiEditTool(lspClients, permissions, files, cwd),
+		tools.NewGrepTool(cwd),
+		tools.NewGlobTool(cwd),
+		tools.NewLsTool(permissions, cwd),
+		tools.NewBashTool(permissions, cwd, cfg.Options.Attribution),
+	}
+	if len(cfg.LSP) > 0 {
+		allTools = append(allTools, tools.NewDiagnosticsTool(lspClients))
+	}
+	return allTools
+}
+
+// Server serves tools over MCP. Every tool call runs in the given session,
+// so permission requests and file history are tracked like in a regular
+// Crush session.
+type Server struct {
+	mcp         *server.MCPServer
+	sessionID   string
+	workingDir  string
+	permissions permission.Service
+}
+
+// New creates an MCP server exposing the given tools.
+func New(sessionID, workingDir string, permissions permission.Service, serverTools []tools.BaseTool) *Server {
+	s := &Server{
+		mcp: server.NewMCPServer(
+			"crush",
+			version.Version,
+			server.WithToolCapabilities(false),
+			server.WithRecovery(),
+		),
+		sessionID:   sessionID,
+		workingDir:  workingDir,
+		permissions: permissions,
+	}
+	for _, tool := range serverTools {
+		s.mcp.AddTool(toMCPTool(tool.Info()), s.handler(tool))
+	}
+	return s
+}
+
+// Serve speaks MCP over in and out until ctx is done or in is closed.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	stdio := server.NewStdioServer(s.mcp)
+	stdio.SetErrorLogger(slog.NewLogLogger(slog.Default().Handler(), slog.LevelError))
+	return stdio.Listen(ctx, in, out)
+}
+
+func (s *Server) handler(tool tools.BaseTool) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := tool.Name()
+		if policy, ok := s.permissions.SessionPolicy(s.sessionID); ok {
+			if err := policy.CheckTool(name); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
+
+		args := req.GetRawArguments()
+		if args == nil {
+			args = map[string]any{}
+		}
+		input, err := json.Marshal(args)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("error parsing parameters: %s", err)), nil
+		}
+		if path, ok := s.ignoredPath(name, input); ok {
+			return mcp.NewToolResultError(fmt.Sprintf("access to %s is blocked by .crushignore", path)), nil
+		}
+
+		callID := uuid.New().String()
+		slog.Info("MCP tool call", "tool", name, "call_id", callID, "session_id", s.sessionID)
+
+		ctx = context.WithValue(ctx, tools.SessionIDContextKey, s.sessionID)
+		ctx = context.WithValue(ctx, tools.MessageIDContextKey, callID)
+		resp, err := tool.Run(ctx, tools.ToolCall{
+			ID:    callID,
+			Name:  name,
+			Input: string(input),
+		})
+		if errors.Is(err, permission.ErrorPermissionDenied) {
+			return mcp.NewToolResultError("permission denied"), nil
+		}
+		if err != nil {
+			slog.Error("MCP tool call failed", "tool", name, "call_id", callID, "error", err)
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if resp.IsError {
+			return mcp.NewToolResultError(resp.Content), nil
+		}
+		return mcp.NewToolResultText(s.filterResults(name, resp.Content)), nil
+	}
+}
+
+func toMCPTool(info tools.ToolInfo) mcp.Tool {
+	required := info.Required
+	if required == nil {
+		required = []string{}
+	}
+	properties := info.Parameters
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	schema, _ := json.Marshal(map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	})
+	return mcp.NewToolWithRawSchema(info.Name, info.Description, schema)
+}
@@ -0,0 +1,189 @@
+package mcpserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTool returns a fixed output and records the calls it gets.
+type fakeTool struct {
+	name   string
+	output string
+	calls  []tools.ToolCall
+}
+
+func (t *fakeTool) Info() tools.ToolInfo { return tools.ToolInfo{Name: t.name} }
+
+func (t *fakeTool) Name() string { return t.name }
+
+func (t *fakeTool) Run(_ context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	t.calls = append(t.calls, call)
+	return tools.NewTextResponse(t.output), nil
+}
+
+// newTestServer creates a server for a project hiding secret.env and the
+// keys directory of the nested app directory.
+func newTestServer(t *testing.T, policy permission.Policy) *Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".crushignore"), []byte("secret.env\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app", ".crushignore"), []byte("keys/\n"), 0o644))
+
+	permissions := permission.NewPermissionService(dir, false, nil)
+	permissions.SetSessionPolicy("session", policy)
+	return New("session", dir, permissions, nil)
+}
+
+func callTool(t *testing.T, s *Server, tool tools.BaseTool, args map[string]any) (string, bool) {
+	t.Helper()
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = tool.Name()
+	req.Params.Arguments = args
+	res, err := s.handler(tool)(t.Context(), req)
+	require.NoError(t, err)
+	require.Len(t, res.Content, 1)
+	text, ok := res.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	return text.Text, res.IsError
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t, permission.Policy{OnlyTools: []string{"view", "bash"}})
+
+	for _, tt := range []struct {
+		name    string
+		tool    string
+		args    map[string]any
+		want    string
+		isError bool
+	}{
+		{
+			name:    "denied tool",
+			tool:    "edit",
+			args:    map[string]any{"file_path": "main.go"},
+			want:    `permission denied: tool "edit" is not available in this run, use one of: view, bash`,
+			isError: true,
+		},
+		{
+			name: "view",
+			tool: "view",
+			args: map[string]any{"file_path": "main.go"},
+			want: "ok",
+		},
+		{
+			name:    "view ignored file",
+			tool:    "view",
+			args:    map[string]any{"file_path": "secret.env"},
+			want:    "access to secret.env is blocked by .crushignore",
+			isError: true,
+		},
+		{
+			name:    "view file ignored by nested ignore file",
+			tool:    "view",
+			args:    map[string]any{"file_path": "app/keys/id_ed25519"},
+			want:    "access to app/keys/id_ed25519 is blocked by .crushignore",
+			isError: true,
+		},
+		{
+			name: "bash",
+			tool: "bash",
+			args: map[string]any{"command": "go test ./... && cat README.md"},
+			want: "ok",
+		},
+		{
+			name:    "bash reading ignored file",
+			tool:    "bash",
+			args:    map[string]any{"command": `go test ./... && cat "secret.env"`},
+			want:    "access to secret.env is blocked by .crushignore",
+			isError: true,
+		},
+		{
+			name:    "bash flag naming ignored file",
+			tool:    "bash",
+			args:    map[string]any{"command": "openssl pkey --in=app/keys/id_ed25519"},
+			want:    "access to app/keys/id_ed25519 is blocked by .crushignore",
+			isError: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tool := &fakeTool{name: tt.tool, output: "ok"}
+			got, isError := callTool(t, s, tool, tt.args)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.isError, isError)
+			if tt.isError {
+				require.Empty(t, tool.calls)
+			}
+		})
+	}
+}
+
+func TestFilterResults(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t, permission.Policy{})
+	abs := func(path string) string { return filepath.Join(s.workingDir, path) }
+
+	for _, tt := range []struct {
+		name   string
+		tool   string
+		output string
+		want   string
+	}{
+		{
+			name: "glob",
+			tool: tools.GlobToolName,
+			output: abs("main.go") + "\n" + abs("secret.env") + "\n" + abs("app/keys/id_ed25519") + "\n" + abs("app/main.go") +
+				"\n\n(Results are truncated. Consider using a more specific path or pattern.)",
+			want: abs("main.go") + "\n" + abs("app/main.go") +
+				"\n\n(Results are truncated. Consider using a more specific path or pattern.)",
+		},
+		{
+			name:   "glob of ignored files only",
+			tool:   tools.GlobToolName,
+			output: abs("secret.env"),
+			want:   "No files found",
+		},
+		{
+			name: "grep",
+			tool: tools.GrepToolName,
+			output: "Found 4 matches\n" +
+				abs("secret.env") + ":\n  Line 1: API_KEY=hunter2\n\n" +
+				abs("main.go") + ":\n  Line 3: key := os.Getenv(\"API_KEY\")\n  Line 9: use(key)\n\n" +
+				"app/keys/id_ed25519:\n  Line 1: key\n",
+			want: "Found 2 matches\n" +
+				abs("main.go") + ":\n  Line 3: key := os.Getenv(\"API_KEY\")\n  Line 9: use(key)\n",
+		},
+		{
+			name:   "grep of ignored files only",
+			tool:   tools.GrepToolName,
+			output: "Found 1 matches\n" + abs("secret.env") + ":\n  Line 1: API_KEY=hunter2\n",
+			want:   "No files found",
+		},
+		{
+			name:   "other tool",
+			tool:   tools.ViewToolName,
+			output: abs("secret.env"),
+			want:   abs("secret.env"),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, isError := callTool(t, s, &fakeTool{name: tt.tool, output: tt.output}, map[string]any{"pattern": "*"})
+			require.False(t, isError)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalParts(t *testing.T) {
+	t.Parallel()
+
+	parts := []ContentPart{
+		ReasoningContent{Thinking: "Looking at the image", Signature: "sig", StartedAt: 1, FinishedAt: 2},
+		TextContent{Text: "What is in this image?"},
+		ImageURLContent{URL: "https://example.com/cat.png", Detail: "high"},
+		BinaryContent{Path: "cat.png", MIMEType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}},
+		ToolCall{ID: "call", Name: "view", Input: `{"file_path":"cat.png"}`, Type: "function", Finished: true},
+		ToolResult{ToolCallID: "call", Name: "view", Content: "A cat", Metadata: "{}", IsError: false},
+		Finish{Reason: FinishReasonEndTurn, Time: 3, Message: "done"},
+	}
+
+	data, err := MarshalParts(parts)
+	require.NoError(t, err)
+	got, err := UnmarshalParts(data)
+	require.NoError(t, err)
+	require.Equal(t, parts, got)
+
+	_, err = MarshalParts([]ContentPart{nil})
+	require.EqualError(t, err, "unknown part type: <nil>")
+}
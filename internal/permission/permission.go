@@ -3,6 +3,7 @@ package permission
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
@@ -49,6 +50,8 @@ type Service interface {
 	Deny(permission PermissionRequest)
 	Request(opts CreatePermissionRequest) bool
 	AutoApproveSession(sessionID string)
+	SetSessionPolicy(sessionID string, policy Policy)
+	SessionPolicy(sessionID string) (Policy, bool)
 	SetSkipRequests(skip bool)
 	SkipRequests() bool
 	SubscribeNotifications(ctx context.Context) <-chan pubsub.Event[PermissionNotification]
@@ -64,6 +67,7 @@ type permissionService struct {
 	pendingRequests       *csync.Map[string, chan bool]
 	autoApproveSessions   map[string]bool
 	autoApproveSessionsMu sync.RWMutex
+	sessionPolicies       *csync.Map[string, Policy]
 	skip                  bool
 	allowedTools          []string
 
@@ -127,6 +131,15 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 		return true
 	}
 
+	// Sessions with a policy are decided without asking the user.
+	if policy, ok := s.sessionPolicies.Get(opts.SessionID); ok {
+		if err := policy.Evaluate(s.workingDir, opts); err != nil {
+			slog.Info("Permission denied by policy", "session_id", opts.SessionID, "tool", opts.ToolName, "reason", err)
+			return false
+		}
+		return true
+	}
+
 	// tell the UI that a permission was requested
 	s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
 		ToolCallID: opts.ToolCallID,
@@ -208,6 +221,14 @@ func (s *permissionService) AutoApproveSession(sessionID string) {
 	s.autoApproveSessionsMu.Unlock()
 }
 
+func (s *permissionService) SetSessionPolicy(sessionID string, policy Policy) {
+	s.sessionPolicies.Set(sessionID, policy)
+}
+
+func (s *permissionService) SessionPolicy(sessionID string) (Policy, bool) {
+	return s.sessionPolicies.Get(sessionID)
+}
+
 func (s *permissionService) SubscribeNotifications(ctx context.Context) <-chan pubsub.Event[PermissionNotification] {
 	return s.notificationBroker.Subscribe(ctx)
 }
@@ -227,6 +248,7 @@ func NewPermissionService(workingDir string, skip bool, allowedTools []string) S
 		workingDir:          workingDir,
 		sessionPermissions:  make([]PermissionRequest, 0),
 		autoApproveSessions: make(map[string]bool),
+		sessionPolicies:     csync.NewMap[string, Policy](),
 		skip:                skip,
 		allowedTools:        allowedTools,
 		pendingRequests:     csync.NewMap[string, chan bool](),
@@ -0,0 +1,91 @@
+package permission
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Policy decides permission requests without asking the user. It is used by
+// non-interactive runs, where there is nobody around to answer a prompt.
+//
+// Tool entries are either a tool name ("bash") or a tool and action pair
+// ("bash:execute").
+type Policy struct {
+	// AllowedTools lists the tools that are approved automatically. When
+	// empty, every request that isn't denied otherwise is approved.
+	AllowedTools []string
+	// DeniedTools lists the tools that are always denied. It takes
+	// precedence over every other setting.
+	DeniedTools []string
+	// OnlyTools, when not empty, restricts the run to these tools. Other
+	// tools are denied even if they don't need permission to run.
+	OnlyTools []string
+	// AllowedPaths lists the directories outside of the working directory
+	// tools may access. Requests for any other path outside of the working
+	// directory are denied.
+	AllowedPaths []string
+}
+
+// CheckTool reports whether the tool may run at all under the policy. The
+// returned error wraps ErrorPermissionDenied.
+func (p Policy) CheckTool(toolName string) error {
+	if slices.Contains(p.DeniedTools, toolName) {
+		return fmt.Errorf("%w: tool %q is denied by policy", ErrorPermissionDenied, toolName)
+	}
+	if len(p.OnlyTools) > 0 && !slices.Contains(p.OnlyTools, toolName) {
+		return fmt.Errorf("%w: tool %q is not available in this run, use one of: %s", ErrorPermissionDenied, toolName, strings.Join(p.OnlyTools, ", "))
+	}
+	return nil
+}
+
+// Evaluate decides a permission request. It returns nil if the request is
+// allowed, or an error wrapping ErrorPermissionDenied explaining why it
+// isn't.
+func (p Policy) Evaluate(workingDir string, req CreatePermissionRequest) error {
+	if err := p.CheckTool(req.ToolName); err != nil {
+		return err
+	}
+	commandKey := req.ToolName + ":" + req.Action
+	if slices.Contains(p.DeniedTools, commandKey) {
+		return fmt.Errorf("%w: %q is denied by policy", ErrorPermissionDenied, commandKey)
+	}
+
+	if path := req.Path; path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		allowed := isWithin(workingDir, path) || slices.ContainsFunc(p.AllowedPaths, func(dir string) bool {
+			return isWithin(dir, path)
+		})
+		if !allowed {
+			return fmt.Errorf("%w: path %q is outside of the working directory and not in the allowed paths", ErrorPermissionDenied, req.Path)
+		}
+	}
+
+	if len(p.AllowedTools) > 0 && !slices.Contains(p.AllowedTools, commandKey) && !slices.Contains(p.AllowedTools, req.ToolName) {
+		return fmt.Errorf("%w: %q is not in the allowed tools", ErrorPermissionDenied, commandKey)
+	}
+	return nil
+}
+
+// isWithin reports whether path is dir or one of its descendants.
+func isWithin(dir, path string) bool {
+	if dir == "" {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
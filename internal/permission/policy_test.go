@@ -0,0 +1,152 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Evaluate(t *testing.T) {
+	t.Parallel()
+
+	const workingDir = "/work/project"
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		request CreatePermissionRequest
+		allowed bool
+	}{
+		{
+			name:    "empty policy allows everything in the working directory",
+			policy:  Policy{},
+			request: CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: workingDir},
+			allowed: true,
+		},
+		{
+			name:    "empty policy denies paths outside of the working directory",
+			policy:  Policy{},
+			request: CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: "/etc"},
+			allowed: false,
+		},
+		{
+			name:    "denied tool",
+			policy:  Policy{DeniedTools: []string{"bash"}},
+			request: CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: workingDir},
+			allowed: false,
+		},
+		{
+			name:    "denied tool action",
+			policy:  Policy{DeniedTools: []string{"edit:write"}},
+			request: CreatePermissionRequest{ToolName: "edit", Action: "write", Path: workingDir},
+			allowed: false,
+		},
+		{
+			name:    "deny wins over allow",
+			policy:  Policy{AllowedTools: []string{"bash"}, DeniedTools: []string{"bash"}},
+			request: CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: workingDir},
+			allowed: false,
+		},
+		{
+			name:    "allowed tool",
+			policy:  Policy{AllowedTools: []string{"bash"}},
+			request: CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: workingDir},
+			allowed: true,
+		},
+		{
+			name:    "allowed tool action",
+			policy:  Policy{AllowedTools: []string{"edit:write"}},
+			request: CreatePermissionRequest{ToolName: "edit", Action: "write", Path: workingDir + "/internal"},
+			allowed: true,
+		},
+		{
+			name:    "tool not in allowlist",
+			policy:  Policy{AllowedTools: []string{"edit"}},
+			request: CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: workingDir},
+			allowed: false,
+		},
+		{
+			name:    "tool not in only tools",
+			policy:  Policy{OnlyTools: []string{"view", "ls"}},
+			request: CreatePermissionRequest{ToolName: "write", Action: "write", Path: workingDir},
+			allowed: false,
+		},
+		{
+			name:    "deny-only policy keeps the working directory restriction",
+			policy:  Policy{DeniedTools: []string{"fetch"}},
+			request: CreatePermissionRequest{ToolName: "edit", Action: "write", Path: "/etc/hosts"},
+			allowed: false,
+		},
+		{
+			name:    "deny-only policy with allowed paths",
+			policy:  Policy{DeniedTools: []string{"fetch"}, AllowedPaths: []string{"/etc"}},
+			request: CreatePermissionRequest{ToolName: "edit", Action: "write", Path: "/etc/hosts"},
+			allowed: true,
+		},
+		{
+			name:    "path outside working directory with only tools",
+			policy:  Policy{OnlyTools: []string{"view"}},
+			request: CreatePermissionRequest{ToolName: "view", Action: "read", Path: "/etc"},
+			allowed: false,
+		},
+		{
+			name:    "path in allowed paths",
+			policy:  Policy{OnlyTools: []string{"view"}, AllowedPaths: []string{"/opt/shared"}},
+			request: CreatePermissionRequest{ToolName: "view", Action: "read", Path: "/opt/shared/docs"},
+			allowed: true,
+		},
+		{
+			name:    "sibling of allowed path",
+			policy:  Policy{AllowedPaths: []string{"/opt/shared"}},
+			request: CreatePermissionRequest{ToolName: "view", Action: "read", Path: "/opt/shared-other"},
+			allowed: false,
+		},
+		{
+			name:    "relative path inside working directory",
+			policy:  Policy{AllowedPaths: []string{"/opt/shared"}},
+			request: CreatePermissionRequest{ToolName: "edit", Action: "write", Path: "internal/cmd"},
+			allowed: true,
+		},
+		{
+			name:    "relative path escaping working directory",
+			policy:  Policy{AllowedPaths: []string{"/opt/shared"}},
+			request: CreatePermissionRequest{ToolName: "edit", Action: "write", Path: "../other"},
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.policy.Evaluate(workingDir, tt.request)
+			if tt.allowed {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrorPermissionDenied)
+		})
+	}
+}
+
+func TestPermissionService_SessionPolicy(t *testing.T) {
+	t.Parallel()
+
+	service := NewPermissionService("/work/project", false, []string{"bash"})
+	service.SetSessionPolicy("session", Policy{DeniedTools: []string{"bash"}})
+
+	// The policy takes precedence over the configured allowlist, and the
+	// request returns without waiting for the user.
+	require.False(t, service.Request(CreatePermissionRequest{
+		SessionID: "session",
+		ToolName:  "bash",
+		Action:    "execute",
+		Path:      "/work/project",
+	}))
+	require.True(t, service.Request(CreatePermissionRequest{
+		SessionID: "session",
+		ToolName:  "edit",
+		Action:    "write",
+		Path:      "/work/project",
+	}))
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// sseKeepAlive is how often a comment is sent on idle event streams so
+// proxies and clients don't time out.
+const sseKeepAlive = 15 * time.Second
+
+// handleEvents streams the events of all services as server-sent events.
+// The optional session_id query parameter limits the stream to the events of
+// one session.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	sessionID := r.URL.Query().Get("session_id")
+
+	ctx, cancel := context.WithCancel(r.Context())
+	events := make(chan Event, 64)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	forward(ctx, &wg, events, "session", s.app.Sessions.Subscribe, func(e pubsub.Event[session.Session]) (any, string) {
+		return toSession(e.Payload, s.app.CoderAgent), e.Payload.ID
+	})
+	forward(ctx, &wg, events, "message", s.app.Messages.Subscribe, func(e pubsub.Event[message.Message]) (any, string) {
+		return toMessage(e.Payload), e.Payload.SessionID
+	})
+	forward(ctx, &wg, events, "file", s.app.History.Subscribe, func(e pubsub.Event[history.File]) (any, string) {
+		return toFile(e.Payload), e.Payload.SessionID
+	})
+	forward(ctx, &wg, events, "permission", s.app.Permissions.Subscribe, func(e pubsub.Event[permission.PermissionRequest]) (any, string) {
+		return e.Payload, e.Payload.SessionID
+	})
+	forward(ctx, &wg, events, "permission_notification", s.app.Permissions.SubscribeNotifications, func(e pubsub.Event[permission.PermissionNotification]) (any, string) {
+		return e.Payload, ""
+	})
+	if s.app.CoderAgent != nil {
+		forward(ctx, &wg, events, "agent", s.app.CoderAgent.Subscribe, func(e pubsub.Event[agent.AgentEvent]) (any, string) {
+			event := toAgentEvent(e.Payload)
+			return event, event.SessionID
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			if sessionID != "" && event.sessionID != "" && event.sessionID != sessionID {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				slog.Error("Failed to marshal event", "kind", event.Kind, "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Kind, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// forward subscribes to a service and sends its events to out, converted to
+// their API representation along with the ID of the session they belong to.
+func forward[T any](
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	out chan<- Event,
+	kind string,
+	subscribe func(context.Context) <-chan pubsub.Event[T],
+	convert func(pubsub.Event[T]) (any, string),
+) {
+	// Subscribe right away so no event is missed once the stream started.
+	sub := subscribe(ctx)
+	wg.Go(func() {
+		for {
+			select {
+			case e, ok := <-sub:
+				if !ok {
+					return
+				}
+				payload, sessionID := convert(e)
+				select {
+				case out <- Event{Kind: kind, Type: string(e.Type), Payload: payload, sessionID: sessionID}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/log"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+var errAgentNotConfigured = errors.New("no agent configured: run crush interactively to set up a provider")
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":     "ok",
+		"configured": s.app.Config().IsConfigured(),
+		"busy":       s.app.CoderAgent != nil && s.app.CoderAgent.IsBusy(),
+	})
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.app.Sessions.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list sessions: %w", err))
+		return
+	}
+	out := make([]Session, len(sessions))
+	for i, sess := range sessions {
+		out[i] = toSession(sess, s.app.CoderAgent)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	var req CreateSessionRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		req.Title = "New Session"
+	}
+	sess, err := s.app.Sessions.Create(r.Context(), req.Title)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create session: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, toSession(sess, s.app.CoderAgent))
+}
+
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, toSession(sess, s.app.CoderAgent))
+}
+
+func (s *Server) handleUpdateSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	var req UpdateSessionRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("title cannot be empty"))
+		return
+	}
+	sess.Title = req.Title
+	sess, err := s.app.Sessions.Save(r.Context(), sess)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to update session: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, toSession(sess, s.app.CoderAgent))
+}
+
+func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	if s.app.CoderAgent != nil && s.app.CoderAgent.IsSessionBusy(sess.ID) {
+		writeError(w, http.StatusConflict, agent.ErrSessionBusy)
+		return
+	}
+	if _, err := session.DeleteTree(r.Context(), s.app.Sessions, sess.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to delete session: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListMessages(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	msgs, err := s.app.Messages.List(r.Context(), sess.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list messages: %w", err))
+		return
+	}
+	out := make([]Message, len(msgs))
+	for i, msg := range msgs {
+		out[i] = toMessage(msg)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	files, err := s.app.History.ListBySession(r.Context(), sess.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list files: %w", err))
+		return
+	}
+	out := make([]File, len(files))
+	for i, f := range files {
+		out[i] = toFile(f)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	if s.app.CoderAgent == nil {
+		writeError(w, http.StatusServiceUnavailable, errAgentNotConfigured)
+		return
+	}
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	var req PromptRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("prompt cannot be empty"))
+		return
+	}
+
+	// The run is bound to the server and not to the request, so it keeps
+	// going when the client disconnects. Use the cancel endpoint to stop it.
+	done, err := s.app.CoderAgent.Run(s.ctx, sess.ID, req.Prompt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to run agent: %w", err))
+		return
+	}
+	if done == nil {
+		// The session is busy; the prompt was queued.
+		writeJSON(w, http.StatusAccepted, PromptResponse{SessionID: sess.ID, Queued: true})
+		return
+	}
+
+	if !req.Wait {
+		go func() {
+			defer log.RecoverPanic("server.handlePrompt", nil)
+			// Results are published on the event stream as well.
+			<-done
+		}()
+		writeJSON(w, http.StatusAccepted, PromptResponse{SessionID: sess.ID})
+		return
+	}
+
+	select {
+	case result := <-done:
+		event := toAgentEvent(result)
+		status := http.StatusOK
+		if result.Error != nil && !errors.Is(result.Error, agent.ErrRequestCancelled) {
+			status = http.StatusInternalServerError
+		}
+		writeJSON(w, status, PromptResponse{SessionID: sess.ID, Result: &event})
+	case <-r.Context().Done():
+		// The client went away; drain the result so the agent isn't
+		// blocked.
+		go func() { <-done }()
+	}
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if s.app.CoderAgent == nil {
+		writeError(w, http.StatusServiceUnavailable, errAgentNotConfigured)
+		return
+	}
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	s.app.CoderAgent.Cancel(sess.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListPermissions(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+	out := []permission.PermissionRequest{}
+	for req := range s.pending.Seq() {
+		if sessionID == "" || req.SessionID == sessionID {
+			out = append(out, req)
+		}
+	}
+	slices.SortFunc(out, func(a, b permission.PermissionRequest) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleGrantPermission(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.takePermission(w, r)
+	if !ok {
+		return
+	}
+	var body GrantRequest
+	if err := readJSON(r, &body); err != nil {
+		// Put the request back so it can still be answered.
+		s.pending.Set(req.ID, req)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.Persistent {
+		s.app.Permissions.GrantPersistent(req)
+	} else {
+		s.app.Permissions.Grant(req)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDenyPermission(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.takePermission(w, r)
+	if !ok {
+		return
+	}
+	s.app.Permissions.Deny(req)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSession loads the session named in the path, writing an error response
+// if it can't.
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request) (session.Session, bool) {
+	id := r.PathValue("id")
+	sess, err := s.app.Sessions.Get(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("session not found: %s", id))
+		return session.Session{}, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get session: %w", err))
+		return session.Session{}, false
+	}
+	return sess, true
+}
+
+// takePermission removes the pending permission request named in the path,
+// writing an error response if there is none.
+func (s *Server) takePermission(w http.ResponseWriter, r *http.Request) (permission.PermissionRequest, bool) {
+	id := r.PathValue("id")
+	req, ok := s.pending.Take(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("permission request not found: %s", id))
+		return permission.PermissionRequest{}, false
+	}
+	return req, true
+}
@@ -0,0 +1,242 @@
+// Package server exposes a Crush application over a local HTTP/JSON API so
+// that other programs, like editor plugins, can drive it without a terminal.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/log"
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Token, when set, must be sent as a bearer token in the Authorization
+	// header of every request.
+	Token string
+}
+
+// Server serves the API of an app.
+type Server struct {
+	app   *app.App
+	token string
+	mux   *http.ServeMux
+
+	// ctx outlives individual requests; agent runs are bound to it.
+	ctx context.Context
+
+	// pending holds the permission requests waiting for an answer, by ID.
+	pending *csync.Map[string, permission.PermissionRequest]
+}
+
+// New creates a server for the given app.
+func New(app *app.App, opts Options) *Server {
+	s := &Server{
+		app:     app,
+		token:   opts.Token,
+		mux:     http.NewServeMux(),
+		ctx:     context.Background(),
+		pending: csync.NewMap[string, permission.PermissionRequest](),
+	}
+	s.routes()
+	return s
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /v1/health", s.handleHealth)
+
+	s.mux.HandleFunc("GET /v1/sessions", s.handleListSessions)
+	s.mux.HandleFunc("POST /v1/sessions", s.handleCreateSession)
+	s.mux.HandleFunc("GET /v1/sessions/{id}", s.handleGetSession)
+	s.mux.HandleFunc("PATCH /v1/sessions/{id}", s.handleUpdateSession)
+	s.mux.HandleFunc("DELETE /v1/sessions/{id}", s.handleDeleteSession)
+	s.mux.HandleFunc("GET /v1/sessions/{id}/messages", s.handleListMessages)
+	s.mux.HandleFunc("GET /v1/sessions/{id}/files", s.handleListFiles)
+	s.mux.HandleFunc("POST /v1/sessions/{id}/prompt", s.handlePrompt)
+	s.mux.HandleFunc("POST /v1/sessions/{id}/cancel", s.handleCancel)
+
+	s.mux.HandleFunc("GET /v1/permissions", s.handleListPermissions)
+	s.mux.HandleFunc("POST /v1/permissions/{id}/grant", s.handleGrantPermission)
+	s.mux.HandleFunc("POST /v1/permissions/{id}/deny", s.handleDenyPermission)
+
+	s.mux.HandleFunc("GET /v1/events", s.handleEvents)
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	return s.checkRequest(s.authenticate(s.mux))
+}
+
+// Serve accepts connections on ln until ctx is done.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.ctx = ctx
+
+	var wg sync.WaitGroup
+	wg.Go(func() { s.trackPermissions(ctx) })
+	defer wg.Wait()
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to shutdown server", "error", err)
+			return srv.Close()
+		}
+		return nil
+	}
+}
+
+// trackPermissions keeps the list of pending permission requests up to date
+// so clients can list and answer them.
+func (s *Server) trackPermissions(ctx context.Context) {
+	defer log.RecoverPanic("server.trackPermissions", nil)
+
+	requests := s.app.Permissions.Subscribe(ctx)
+	notifications := s.app.Permissions.SubscribeNotifications(ctx)
+	for {
+		select {
+		case event, ok := <-requests:
+			if !ok {
+				return
+			}
+			s.pending.Set(event.Payload.ID, event.Payload)
+		case event, ok := <-notifications:
+			if !ok {
+				return
+			}
+			if !event.Payload.Granted && !event.Payload.Denied {
+				continue
+			}
+			for id, req := range s.pending.Seq2() {
+				if req.ToolCallID == event.Payload.ToolCallID {
+					s.pending.Del(id)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkRequest rejects the requests a web page could trick a browser into
+// sending: requests naming another host, as sent after DNS rebinding,
+// requests from other origins, and request bodies that aren't JSON, which
+// browsers send to other origins without asking first.
+func (s *Server) checkRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		local, _ := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+		if !allowedHost(local, r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not allowed", r.Host))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Scheme != "http" || !allowedHost(local, u.Host) {
+				writeError(w, http.StatusForbidden, fmt.Errorf("origin %q is not allowed", origin))
+				return
+			}
+		}
+		if r.ContentLength != 0 && r.Method != http.MethodGet {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("request body must be application/json"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether host, from the Host header or the origin of a
+// request, names the address the server listens on. Unix sockets can only be
+// reached by local programs allowed to open them, so any host goes there.
+func allowedHost(local net.Addr, host string) bool {
+	if local == nil || local.Network() != "tcp" {
+		return true
+	}
+	localHost, localPort, err := net.SplitHostPort(local.String())
+	if err != nil {
+		return false
+	}
+	name, port, err := net.SplitHostPort(host)
+	if err != nil || port != localPort {
+		return false
+	}
+	if name == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(name)
+	return ip != nil && ip.Equal(net.ParseIP(localHost))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+func readJSON(r *http.Request, v any) error {
+	if r.Body == nil || r.ContentLength == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 10<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer serves the API of an app without any provider configured,
+// backed by a fresh database.
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+	ts, _ := newTestServerApp(t, opts)
+	return ts
+}
+
+// newTestServerApp is newTestServer, also returning the app served.
+func newTestServerApp(t *testing.T, opts Options) (*httptest.Server, *app.App) {
+	t.Helper()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	a, err := app.New(t.Context(), conn, &config.Config{
+		Providers: csync.NewMap[string, config.ProviderConfig](),
+	})
+	require.NoError(t, err)
+	t.Cleanup(a.Shutdown)
+
+	ts := httptest.NewServer(New(a, opts).Handler())
+	t.Cleanup(ts.Close)
+	return ts, a
+}
+
+func do(t *testing.T, ts *httptest.Server, method, path, body string, header http.Header) (*http.Response, string) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(t.Context(), method, ts.URL+path, reader)
+	require.NoError(t, err)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if host := header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(data)
+}
+
+func TestAuthentication(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, Options{Token: "secret"})
+
+	for _, tt := range []struct {
+		name   string
+		header string
+		status int
+	}{
+		{name: "no token", status: http.StatusUnauthorized},
+		{name: "wrong token", header: "Bearer wrong", status: http.StatusUnauthorized},
+		{name: "not a bearer token", header: "secret", status: http.StatusUnauthorized},
+		{name: "token", header: "Bearer secret", status: http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("Authorization", tt.header)
+			}
+			resp, _ := do(t, ts, http.MethodGet, "/v1/health", "", header)
+			require.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestRequestChecks(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, Options{})
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name   string
+		method string
+		body   string
+		header http.Header
+		status int
+	}{
+		{
+			name:   "rebound host",
+			header: http.Header{"Host": {"attacker.example:" + u.Port()}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "other port",
+			header: http.Header{"Host": {"127.0.0.1:1"}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "localhost",
+			header: http.Header{"Host": {"localhost:" + u.Port()}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "other origin",
+			header: http.Header{"Origin": {"http://attacker.example"}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "same origin",
+			header: http.Header{"Origin": {ts.URL}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "text body",
+			method: http.MethodPost,
+			body:   `{"title": "Sneaky"}`,
+			header: http.Header{"Content-Type": {"text/plain"}},
+			status: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "json body",
+			method: http.MethodPost,
+			body:   `{"title": "Welcome"}`,
+			header: http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+			status: http.StatusCreated,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			resp, _ := do(t, ts, method, "/v1/sessions", tt.body, tt.header)
+			require.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestSessionRoutes(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, Options{})
+
+	resp, body := do(t, ts, http.MethodPost, "/v1/sessions", `{"title": "Fix the tests"}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created Session
+	require.NoError(t, json.Unmarshal([]byte(body), &created))
+	require.NotEmpty(t, created.ID)
+	require.Equal(t, "Fix the tests", created.Title)
+
+	resp, body = do(t, ts, http.MethodPatch, "/v1/sessions/"+created.ID, `{"title": "Fix the flaky test"}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, body, `"title":"Fix the flaky test"`)
+
+	resp, body = do(t, ts, http.MethodGet, "/v1/sessions", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var sessions []Session
+	require.NoError(t, json.Unmarshal([]byte(body), &sessions))
+	require.Len(t, sessions, 1)
+	require.Equal(t, "Fix the flaky test", sessions[0].Title)
+
+	resp, body = do(t, ts, http.MethodGet, "/v1/sessions/"+created.ID+"/messages", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.JSONEq(t, `[]`, body)
+
+	resp, _ = do(t, ts, http.MethodPatch, "/v1/sessions/"+created.ID, `{"name": "unknown field"}`, nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// No provider is configured, so there is no agent to prompt.
+	resp, _ = do(t, ts, http.MethodPost, "/v1/sessions/"+created.ID+"/prompt", `{"prompt": "hi"}`, nil)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	resp, _ = do(t, ts, http.MethodDelete, "/v1/sessions/"+created.ID, "", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, body = do(t, ts, http.MethodGet, "/v1/sessions/"+created.ID, "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.JSONEq(t, `{"error": "session not found: `+created.ID+`"}`, body)
+
+	resp, _ = do(t, ts, http.MethodPut, "/v1/sessions", "", nil)
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, _ = do(t, ts, http.MethodGet, "/v1/unknown", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestDeleteSessionTasks(t *testing.T) {
+	t.Parallel()
+
+	ts, a := newTestServerApp(t, Options{})
+	ctx := t.Context()
+	parent, err := a.Sessions.Create(ctx, "Fix the flaky test")
+	require.NoError(t, err)
+	task, err := a.Sessions.CreateTaskSession(ctx, "call", parent.ID, "Find the test")
+	require.NoError(t, err)
+	title, err := a.Sessions.CreateTitleSession(ctx, parent.ID)
+	require.NoError(t, err)
+
+	resp, _ := do(t, ts, http.MethodDelete, "/v1/sessions/"+parent.ID, "", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	for _, id := range []string{task.ID, title.ID} {
+		_, err := a.Sessions.Get(ctx, id)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestServer(t, Options{})
+
+	_, body := do(t, ts, http.MethodPost, "/v1/sessions", `{"title": "Watched"}`, nil)
+	var watched Session
+	require.NoError(t, json.Unmarshal([]byte(body), &watched))
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, ts.URL+"/v1/events?session_id="+watched.ID, nil)
+	require.NoError(t, err)
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// Events of other sessions are filtered out.
+	do(t, ts, http.MethodPost, "/v1/sessions", `{"title": "Other"}`, nil)
+	do(t, ts, http.MethodPatch, "/v1/sessions/"+watched.ID, `{"title": "Renamed"}`, nil)
+
+	scanner := bufio.NewScanner(resp.Body)
+	var lines []string
+	for scanner.Scan() && len(lines) < 2 {
+		if line := scanner.Text(); line != "" && !strings.HasPrefix(line, ":") {
+			lines = append(lines, line)
+		}
+	}
+	require.Len(t, lines, 2)
+	require.Equal(t, "event: session", lines[0])
+
+	data, ok := strings.CutPrefix(lines[1], "data: ")
+	require.True(t, ok)
+	var event struct {
+		Kind    string  `json:"kind"`
+		Type    string  `json:"type"`
+		Payload Session `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &event))
+	require.Equal(t, "session", event.Kind)
+	require.Equal(t, "updated", event.Type)
+	require.Equal(t, watched.ID, event.Payload.ID)
+	require.Equal(t, "Renamed", event.Payload.Title)
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// Session is the API representation of a session.
+type Session struct {
+	ID               string  `json:"id"`
+	ParentSessionID  string  `json:"parent_session_id,omitempty"`
+	Title            string  `json:"title"`
+	MessageCount     int64   `json:"message_count"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	SummaryMessageID string  `json:"summary_message_id,omitempty"`
+	Cost             float64 `json:"cost"`
+	CreatedAt        int64   `json:"created_at"`
+	UpdatedAt        int64   `json:"updated_at"`
+	Busy             bool    `json:"busy"`
+	QueuedPrompts    int     `json:"queued_prompts"`
+}
+
+// Message is the API representation of a message.
+type Message struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	Role      string `json:"role"`
+	Model     string `json:"model,omitempty"`
+	Provider  string `json:"provider,omitempty"`
+	Parts     []Part `json:"parts"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// Part is the API representation of a message part. Type tells which of the
+// other fields are set.
+type Part struct {
+	Type       string `json:"type"`
+	Text       string `json:"text,omitempty"`
+	ID         string `json:"id,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Input      string `json:"input,omitempty"`
+	Finished   bool   `json:"finished,omitempty"`
+	Content    string `json:"content,omitempty"`
+	Metadata   string `json:"metadata,omitempty"`
+	IsError    bool   `json:"is_error,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Path       string `json:"path,omitempty"`
+	MIMEType   string `json:"mime_type,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Details    string `json:"details,omitempty"`
+}
+
+// File is the API representation of a version of a file in the history.
+type File struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	Version   int64  `json:"version"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// AgentEvent is the API representation of an agent event.
+type AgentEvent struct {
+	Type      string   `json:"type"`
+	SessionID string   `json:"session_id,omitempty"`
+	Message   *Message `json:"message,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Progress  string   `json:"progress,omitempty"`
+	Done      bool     `json:"done"`
+}
+
+// Event is a record sent on the event stream.
+type Event struct {
+	// Kind is the resource the event is about: session, message,
+	// permission, permission_notification, file or agent.
+	Kind string `json:"kind"`
+	// Type is the pubsub event type: created, updated or deleted.
+	Type    string `json:"type"`
+	Payload any    `json:"payload"`
+
+	// sessionID is used to filter the stream by session.
+	sessionID string
+}
+
+// CreateSessionRequest is the body of POST /v1/sessions.
+type CreateSessionRequest struct {
+	Title string `json:"title"`
+}
+
+// UpdateSessionRequest is the body of PATCH /v1/sessions/{id}.
+type UpdateSessionRequest struct {
+	Title string `json:"title"`
+}
+
+// PromptRequest is the body of POST /v1/sessions/{id}/prompt.
+type PromptRequest struct {
+	Prompt string `json:"prompt"`
+	// Wait makes the request block until the agent is done and return its
+	// final event.
+	Wait bool `json:"wait,omitempty"`
+}
+
+// PromptResponse is returned by POST /v1/sessions/{id}/prompt.
+type PromptResponse struct {
+	SessionID string      `json:"session_id"`
+	Queued    bool        `json:"queued"`
+	Result    *AgentEvent `json:"result,omitempty"`
+}
+
+// GrantRequest is the body of POST /v1/permissions/{id}/grant.
+type GrantRequest struct {
+	// Persistent grants the permission for the rest of the session.
+	Persistent bool `json:"persistent,omitempty"`
+}
+
+// ErrorResponse is returned when a request fails.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func toSession(s session.Session, coder agent.Service) Session {
+	out := Session{
+		ID:               s.ID,
+		ParentSessionID:  s.ParentSessionID,
+		Title:            s.Title,
+		MessageCount:     s.MessageCount,
+		PromptTokens:     s.PromptTokens,
+		CompletionTokens: s.CompletionTokens,
+		SummaryMessageID: s.SummaryMessageID,
+		Cost:             s.Cost,
+		CreatedAt:        s.CreatedAt,
+		UpdatedAt:        s.UpdatedAt,
+	}
+	if coder != nil {
+		out.Busy = coder.IsSessionBusy(s.ID)
+		out.QueuedPrompts = coder.QueuedPrompts(s.ID)
+	}
+	return out
+}
+
+func toMessage(msg message.Message) Message {
+	out := Message{
+		ID:        msg.ID,
+		SessionID: msg.SessionID,
+		Role:      string(msg.Role),
+		Model:     msg.Model,
+		Provider:  msg.Provider,
+		Parts:     make([]Part, 0, len(msg.Parts)),
+		CreatedAt: msg.CreatedAt,
+		UpdatedAt: msg.UpdatedAt,
+	}
+	for _, part := range msg.Parts {
+		switch p := part.(type) {
+		case message.ReasoningContent:
+			out.Parts = append(out.Parts, Part{Type: "reasoning", Text: p.Thinking})
+		case message.TextContent:
+			out.Parts = append(out.Parts, Part{Type: "text", Text: p.Text})
+		case message.ImageURLContent:
+			out.Parts = append(out.Parts, Part{Type: "image_url", URL: p.URL})
+		case message.BinaryContent:
+			out.Parts = append(out.Parts, Part{Type: "binary", Path: p.Path, MIMEType: p.MIMEType})
+		case message.ToolCall:
+			out.Parts = append(out.Parts, Part{Type: "tool_call", ID: p.ID, Name: p.Name, Input: p.Input, Finished: p.Finished})
+		case message.ToolResult:
+			out.Parts = append(out.Parts, Part{Type: "tool_result", ToolCallID: p.ToolCallID, Name: p.Name, Content: p.Content, Metadata: p.Metadata, IsError: p.IsError})
+		case message.Finish:
+			out.Parts = append(out.Parts, Part{Type: "finish", Reason: string(p.Reason), Message: p.Message, Details: p.Details})
+		}
+	}
+	return out
+}
+
+func toFile(f history.File) File {
+	return File{
+		ID:        f.ID,
+		SessionID: f.SessionID,
+		Path:      f.Path,
+		Content:   f.Content,
+		Version:   f.Version,
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+	}
+}
+
+func toAgentEvent(e agent.AgentEvent) AgentEvent {
+	out := AgentEvent{
+		Type:      string(e.Type),
+		SessionID: e.SessionID,
+		Progress:  e.Progress,
+		Done:      e.Done,
+	}
+	if e.Message.ID != "" {
+		msg := toMessage(e.Message)
+		out.Message = &msg
+		if out.SessionID == "" {
+			out.SessionID = e.Message.SessionID
+		}
+	}
+	if e.Error != nil {
+		out.Error = e.Error.Error()
+	}
+	return out
+}
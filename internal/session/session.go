@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/charmbracelet/crush/internal/db"
 	"github.com/charmbracelet/crush/internal/event"
@@ -21,6 +22,8 @@ type Session struct {
 	Cost             float64
 	CreatedAt        int64
 	UpdatedAt        int64
+	// ForkedFromSessionID is the session this one was forked from, if any.
+	ForkedFromSessionID string
 }
 
 type Service interface {
@@ -30,6 +33,7 @@ type Service interface {
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
 	List(ctx context.Context) ([]Session, error)
+	ListChildren(ctx context.Context, parentSessionID string) ([]Session, error)
 	Save(ctx context.Context, session Session) (Session, error)
 	Delete(ctx context.Context, id string) error
 }
@@ -135,6 +139,20 @@ func (s *service) List(ctx context.Context) ([]Session, error) {
 	return sessions, nil
 }
 
+// ListChildren lists the task and title sessions created by a session, oldest
+// first.
+func (s *service) ListChildren(ctx context.Context, parentSessionID string) ([]Session, error) {
+	dbSessions, err := s.q.ListChildSessions(ctx, sql.NullString{String: parentSessionID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = s.fromDBItem(dbSession)
+	}
+	return sessions, nil
+}
+
 func (s service) fromDBItem(item db.Session) Session {
 	return Session{
 		ID:               item.ID,
@@ -147,6 +165,8 @@ func (s service) fromDBItem(item db.Session) Session {
 		Cost:             item.Cost,
 		CreatedAt:        item.CreatedAt,
 		UpdatedAt:        item.UpdatedAt,
+
+		ForkedFromSessionID: item.ForkedFromSessionID.String,
 	}
 }
 
@@ -157,3 +177,25 @@ func NewService(q db.Querier) Service {
 		q,
 	}
 }
+
+// DeleteTree deletes a session after the task and title sessions it
+// started, so none are left behind without a parent. It returns the IDs of the
+// sessions deleted, the session itself last.
+func DeleteTree(ctx context.Context, sessions Service, id string) ([]string, error) {
+	children, err := sessions.ListChildren(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task sessions of session %s: %w", id, err)
+	}
+	var deleted []string
+	for _, child := range children {
+		ids, err := DeleteTree(ctx, sessions, child.ID)
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, ids...)
+	}
+	if err := sessions.Delete(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to delete session %s: %w", id, err)
+	}
+	return append(deleted, id), nil
+}
@@ -0,0 +1,162 @@
+// Package todo keeps the task lists the agent maintains for its sessions.
+package todo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed"
+)
+
+// Valid reports whether s is one of the known statuses.
+func (s Status) Valid() bool {
+	switch s {
+	case StatusPending, StatusInProgress, StatusCompleted:
+		return true
+	}
+	return false
+}
+
+// Todo is a task item the agent keeps track of in a session. Position is the
+// 1-based place of the item in the session's list.
+type Todo struct {
+	ID        string
+	SessionID string
+	Content   string
+	Status    Status
+	Position  int64
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+type Service interface {
+	pubsub.Suscriber[Todo]
+	Create(ctx context.Context, sessionID, content string, status Status) (Todo, error)
+	Update(ctx context.Context, todo Todo) (Todo, error)
+	List(ctx context.Context, sessionID string) ([]Todo, error)
+	DeleteSessionTodos(ctx context.Context, sessionID string) error
+}
+
+type service struct {
+	*pubsub.Broker[Todo]
+	q db.Querier
+}
+
+func NewService(q db.Querier) Service {
+	return &service{
+		Broker: pubsub.NewBroker[Todo](),
+		q:      q,
+	}
+}
+
+// Create adds an item at the end of the session's list.
+func (s *service) Create(ctx context.Context, sessionID, content string, status Status) (Todo, error) {
+	todos, err := s.q.ListTodosBySession(ctx, sessionID)
+	if err != nil {
+		return Todo{}, err
+	}
+	dbTodo, err := s.q.CreateTodo(ctx, db.CreateTodoParams{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		Content:   content,
+		Status:    string(status),
+		Position:  int64(len(todos)) + 1,
+	})
+	if err != nil {
+		return Todo{}, err
+	}
+	todo := s.fromDBItem(dbTodo)
+	s.Publish(pubsub.CreatedEvent, todo)
+	return todo, nil
+}
+
+func (s *service) Update(ctx context.Context, todo Todo) (Todo, error) {
+	dbTodo, err := s.q.UpdateTodo(ctx, db.UpdateTodoParams{
+		Content: todo.Content,
+		Status:  string(todo.Status),
+		ID:      todo.ID,
+	})
+	if err != nil {
+		return Todo{}, err
+	}
+	todo = s.fromDBItem(dbTodo)
+	s.Publish(pubsub.UpdatedEvent, todo)
+	return todo, nil
+}
+
+func (s *service) List(ctx context.Context, sessionID string) ([]Todo, error) {
+	dbTodos, err := s.q.ListTodosBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	todos := make([]Todo, len(dbTodos))
+	for i, dbTodo := range dbTodos {
+		todos[i] = s.fromDBItem(dbTodo)
+	}
+	return todos, nil
+}
+
+func (s *service) DeleteSessionTodos(ctx context.Context, sessionID string) error {
+	todos, err := s.List(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	for _, todo := range todos {
+		if err := s.q.DeleteTodo(ctx, todo.ID); err != nil {
+			return err
+		}
+		s.Publish(pubsub.DeletedEvent, todo)
+	}
+	return nil
+}
+
+func (s *service) fromDBItem(item db.Todo) Todo {
+	return Todo{
+		ID:        item.ID,
+		SessionID: item.SessionID,
+		Content:   item.Content,
+		Status:    Status(item.Status),
+		Position:  item.Position,
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: item.UpdatedAt,
+	}
+}
+
+// Open returns the items that aren't completed yet.
+func Open(todos []Todo) []Todo {
+	var open []Todo
+	for _, todo := range todos {
+		if todo.Status != StatusCompleted {
+			open = append(open, todo)
+		}
+	}
+	return open
+}
+
+// Format renders todos as a markdown task list, with their positions so they
+// can be referred to.
+func Format(todos []Todo) string {
+	var sb strings.Builder
+	for _, todo := range todos {
+		mark := " "
+		switch todo.Status {
+		case StatusInProgress:
+			mark = "~"
+		case StatusCompleted:
+			mark = "x"
+		}
+		fmt.Fprintf(&sb, "- [%s] %d. %s\n", mark, todo.Position, todo.Content)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
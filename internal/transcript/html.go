@@ -0,0 +1,69 @@
+package transcript
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+//go:embed transcript.html
+var htmlTemplate string
+
+var htmlTmpl = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time":      formatTime,
+	"cost":      formatCost,
+	"role":      roleTitle,
+	"diffLines": diffLines,
+	"dataURL":   dataURL,
+}).Parse(htmlTemplate))
+
+type htmlData struct {
+	Transcript Transcript
+	Root       sessionView
+}
+
+// WriteHTML writes the transcript as a standalone HTML page.
+func WriteHTML(w io.Writer, t Transcript) error {
+	return htmlTmpl.Execute(w, htmlData{
+		Transcript: t,
+		Root:       newSessionView(&t.Session, 1),
+	})
+}
+
+type diffLine struct {
+	Class string
+	Text  string
+}
+
+func diffLines(d string) []diffLine {
+	lines := strings.Split(d, "\n")
+	out := make([]diffLine, len(lines))
+	for i, line := range lines {
+		class := ""
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			class = "file"
+		case strings.HasPrefix(line, "@@"):
+			class = "hunk"
+		case strings.HasPrefix(line, "+"):
+			class = "add"
+		case strings.HasPrefix(line, "-"):
+			class = "del"
+		}
+		out[i] = diffLine{Class: class, Text: line}
+	}
+	return out
+}
+
+// dataURL embeds an image attachment in the page. Other attachments are not
+// embedded.
+func dataURL(b message.BinaryContent) template.URL {
+	if !strings.HasPrefix(b.MIMEType, "image/") || len(b.Data) == 0 {
+		return ""
+	}
+	return template.URL("data:" + b.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(b.Data))
+}
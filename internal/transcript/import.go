@@ -0,0 +1,244 @@
+package transcript
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/google/uuid"
+)
+
+// Read decodes a transcript exported as JSON.
+func Read(r io.Reader) (Transcript, error) {
+	var t Transcript
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return Transcript{}, fmt.Errorf("failed to decode transcript: %w", err)
+	}
+	if t.Version < 1 || t.Version > Version {
+		return Transcript{}, fmt.Errorf("unsupported transcript version %d", t.Version)
+	}
+	if t.Session.ID == "" {
+		return Transcript{}, fmt.Errorf("transcript has no session")
+	}
+	return t, nil
+}
+
+// Import recreates an exported session with its messages, task sessions and
+// file history, and returns the ID of the new session. Everything gets a new
+// ID so that a session can be imported next to the one it was exported from.
+// File paths under the working directory of the export are moved under
+// workingDir, in the file history as well as in tool call params, tool result
+// metadata and attachments. The history of files outside the working
+// directory of the export is dropped, as it can't be restored anyway.
+func Import(ctx context.Context, conn *sql.DB, t Transcript, workingDir string) (string, error) {
+	s := remapSession(t.Session, uuid.New().String(), "", t.WorkingDir, workingDir)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertSession(ctx, db.New(tx), s); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.ID, nil
+}
+
+// remapSession returns a copy of s with the given ID and parent, and new IDs
+// for everything it contains. Task sessions share their ID with the tool call
+// that started them, so those tool calls get the new ID of the task session.
+func remapSession(s Session, id, parentID, fromDir, toDir string) Session {
+	taskIDs := make(map[string]string, len(s.Children))
+	for _, child := range s.Children {
+		taskIDs[child.ID] = uuid.New().String()
+	}
+	messageIDs := make(map[string]string, len(s.Messages))
+	for _, msg := range s.Messages {
+		messageIDs[msg.ID] = uuid.New().String()
+	}
+
+	out := s
+	out.ID = id
+	out.ParentSessionID = parentID
+	out.SummaryMessageID = messageIDs[s.SummaryMessageID]
+	out.Messages = make([]Message, len(s.Messages))
+	for i, msg := range s.Messages {
+		msg.ID = messageIDs[msg.ID]
+		parts := make(Parts, len(msg.Parts))
+		for j, part := range msg.Parts {
+			switch p := part.(type) {
+			case message.ToolCall:
+				if taskID, ok := taskIDs[p.ID]; ok {
+					p.ID = taskID
+				}
+				p.Input = movePaths(p.Input, fromDir, toDir)
+				part = p
+			case message.ToolResult:
+				if taskID, ok := taskIDs[p.ToolCallID]; ok {
+					p.ToolCallID = taskID
+				}
+				p.Metadata = movePaths(p.Metadata, fromDir, toDir)
+				part = p
+			case message.BinaryContent:
+				p.Path = movePath(p.Path, fromDir, toDir)
+				part = p
+			}
+			parts[j] = part
+		}
+		msg.Parts = parts
+		out.Messages[i] = msg
+	}
+	out.Files = make([]File, 0, len(s.Files))
+	for _, f := range s.Files {
+		if _, ok := relPath(fromDir, f.Path); fromDir != "" && !ok {
+			slog.Warn("Skipping the history of a file outside the working directory", "path", f.Path)
+			continue
+		}
+		f.ID = uuid.New().String()
+		f.Path = movePath(f.Path, fromDir, toDir)
+		out.Files = append(out.Files, f)
+	}
+	out.Children = make([]Session, len(s.Children))
+	for i, child := range s.Children {
+		out.Children[i] = remapSession(child, taskIDs[child.ID], id, fromDir, toDir)
+	}
+	return out
+}
+
+// pathKeys are the keys of tool call params and tool result metadata that
+// hold paths.
+var pathKeys = []string{"file_path", "path", "working_directory"}
+
+// relPath returns path relative to dir, if path is under dir.
+func relPath(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// movePath moves path from under fromDir to under toDir. Relative paths and
+// paths outside fromDir are left alone.
+func movePath(path, fromDir, toDir string) string {
+	if fromDir == "" || toDir == "" || fromDir == toDir || !filepath.IsAbs(path) {
+		return path
+	}
+	rel, ok := relPath(fromDir, path)
+	if !ok {
+		return path
+	}
+	return filepath.Join(toDir, rel)
+}
+
+// movePaths moves the paths of a JSON object, like tool call params or tool
+// result metadata, with movePath. Anything that isn't a JSON object is left
+// alone.
+func movePaths(data, fromDir, toDir string) string {
+	if data == "" || fromDir == "" || toDir == "" || fromDir == toDir {
+		return data
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return data
+	}
+	moved := false
+	for _, key := range pathKeys {
+		var path string
+		if err := json.Unmarshal(fields[key], &path); err != nil {
+			continue
+		}
+		if newPath := movePath(path, fromDir, toDir); newPath != path {
+			fields[key], _ = json.Marshal(newPath)
+			moved = true
+		}
+	}
+	if !moved {
+		return data
+	}
+	var out strings.Builder
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(fields); err != nil {
+		return data
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+func insertSession(ctx context.Context, q *db.Queries, s Session) error {
+	err := q.ImportSession(ctx, db.ImportSessionParams{
+		ID:               s.ID,
+		ParentSessionID:  sql.NullString{String: s.ParentSessionID, Valid: s.ParentSessionID != ""},
+		Title:            s.Title,
+		PromptTokens:     s.PromptTokens,
+		CompletionTokens: s.CompletionTokens,
+		Cost:             s.Cost,
+		SummaryMessageID: sql.NullString{String: s.SummaryMessageID, Valid: s.SummaryMessageID != ""},
+		UpdatedAt:        s.UpdatedAt,
+		CreatedAt:        s.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import session %q: %w", s.Title, err)
+	}
+
+	for _, msg := range s.Messages {
+		parts, err := message.MarshalParts(msg.Parts)
+		if err != nil {
+			return fmt.Errorf("failed to encode message: %w", err)
+		}
+		var finishedAt sql.NullInt64
+		for _, part := range msg.Parts {
+			if f, ok := part.(message.Finish); ok {
+				finishedAt = sql.NullInt64{Int64: f.Time, Valid: true}
+			}
+		}
+		err = q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:         msg.ID,
+			SessionID:  s.ID,
+			Role:       string(msg.Role),
+			Parts:      string(parts),
+			Model:      sql.NullString{String: msg.Model, Valid: true},
+			Provider:   sql.NullString{String: msg.Provider, Valid: msg.Provider != ""},
+			CreatedAt:  msg.CreatedAt,
+			UpdatedAt:  msg.UpdatedAt,
+			FinishedAt: finishedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import message: %w", err)
+		}
+	}
+
+	for _, f := range s.Files {
+		err := q.ImportFile(ctx, db.ImportFileParams{
+			ID:        f.ID,
+			SessionID: s.ID,
+			Path:      f.Path,
+			Content:   f.Content,
+			Version:   f.Version,
+			IsNew:     f.IsNew,
+			CreatedAt: f.CreatedAt,
+			UpdatedAt: f.UpdatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import file %s: %w", f.Path, err)
+		}
+	}
+
+	for _, child := range s.Children {
+		if err := insertSession(ctx, q, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package transcript
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	_, err := Read(strings.NewReader(`{"version": 2, "session": {"id": "a"}}`))
+	require.ErrorContains(t, err, "unsupported transcript version 2")
+
+	_, err = Read(strings.NewReader(`{"version": 1}`))
+	require.ErrorContains(t, err, "transcript has no session")
+
+	tr, err := Read(strings.NewReader(`{
+		"version": 1,
+		"session": {
+			"id": "a",
+			"title": "Imported",
+			"messages": [{"id": "m", "role": "user", "parts": [{"type": "text", "data": {"text": "hi"}}]}]
+		}
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, "Imported", tr.Session.Title)
+	require.Equal(t, Parts{message.TextContent{Text: "hi"}}, tr.Session.Messages[0].Parts)
+}
+
+func TestRemapSession(t *testing.T) {
+	t.Parallel()
+
+	from := filepath.FromSlash("/home/alice/project")
+	to := filepath.FromSlash("/home/bob/project")
+
+	orig := testTranscript().Session
+	orig.SummaryMessageID = "m2"
+	orig.Files = []File{
+		{ID: "f1", Path: filepath.Join(from, "main.go"), Content: "hello\n"},
+		{ID: "f2", Path: filepath.FromSlash("/etc/hosts")},
+	}
+	viewInput, err := json.Marshal(map[string]string{"file_path": filepath.Join(from, "logo.png")})
+	require.NoError(t, err)
+	bashMetadata, err := json.Marshal(map[string]any{"working_directory": filepath.Join(from, "cmd"), "output": "<ok>"})
+	require.NoError(t, err)
+	orig.Messages = append(orig.Messages,
+		Message{ID: "m4", Role: message.User, Parts: Parts{
+			message.TextContent{Text: "Look at the logo"},
+			message.BinaryContent{Path: filepath.Join(from, "logo.png"), MIMEType: "image/png"},
+		}},
+		Message{ID: "m5", Role: message.Assistant, Parts: Parts{
+			message.ToolCall{ID: "call-view", Name: "view", Input: string(viewInput), Finished: true},
+			message.ToolCall{ID: "call-ls", Name: "ls", Input: `{"path":"/etc"}`, Finished: true},
+		}},
+		Message{ID: "m6", Role: message.Tool, Parts: Parts{
+			message.ToolResult{ToolCallID: "call-bash", Name: "bash", Content: "ok", Metadata: string(bashMetadata)},
+		}},
+	)
+
+	s := remapSession(orig, "new", "", from, to)
+
+	require.Equal(t, "new", s.ID)
+	require.Empty(t, s.ParentSessionID)
+	require.Len(t, s.Messages, len(orig.Messages))
+	for i, msg := range s.Messages {
+		require.NotEqual(t, orig.Messages[i].ID, msg.ID)
+	}
+	require.Equal(t, s.Messages[1].ID, s.SummaryMessageID)
+
+	// Task sessions keep sharing their ID with the tool call that started
+	// them, other tool calls are left alone.
+	require.Len(t, s.Children, 1)
+	task := s.Children[0]
+	require.NotEqual(t, "call-agent", task.ID)
+	require.Equal(t, "new", task.ParentSessionID)
+	require.Equal(t, "call-edit", s.Messages[1].Parts[2].(message.ToolCall).ID)
+	require.Equal(t, task.ID, s.Messages[1].Parts[3].(message.ToolCall).ID)
+	require.Equal(t, "call-edit", s.Messages[2].Parts[0].(message.ToolResult).ToolCallID)
+	require.Equal(t, task.ID, s.Messages[2].Parts[1].(message.ToolResult).ToolCallID)
+
+	// Paths under the working directory are moved, others are left alone.
+	require.Equal(t, `{"file_path":"main.go"}`, s.Messages[1].Parts[2].(message.ToolCall).Input)
+	require.Equal(t, filepath.Join(to, "logo.png"), s.Messages[3].Parts[1].(message.BinaryContent).Path)
+	var params map[string]string
+	require.NoError(t, json.Unmarshal([]byte(s.Messages[4].Parts[0].(message.ToolCall).Input), &params))
+	require.Equal(t, filepath.Join(to, "logo.png"), params["file_path"])
+	require.Equal(t, `{"path":"/etc"}`, s.Messages[4].Parts[1].(message.ToolCall).Input)
+	var metadata map[string]string
+	require.NoError(t, json.Unmarshal([]byte(s.Messages[5].Parts[0].(message.ToolResult).Metadata), &metadata))
+	require.Equal(t, map[string]string{"working_directory": filepath.Join(to, "cmd"), "output": "<ok>"}, metadata)
+
+	// The history of files outside the working directory is dropped.
+	require.Len(t, s.Files, 1)
+	require.NotEqual(t, "f1", s.Files[0].ID)
+	require.Equal(t, filepath.Join(to, "main.go"), s.Files[0].Path)
+
+	// The original is not modified.
+	require.Equal(t, "call-agent", orig.Messages[1].Parts[3].(message.ToolCall).ID)
+	require.Equal(t, "f1", orig.Files[0].ID)
+}
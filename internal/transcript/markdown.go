@@ -0,0 +1,143 @@
+package transcript
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// WriteMarkdown writes the transcript as Markdown.
+func WriteMarkdown(w io.Writer, t Transcript) error {
+	bw := bufio.NewWriter(w)
+	view := newSessionView(&t.Session, 1)
+	writeMarkdownSession(bw, view)
+	fmt.Fprintf(bw, "---\n\n_Exported from Crush on %s._\n", formatTime(t.ExportedAt.Unix()))
+	return bw.Flush()
+}
+
+func writeMarkdownSession(w io.Writer, view sessionView) {
+	s := view.Session
+	title := s.Title
+	if view.Level > 1 {
+		title = "Task: " + title
+	}
+	fmt.Fprintf(w, "%s %s\n\n", heading(view.Level), title)
+	fmt.Fprintf(w, "- **Session:** `%s`\n", s.ID)
+	fmt.Fprintf(w, "- **Created:** %s\n", formatTime(s.CreatedAt))
+	fmt.Fprintf(w, "- **Updated:** %s\n", formatTime(s.UpdatedAt))
+	fmt.Fprintf(w, "- **Tokens:** %d prompt, %d completion\n", s.PromptTokens, s.CompletionTokens)
+	fmt.Fprintf(w, "- **Cost:** %s\n\n", formatCost(s.Cost))
+
+	for _, turn := range view.Turns {
+		writeMarkdownTurn(w, turn, view.Level+1)
+	}
+	for _, task := range view.Tasks {
+		writeMarkdownSession(w, task)
+	}
+}
+
+func writeMarkdownTurn(w io.Writer, turn turnView, level int) {
+	msg := turn.Message
+	fmt.Fprintf(w, "%s %s · %s\n\n", heading(level), roleTitle(turn), formatTime(msg.CreatedAt))
+
+	if turn.Reasoning != "" {
+		fmt.Fprintf(w, "<details>\n<summary>Reasoning</summary>\n\n%s\n\n</details>\n\n", quote(turn.Reasoning))
+	}
+	if turn.Text != "" {
+		fmt.Fprintf(w, "%s\n\n", turn.Text)
+	}
+	for _, image := range turn.Images {
+		fmt.Fprintf(w, "![image](%s)\n\n", image.URL)
+	}
+	for _, binary := range turn.Binaries {
+		fmt.Fprintf(w, "📎 `%s` (%s, %d bytes)\n\n", binary.Path, binary.MIMEType, len(binary.Data))
+	}
+	for _, call := range turn.Calls {
+		fmt.Fprintf(w, "**Tool call:** `%s`\n\n", call.Call.Name)
+		if call.Input != "" {
+			writeCodeBlock(w, "json", call.Input)
+		}
+		if call.Result != nil {
+			writeMarkdownResult(w, *call.Result)
+		}
+		if call.Diff != "" {
+			writeCodeBlock(w, "diff", call.Diff)
+		}
+		if call.Task != nil {
+			writeMarkdownSession(w, *call.Task)
+		}
+	}
+	for _, result := range turn.Results {
+		writeMarkdownResult(w, result)
+	}
+	if turn.Finish != nil {
+		fmt.Fprintf(w, "_Finished: %s", turn.Finish.Reason)
+		if turn.Finish.Message != "" {
+			fmt.Fprintf(w, " (%s)", turn.Finish.Message)
+		}
+		fmt.Fprint(w, "_\n\n")
+	}
+}
+
+func writeMarkdownResult(w io.Writer, result message.ToolResult) {
+	if result.IsError {
+		fmt.Fprintf(w, "**Result (error):**\n\n")
+	} else {
+		fmt.Fprintf(w, "**Result:**\n\n")
+	}
+	writeCodeBlock(w, "", result.Content)
+}
+
+// writeCodeBlock writes a fenced code block, with a fence longer than any
+// run of backticks in s.
+func writeCodeBlock(w io.Writer, lang, s string) {
+	fence := "```"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(w, "%s%s\n%s\n%s\n\n", fence, lang, strings.TrimRight(s, "\n"), fence)
+}
+
+func heading(level int) string {
+	return strings.Repeat("#", min(level, 6))
+}
+
+func quote(s string) string {
+	return "> " + strings.ReplaceAll(s, "\n", "\n> ")
+}
+
+func roleTitle(turn turnView) string {
+	msg := turn.Message
+	switch {
+	case turn.Summary:
+		return "Summary"
+	case msg.Role == message.Assistant && msg.Model != "":
+		if msg.Provider != "" {
+			return fmt.Sprintf("Assistant (%s via %s)", msg.Model, msg.Provider)
+		}
+		return fmt.Sprintf("Assistant (%s)", msg.Model)
+	case msg.Role == message.Assistant:
+		return "Assistant"
+	case msg.Role == message.Tool:
+		return "Tool"
+	case msg.Role == message.System:
+		return "System"
+	default:
+		return "User"
+	}
+}
+
+func formatTime(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("$%.4f", cost)
+}
@@ -0,0 +1,241 @@
+// Package transcript exports sessions, with their messages, file history and
+// task sessions, to Markdown, HTML and JSON.
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// Version is the version of the JSON format.
+const Version = 1
+
+// Format is an output format of a transcript.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatJSON     Format = "json"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{FormatMarkdown, FormatHTML, FormatJSON}
+
+// FormatFromPath guesses the format from the extension of a file name.
+func FormatFromPath(path string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return FormatMarkdown, true
+	case ".html", ".htm":
+		return FormatHTML, true
+	case ".json":
+		return FormatJSON, true
+	}
+	return "", false
+}
+
+// Extension returns the file extension of the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatHTML:
+		return ".html"
+	case FormatJSON:
+		return ".json"
+	default:
+		return ".md"
+	}
+}
+
+// Transcript is an exported session.
+type Transcript struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	// WorkingDir is the project directory the session was exported from.
+	WorkingDir string  `json:"working_dir,omitempty"`
+	Session    Session `json:"session"`
+}
+
+// Session is a session with its messages, file history and the task sessions
+// it started. Timestamps are Unix seconds, as stored in the database.
+type Session struct {
+	ID               string    `json:"id"`
+	ParentSessionID  string    `json:"parent_session_id,omitempty"`
+	Title            string    `json:"title"`
+	MessageCount     int64     `json:"message_count"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	SummaryMessageID string    `json:"summary_message_id,omitempty"`
+	Cost             float64   `json:"cost"`
+	CreatedAt        int64     `json:"created_at"`
+	UpdatedAt        int64     `json:"updated_at"`
+	Messages         []Message `json:"messages"`
+	Files            []File    `json:"files,omitempty"`
+	Children         []Session `json:"children,omitempty"`
+}
+
+// Message is a message of a session.
+type Message struct {
+	ID        string              `json:"id"`
+	Role      message.MessageRole `json:"role"`
+	Model     string              `json:"model,omitempty"`
+	Provider  string              `json:"provider,omitempty"`
+	Parts     Parts               `json:"parts"`
+	CreatedAt int64               `json:"created_at"`
+	UpdatedAt int64               `json:"updated_at"`
+}
+
+// Parts are the parts of a message. They are encoded like in the database, so
+// that every part type survives a round trip.
+type Parts []message.ContentPart
+
+func (p Parts) MarshalJSON() ([]byte, error) {
+	return message.MarshalParts(p)
+}
+
+func (p *Parts) UnmarshalJSON(data []byte) error {
+	parts, err := message.UnmarshalParts(data)
+	if err != nil {
+		return err
+	}
+	*p = parts
+	return nil
+}
+
+// File is a version of a file in the file history of a session.
+type File struct {
+	ID        string `json:"id"`
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	Version   int64  `json:"version"`
+	IsNew     bool   `json:"is_new,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// Load reads a session and everything it references from the database of the
+// project in workingDir.
+func Load(ctx context.Context, sessions session.Service, messages message.Service, files history.Service, workingDir, sessionID string) (Transcript, error) {
+	s, err := loadSession(ctx, sessions, messages, files, sessionID)
+	if err != nil {
+		return Transcript{}, err
+	}
+	return Transcript{
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		WorkingDir: workingDir,
+		Session:    s,
+	}, nil
+}
+
+func loadSession(ctx context.Context, sessions session.Service, messages message.Service, files history.Service, sessionID string) (Session, error) {
+	s, err := sessions.Get(ctx, sessionID)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+	}
+	msgs, err := messages.List(ctx, sessionID)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to list messages of session %s: %w", sessionID, err)
+	}
+	versions, err := files.ListBySession(ctx, sessionID)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to list files of session %s: %w", sessionID, err)
+	}
+	children, err := sessions.ListChildren(ctx, sessionID)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to list task sessions of session %s: %w", sessionID, err)
+	}
+
+	out := Session{
+		ID:               s.ID,
+		ParentSessionID:  s.ParentSessionID,
+		Title:            s.Title,
+		MessageCount:     s.MessageCount,
+		PromptTokens:     s.PromptTokens,
+		CompletionTokens: s.CompletionTokens,
+		SummaryMessageID: s.SummaryMessageID,
+		Cost:             s.Cost,
+		CreatedAt:        s.CreatedAt,
+		UpdatedAt:        s.UpdatedAt,
+		Messages:         make([]Message, len(msgs)),
+		Files:            make([]File, len(versions)),
+	}
+	for i, msg := range msgs {
+		out.Messages[i] = Message{
+			ID:        msg.ID,
+			Role:      msg.Role,
+			Model:     msg.Model,
+			Provider:  msg.Provider,
+			Parts:     msg.Parts,
+			CreatedAt: msg.CreatedAt,
+			UpdatedAt: msg.UpdatedAt,
+		}
+	}
+	for i, f := range versions {
+		out.Files[i] = File{
+			ID:        f.ID,
+			Path:      f.Path,
+			Content:   f.Content,
+			Version:   f.Version,
+			IsNew:     f.IsNew,
+			CreatedAt: f.CreatedAt,
+			UpdatedAt: f.UpdatedAt,
+		}
+	}
+	for _, child := range children {
+		// Title sessions only hold the request used to name the session.
+		if strings.HasPrefix(child.ID, "title-") {
+			continue
+		}
+		c, err := loadSession(ctx, sessions, messages, files, child.ID)
+		if err != nil {
+			return Session{}, err
+		}
+		out.Children = append(out.Children, c)
+	}
+	return out, nil
+}
+
+// Write writes the transcript in the given format.
+func Write(w io.Writer, t Transcript, format Format) error {
+	switch format {
+	case FormatMarkdown:
+		return WriteMarkdown(w, t)
+	case FormatHTML:
+		return WriteHTML(w, t)
+	case FormatJSON:
+		return WriteJSON(w, t)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// WriteFile writes the transcript to a file in the given format.
+func WriteFile(path string, t Transcript, format Format) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := Write(f, t, format); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// WriteJSON writes the transcript as indented JSON.
+func WriteJSON(w io.Writer, t Transcript) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
@@ -0,0 +1,163 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func testTranscript() Transcript {
+	return Transcript{
+		Version:    Version,
+		ExportedAt: time.Unix(1751364000, 0).UTC(),
+		Session: Session{
+			ID:               "session-1",
+			Title:            "Fix the greeting",
+			PromptTokens:     1200,
+			CompletionTokens: 300,
+			Cost:             0.0421,
+			CreatedAt:        1751360400,
+			UpdatedAt:        1751364000,
+			Messages: []Message{
+				{
+					ID:        "m1",
+					Role:      message.User,
+					Parts:     Parts{message.TextContent{Text: "Say world instead of <hello>"}},
+					CreatedAt: 1751360400,
+				},
+				{
+					ID:       "m2",
+					Role:     message.Assistant,
+					Model:    "gpt-4o",
+					Provider: "openai",
+					Parts: Parts{
+						message.ReasoningContent{Thinking: "Edit main.go"},
+						message.TextContent{Text: "Let me fix that."},
+						message.ToolCall{ID: "call-edit", Name: "edit", Input: `{"file_path":"main.go"}`, Finished: true},
+						message.ToolCall{ID: "call-agent", Name: "agent", Input: `{"prompt":"check"}`, Finished: true},
+						message.Finish{Reason: message.FinishReasonToolUse},
+					},
+					CreatedAt: 1751360410,
+				},
+				{
+					ID:   "m3",
+					Role: message.Tool,
+					Parts: Parts{
+						message.ToolResult{
+							ToolCallID: "call-edit",
+							Name:       "edit",
+							Content:    "File edited",
+							Metadata:   `{"additions":1,"removals":1,"old_content":"hello\n","new_content":"world\n"}`,
+						},
+						message.ToolResult{ToolCallID: "call-agent", Name: "agent", Content: "All good"},
+					},
+					CreatedAt: 1751360420,
+				},
+			},
+			Children: []Session{
+				{
+					ID:              "call-agent",
+					ParentSessionID: "session-1",
+					Title:           "Check the change",
+					Messages: []Message{
+						{
+							ID:        "c1",
+							Role:      message.User,
+							Parts:     Parts{message.TextContent{Text: "check"}},
+							CreatedAt: 1751360415,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPartsRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	parts := Parts{
+		message.ReasoningContent{Thinking: "thinking", Signature: "sig"},
+		message.TextContent{Text: "text"},
+		message.ImageURLContent{URL: "https://example.com/a.png"},
+		message.BinaryContent{Path: "a.png", MIMEType: "image/png", Data: []byte{1, 2, 3}},
+		message.ToolCall{ID: "call", Name: "view", Input: "{}", Finished: true},
+		message.ToolResult{ToolCallID: "call", Name: "view", Content: "ok", Metadata: "{}"},
+		message.Finish{Reason: message.FinishReasonEndTurn, Time: 42},
+	}
+
+	data, err := json.Marshal(parts)
+	require.NoError(t, err)
+
+	var decoded Parts
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, parts, decoded)
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMarkdown(&buf, testTranscript()))
+	out := buf.String()
+
+	require.Contains(t, out, "# Fix the greeting\n")
+	require.Contains(t, out, "- **Cost:** $0.0421\n")
+	require.Contains(t, out, "## User · 2025-07-01 09:00:00 UTC\n\nSay world instead of <hello>\n")
+	require.Contains(t, out, "## Assistant (gpt-4o via openai)")
+	require.Contains(t, out, "> Edit main.go")
+	require.Contains(t, out, "-hello")
+	require.Contains(t, out, "+world")
+	require.Contains(t, out, "### Task: Check the change\n")
+	require.NotContains(t, out, "## Tool")
+
+	// Results and task sessions follow the tool call that produced them.
+	edit := strings.Index(out, "**Tool call:** `edit`")
+	result := strings.Index(out, "File edited")
+	agent := strings.Index(out, "**Tool call:** `agent`")
+	task := strings.Index(out, "### Task: Check the change")
+	require.True(t, edit < result && result < agent && agent < task, out)
+}
+
+func TestWriteHTML(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTML(&buf, testTranscript()))
+	out := buf.String()
+
+	require.Contains(t, out, "<title>Fix the greeting</title>")
+	require.Contains(t, out, "Say world instead of &lt;hello&gt;")
+	require.Contains(t, out, `<span class="add">&#43;world</span>`)
+	require.Contains(t, out, "<summary>Task: Check the change</summary>")
+}
+
+func TestWriteCodeBlock(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	writeCodeBlock(&buf, "md", "```go\nfmt.Println()\n```\n")
+	require.Equal(t, "````md\n```go\nfmt.Println()\n```\n````\n\n", buf.String())
+}
+
+func TestFormatFromPath(t *testing.T) {
+	t.Parallel()
+
+	for path, want := range map[string]Format{
+		"session.md":   FormatMarkdown,
+		"session.HTML": FormatHTML,
+		"bundle.json":  FormatJSON,
+	} {
+		got, ok := FormatFromPath(path)
+		require.True(t, ok, path)
+		require.Equal(t, want, got, path)
+	}
+
+	_, ok := FormatFromPath("session.txt")
+	require.False(t, ok)
+}
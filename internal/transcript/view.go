@@ -0,0 +1,167 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// sessionView is a session arranged for rendering: tool results are placed
+// after their calls, and task sessions after the tool call that started them.
+type sessionView struct {
+	Session *Session
+	Level   int
+	Turns   []turnView
+	// Tasks are the task sessions not started by a tool call of the session.
+	Tasks []sessionView
+}
+
+type turnView struct {
+	Message   *Message
+	Summary   bool
+	Reasoning string
+	Text      string
+	Images    []message.ImageURLContent
+	Binaries  []message.BinaryContent
+	Calls     []callView
+	// Results are the tool results without a matching tool call.
+	Results []message.ToolResult
+	Finish  *message.Finish
+}
+
+type callView struct {
+	Call   message.ToolCall
+	Input  string
+	Result *message.ToolResult
+	Diff   string
+	Task   *sessionView
+}
+
+func newSessionView(s *Session, level int) sessionView {
+	view := sessionView{Session: s, Level: level}
+
+	results := make(map[string]message.ToolResult)
+	calls := make(map[string]bool)
+	for _, msg := range s.Messages {
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case message.ToolResult:
+				results[p.ToolCallID] = p
+			case message.ToolCall:
+				calls[p.ID] = true
+			}
+		}
+	}
+	tasks := make(map[string]*Session, len(s.Children))
+	for i := range s.Children {
+		tasks[s.Children[i].ID] = &s.Children[i]
+	}
+
+	for i := range s.Messages {
+		msg := &s.Messages[i]
+		turn := turnView{Message: msg, Summary: msg.ID == s.SummaryMessageID}
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case message.ReasoningContent:
+				turn.Reasoning = strings.TrimSpace(p.Thinking)
+			case message.TextContent:
+				turn.Text = strings.TrimSpace(p.Text)
+			case message.ImageURLContent:
+				turn.Images = append(turn.Images, p)
+			case message.BinaryContent:
+				turn.Binaries = append(turn.Binaries, p)
+			case message.ToolCall:
+				call := callView{Call: p, Input: prettyJSON(p.Input)}
+				if result, ok := results[p.ID]; ok {
+					call.Result = &result
+					call.Diff = resultDiff(p, result)
+				}
+				if task, ok := tasks[p.ID]; ok {
+					taskView := newSessionView(task, level+2)
+					call.Task = &taskView
+					delete(tasks, p.ID)
+				}
+				turn.Calls = append(turn.Calls, call)
+			case message.ToolResult:
+				if !calls[p.ToolCallID] {
+					turn.Results = append(turn.Results, p)
+				}
+			case message.Finish:
+				if isNotable(p.Reason) {
+					turn.Finish = &p
+				}
+			}
+		}
+		if turn.empty() {
+			continue
+		}
+		view.Turns = append(view.Turns, turn)
+	}
+
+	for i := range s.Children {
+		if task, ok := tasks[s.Children[i].ID]; ok {
+			view.Tasks = append(view.Tasks, newSessionView(task, level+1))
+		}
+	}
+	return view
+}
+
+func (t turnView) empty() bool {
+	return t.Reasoning == "" &&
+		t.Text == "" &&
+		len(t.Images) == 0 &&
+		len(t.Binaries) == 0 &&
+		len(t.Calls) == 0 &&
+		len(t.Results) == 0 &&
+		t.Finish == nil
+}
+
+// isNotable reports whether a finish reason is worth showing, as in the turn
+// did not end normally.
+func isNotable(reason message.FinishReason) bool {
+	switch reason {
+	case message.FinishReasonEndTurn, message.FinishReasonToolUse, "stop", "":
+		return false
+	}
+	return true
+}
+
+// resultDiff returns the unified diff of the change made by a tool call, from
+// the metadata of its result.
+func resultDiff(call message.ToolCall, result message.ToolResult) string {
+	if result.Metadata == "" || result.IsError {
+		return ""
+	}
+	var metadata struct {
+		Diff       string `json:"diff"`
+		OldContent string `json:"old_content"`
+		NewContent string `json:"new_content"`
+	}
+	if err := json.Unmarshal([]byte(result.Metadata), &metadata); err != nil {
+		return ""
+	}
+	if metadata.Diff != "" {
+		return strings.TrimRight(metadata.Diff, "\n")
+	}
+	if metadata.OldContent == metadata.NewContent {
+		return ""
+	}
+	var input struct {
+		FilePath string `json:"file_path"`
+	}
+	_ = json.Unmarshal([]byte(call.Input), &input)
+	unified, _, _ := diff.GenerateDiff(metadata.OldContent, metadata.NewContent, input.FilePath)
+	return strings.TrimRight(unified, "\n")
+}
+
+// prettyJSON indents s if it is JSON.
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return strings.TrimSpace(s)
+	}
+	return buf.String()
+}
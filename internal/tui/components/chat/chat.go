@@ -26,6 +26,14 @@ type SendMsg struct {
 	Attachments []message.Attachment
 }
 
+// ResendMsg sends an edited user message in place of the original one,
+// dropping everything after it.
+type ResendMsg struct {
+	SessionID string
+	MessageID string
+	SendMsg
+}
+
 type SessionSelectedMsg = session.Session
 
 type SessionClearedMsg struct{}
@@ -337,10 +345,25 @@ func (m *messageListCmp) handleMessageEvent(event pubsub.Event[message.Message])
 		case message.Tool:
 			return m.handleToolMessage(event.Payload)
 		}
+	case pubsub.DeletedEvent:
+		if event.Payload.SessionID != m.session.ID {
+			return nil
+		}
+		return m.handleDeletedMessage(event.Payload)
 	}
 	return nil
 }
 
+// handleDeletedMessage removes a deleted message and its tool calls from the
+// list.
+func (m *messageListCmp) handleDeletedMessage(msg message.Message) tea.Cmd {
+	cmds := []tea.Cmd{m.listCmp.DeleteItem(msg.ID)}
+	for _, tc := range msg.ToolCalls() {
+		cmds = append(cmds, m.listCmp.DeleteItem(tc.ID))
+	}
+	return tea.Batch(cmds...)
+}
+
 // messageExists checks if a message with the given ID already exists in the list.
 func (m *messageListCmp) messageExists(messageID string) bool {
 	items := m.listCmp.Items()
@@ -21,6 +21,7 @@ import (
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
+	"github.com/charmbracelet/crush/internal/tui/components/chat/messages"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
@@ -40,6 +41,7 @@ type Editor interface {
 	layout.Positional
 
 	SetSession(session session.Session) tea.Cmd
+	SetPlanMode(enabled bool)
 	IsCompletionsOpen() bool
 	HasAttachments() bool
 	Cursor() *tea.Cursor
@@ -60,6 +62,9 @@ type editorCmp struct {
 	deleteMode         bool
 	readyPlaceholder   string
 	workingPlaceholder string
+	planMode           bool
+	// editingMessageID is the user message being edited, if any.
+	editingMessageID string
 
 	keyMap EditorKeyMap
 
@@ -149,8 +154,10 @@ func (m *editorCmp) send() tea.Cmd {
 
 	m.textarea.Reset()
 	attachments := m.attachments
+	editingMessageID := m.editingMessageID
 
 	m.attachments = nil
+	m.editingMessageID = ""
 	if value == "" {
 		return nil
 	}
@@ -158,6 +165,17 @@ func (m *editorCmp) send() tea.Cmd {
 	// Change the placeholder when sending a new message.
 	m.randomizePlaceholders()
 
+	if editingMessageID != "" {
+		return util.CmdHandler(chat.ResendMsg{
+			SessionID: m.session.ID,
+			MessageID: editingMessageID,
+			SendMsg: chat.SendMsg{
+				Text:        value,
+				Attachments: attachments,
+			},
+		})
+	}
+
 	return tea.Batch(
 		util.CmdHandler(chat.SendMsg{
 			Text:        value,
@@ -218,6 +236,20 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case OpenEditorMsg:
 		m.textarea.SetValue(msg.Text)
 		m.textarea.MoveToEnd()
+	case messages.EditMsg:
+		m.editingMessageID = msg.Message.ID
+		m.textarea.SetValue(msg.Message.Content().Text)
+		m.textarea.MoveToEnd()
+		m.attachments = nil
+		for _, bc := range msg.Message.BinaryContent() {
+			m.attachments = append(m.attachments, message.Attachment{
+				FilePath: bc.Path,
+				FileName: filepath.Base(bc.Path),
+				MimeType: bc.MIMEType,
+				Content:  bc.Data,
+			})
+		}
+		return m, nil
 	case tea.PasteMsg:
 		path := strings.ReplaceAll(string(msg), "\\ ", " ")
 		// try to get an image
@@ -303,6 +335,12 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, m.openEditor(m.textarea.Value())
 		}
 		if key.Matches(msg, DeleteKeyMaps.Escape) {
+			if m.editingMessageID != "" && !m.deleteMode {
+				m.editingMessageID = ""
+				m.textarea.Reset()
+				m.attachments = nil
+				return m, util.ReportInfo("Edit canceled")
+			}
 			m.deleteMode = false
 			return m, nil
 		}
@@ -420,18 +458,28 @@ func (m *editorCmp) View() string {
 	} else {
 		m.textarea.Placeholder = m.readyPlaceholder
 	}
+	if m.planMode {
+		m.textarea.Placeholder = "Plan mode: what should we plan?"
+	}
 	if m.app.Permissions.SkipRequests() {
 		m.textarea.Placeholder = "Yolo mode!"
 	}
-	if len(m.attachments) == 0 {
+	if len(m.attachments) == 0 && m.editingMessageID == "" {
 		content := t.S().Base.Padding(1).Render(
 			m.textarea.View(),
 		)
 		return content
 	}
+	top := m.attachmentsContent()
+	if m.editingMessageID != "" {
+		top = lipgloss.JoinHorizontal(lipgloss.Left,
+			top,
+			t.S().Muted.MarginLeft(1).Render("Editing a previous message, enter to resend it, esc to cancel"),
+		)
+	}
 	content := t.S().Base.Padding(0, 1, 1, 1).Render(
 		lipgloss.JoinVertical(lipgloss.Top,
-			m.attachmentsContent(),
+			top,
 			m.textarea.View(),
 		),
 	)
@@ -525,10 +573,17 @@ func (c *editorCmp) Bindings() []key.Binding {
 // TODO: most likely we do not need to have the session here
 // we need to move some functionality to the page level
 func (c *editorCmp) SetSession(session session.Session) tea.Cmd {
+	if c.session.ID != session.ID {
+		c.editingMessageID = ""
+	}
 	c.session = session
 	return nil
 }
 
+func (c *editorCmp) SetPlanMode(enabled bool) {
+	c.planMode = enabled
+}
+
 func (c *editorCmp) IsCompletionsOpen() bool {
 	return c.isCompletionsOpen
 }
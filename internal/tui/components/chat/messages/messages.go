@@ -28,6 +28,32 @@ import (
 // CopyKey is the key binding for copying message content to the clipboard.
 var CopyKey = key.NewBinding(key.WithKeys("c", "y", "C", "Y"), key.WithHelp("c/y", "copy"))
 
+// RewindKey is the key binding for rewinding the session to a user message.
+var RewindKey = key.NewBinding(key.WithKeys("r", "R"), key.WithHelp("r", "rewind to here"))
+
+// RewindMsg asks to rewind a session to one of its user messages.
+type RewindMsg struct {
+	SessionID string
+	MessageID string
+}
+
+// ForkKey is the key binding for forking the session at a message.
+var ForkKey = key.NewBinding(key.WithKeys("F"), key.WithHelp("F", "fork from here"))
+
+// ForkMsg asks to fork a session at one of its messages.
+type ForkMsg struct {
+	SessionID string
+	MessageID string
+}
+
+// EditKey is the key binding for editing a user message and resending it.
+var EditKey = key.NewBinding(key.WithKeys("e", "E"), key.WithHelp("e", "edit"))
+
+// EditMsg asks to edit a user message in the editor.
+type EditMsg struct {
+	Message message.Message
+}
+
 // ClearSelectionKey is the key binding for clearing the current selection in the chat interface.
 var ClearSelectionKey = key.NewBinding(key.WithKeys("esc", "alt+esc"), key.WithHelp("esc", "clear selection"))
 
@@ -113,6 +139,21 @@ func (m *messageCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				util.ReportInfo("Message copied to clipboard"),
 			)
 		}
+		if key.Matches(msg, ForkKey) {
+			return m, util.CmdHandler(ForkMsg{
+				SessionID: m.message.SessionID,
+				MessageID: m.message.ID,
+			})
+		}
+		if key.Matches(msg, EditKey) && m.message.Role == message.User {
+			return m, util.CmdHandler(EditMsg{Message: m.message})
+		}
+		if key.Matches(msg, RewindKey) && m.message.Role == message.User {
+			return m, util.CmdHandler(RewindMsg{
+				SessionID: m.message.SessionID,
+				MessageID: m.message.ID,
+			})
+		}
 	}
 	return m, nil
 }
@@ -197,6 +238,11 @@ func (m *messageCmp) renderAssistantMessage() string {
 		details := t.S().Base.Foreground(t.FgSubtle).Width(m.textWidth() - 2).Render(finishedData.Details)
 		errorContent := fmt.Sprintf("%s\n\n%s", title, details)
 		return m.style().Render(errorContent)
+	} else if finished && content == "" && finishedData.Reason == message.FinishReasonBudgetExceeded {
+		tag := t.S().Base.Padding(0, 1).Background(t.Yellow).Foreground(t.BgOverlay).Render("BUDGET")
+		title := fmt.Sprintf("%s %s", tag, t.S().Base.Foreground(t.FgHalfMuted).Render(finishedData.Message))
+		details := t.S().Base.Foreground(t.FgSubtle).Width(m.textWidth() - 2).Render(finishedData.Details)
+		return m.style().Render(fmt.Sprintf("%s\n\n%s", title, details))
 	}
 
 	if thinkingContent != "" {
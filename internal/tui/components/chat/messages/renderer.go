@@ -174,6 +174,7 @@ func init() {
 	registry.register(tools.LSToolName, func() renderer { return lsRenderer{} })
 	registry.register(tools.SourcegraphToolName, func() renderer { return sourcegraphRenderer{} })
 	registry.register(tools.DiagnosticsToolName, func() renderer { return diagnosticsRenderer{} })
+	registry.register(tools.TodosToolName, func() renderer { return todosRenderer{} })
 	registry.register(agent.AgentToolName, func() renderer { return agentRenderer{} })
 }
 
@@ -589,6 +590,31 @@ func (dr diagnosticsRenderer) Render(v *toolCallCmp) string {
 	})
 }
 
+// -----------------------------------------------------------------------------
+//  Todos renderer
+// -----------------------------------------------------------------------------
+
+// todosRenderer handles todo list updates
+type todosRenderer struct {
+	baseRenderer
+}
+
+// Render displays the number of items changed and the resulting list
+func (tr todosRenderer) Render(v *toolCallCmp) string {
+	var params tools.TodosParams
+	var args []string
+	if err := tr.unmarshalParams(v.call.Input, &params); err == nil {
+		args = newParamBuilder().
+			addMain(fmt.Sprintf("%d items", len(params.Todos))).
+			addFlag("replace", params.Replace).
+			build()
+	}
+
+	return tr.renderWithParams(v, "Todos", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
 // -----------------------------------------------------------------------------
 //  Task renderer
 // -----------------------------------------------------------------------------
@@ -882,6 +908,8 @@ func prettifyToolName(name string) string {
 		return "List"
 	case tools.SourcegraphToolName:
 		return "Sourcegraph"
+	case tools.TodosToolName:
+		return "Todos"
 	case tools.ViewToolName:
 		return "View"
 	case tools.WriteToolName:
@@ -24,5 +24,5 @@ func queuePill(queue int, t *styles.Theme) string {
 		BorderForeground(t.BgOverlay).
 		PaddingLeft(1).
 		PaddingRight(1).
-		Render(fmt.Sprintf("%s %d Queued", allTriangles, queue))
+		Render(fmt.Sprintf("%s %d Queued %s", allTriangles, queue, t.S().Subtle.Render("ctrl+q to manage")))
 }
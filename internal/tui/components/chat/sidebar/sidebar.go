@@ -17,6 +17,7 @@ import (
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/todo"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
@@ -24,6 +25,7 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/logo"
 	lspcomponent "github.com/charmbracelet/crush/internal/tui/components/lsp"
 	"github.com/charmbracelet/crush/internal/tui/components/mcp"
+	"github.com/charmbracelet/crush/internal/tui/components/todos"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/crush/internal/version"
@@ -42,6 +44,7 @@ const LogoHeightBreakpoint = 30
 // Default maximum number of items to show in each section
 const (
 	DefaultMaxFilesShown = 10
+	DefaultMaxTodosShown = 8
 	DefaultMaxLSPsShown  = 8
 	DefaultMaxMCPsShown  = 8
 	MinItemsPerSection   = 2 // Minimum items to show per section
@@ -57,6 +60,11 @@ type SessionFilesMsg struct {
 	Files []SessionFile
 }
 
+type SessionTodosMsg struct {
+	SessionID string
+	Todos     []todo.Todo
+}
+
 type Sidebar interface {
 	util.Model
 	layout.Sizeable
@@ -73,12 +81,15 @@ type sidebarCmp struct {
 	compactMode   bool
 	history       history.Service
 	files         *csync.Map[string, SessionFile]
+	todoService   todo.Service
+	todos         []todo.Todo
 }
 
-func New(history history.Service, lspClients *csync.Map[string, *lsp.Client], compact bool) Sidebar {
+func New(history history.Service, todoService todo.Service, lspClients *csync.Map[string, *lsp.Client], compact bool) Sidebar {
 	return &sidebarCmp{
 		lspClients:  lspClients,
 		history:     history,
+		todoService: todoService,
 		compactMode: compact,
 		files:       csync.NewMap[string, SessionFile](),
 	}
@@ -96,11 +107,21 @@ func (m *sidebarCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.files.Set(file.FilePath, file)
 		}
 		return m, nil
+	case SessionTodosMsg:
+		if msg.SessionID == m.session.ID {
+			m.todos = msg.Todos
+		}
+		return m, nil
 
 	case chat.SessionClearedMsg:
 		m.session = session.Session{}
+		m.todos = nil
 	case pubsub.Event[history.File]:
 		return m, m.handleFileHistoryEvent(msg)
+	case pubsub.Event[todo.Todo]:
+		if msg.Payload.SessionID == m.session.ID {
+			return m, m.loadSessionTodos
+		}
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent {
 			if m.session.ID == msg.Payload.ID {
@@ -160,6 +181,9 @@ func (m *sidebarCmp) View() string {
 	} else {
 		// Vertical layout (default)
 		if m.session.ID != "" {
+			if len(m.todos) > 0 {
+				parts = append(parts, "", m.todosBlock())
+			}
 			parts = append(parts, "", m.filesBlock())
 		}
 		parts = append(parts,
@@ -176,6 +200,14 @@ func (m *sidebarCmp) View() string {
 }
 
 func (m *sidebarCmp) handleFileHistoryEvent(event pubsub.Event[history.File]) tea.Cmd {
+	if event.Type == pubsub.DeletedEvent {
+		// Versions are only deleted when a session is rewound, reload
+		// what is left.
+		if event.Payload.SessionID != m.session.ID {
+			return nil
+		}
+		return m.loadSessionFiles
+	}
 	return func() tea.Msg {
 		file := event.Payload
 		found := false
@@ -265,6 +297,21 @@ func (m *sidebarCmp) loadSessionFiles() tea.Msg {
 	}
 }
 
+func (m *sidebarCmp) loadSessionTodos() tea.Msg {
+	sessionID := m.session.ID
+	todos, err := m.todoService.List(context.Background(), sessionID)
+	if err != nil {
+		return util.InfoMsg{
+			Type: util.InfoTypeError,
+			Msg:  err.Error(),
+		}
+	}
+	return SessionTodosMsg{
+		SessionID: sessionID,
+		Todos:     todos,
+	}
+}
+
 func (m *sidebarCmp) SetSize(width, height int) tea.Cmd {
 	m.logo = m.logoBlock()
 	m.cwd = cwd()
@@ -320,6 +367,11 @@ func (m *sidebarCmp) calculateAvailableHeight() int {
 
 	usedHeight += 6 // 3 sections × 2 lines each (header + empty line)
 
+	if len(m.todos) > 0 {
+		usedHeight += 3 // Todos header, empty line and the one before it
+		usedHeight += min(len(m.todos), DefaultMaxTodosShown)
+	}
+
 	// Base padding
 	usedHeight += 2 // Top and bottom padding
 
@@ -477,6 +529,16 @@ func (m *sidebarCmp) filesBlock() string {
 	}, true)
 }
 
+func (m *sidebarCmp) todosBlock() string {
+	completed := len(m.todos) - len(todo.Open(m.todos))
+	return todos.RenderTodoBlock(m.todos, todos.RenderOptions{
+		MaxWidth:    m.getMaxWidth(),
+		MaxItems:    DefaultMaxTodosShown,
+		ShowSection: true,
+		SectionName: core.Section(fmt.Sprintf("Todos %d/%d", completed, len(m.todos)), m.getMaxWidth()),
+	}, true)
+}
+
 func (m *sidebarCmp) lspBlock() string {
 	// Limit the number of LSPs shown
 	_, maxLSPs, _ := m.getDynamicLimits()
@@ -598,7 +660,8 @@ func (s *sidebarCmp) currentModelBlock() string {
 // SetSession implements Sidebar.
 func (m *sidebarCmp) SetSession(session session.Session) tea.Cmd {
 	m.session = session
-	return m.loadSessionFiles
+	m.todos = nil
+	return tea.Batch(m.loadSessionFiles, m.loadSessionTodos)
 }
 
 // SetCompactMode sets the compact mode for the sidebar.
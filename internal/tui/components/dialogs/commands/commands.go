@@ -11,6 +11,7 @@ import (
 
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/llm/prompt"
+	"github.com/charmbracelet/crush/internal/transcript"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
@@ -71,9 +72,17 @@ type (
 	OpenReasoningDialogMsg struct{}
 	OpenExternalEditorMsg  struct{}
 	ToggleYoloModeMsg      struct{}
+	TogglePlanModeMsg      struct{}
 	CompactMsg             struct {
 		SessionID string
 	}
+	ExportSessionMsg struct {
+		SessionID string
+		Format    transcript.Format
+	}
+	SwitchAgentMsg struct {
+		AgentID string
+	}
 )
 
 func NewCommandDialog(sessionID string) CommandsDialog {
@@ -299,10 +308,53 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				})
 			},
 		})
+		commands = append(commands,
+			Command{
+				ID:          "export_session_markdown",
+				Title:       "Export Session as Markdown",
+				Description: "Save the transcript of the current session as Markdown",
+				Handler: func(cmd Command) tea.Cmd {
+					return util.CmdHandler(ExportSessionMsg{
+						SessionID: c.sessionID,
+						Format:    transcript.FormatMarkdown,
+					})
+				},
+			},
+			Command{
+				ID:          "export_session_html",
+				Title:       "Export Session as HTML",
+				Description: "Save the transcript of the current session as a standalone HTML page",
+				Handler: func(cmd Command) tea.Cmd {
+					return util.CmdHandler(ExportSessionMsg{
+						SessionID: c.sessionID,
+						Format:    transcript.FormatHTML,
+					})
+				},
+			},
+		)
 	}
 
-	// Add reasoning toggle for models that support it
 	cfg := config.Get()
+	if agents := cfg.SelectableAgents(); len(agents) > 1 {
+		for _, agent := range agents {
+			description := agent.Description
+			if description == "" {
+				description = "Use the " + agent.Name + " agent for the next prompts"
+			}
+			commands = append(commands, Command{
+				ID:          "switch_agent_" + agent.ID,
+				Title:       "Switch to " + agent.Name + " Agent",
+				Description: description,
+				Handler: func(cmd Command) tea.Cmd {
+					return util.CmdHandler(SwitchAgentMsg{
+						AgentID: agent.ID,
+					})
+				},
+			})
+		}
+	}
+
+	// Add reasoning toggle for models that support it
 	if agentCfg, ok := cfg.Agents["coder"]; ok {
 		providerCfg := cfg.GetProviderForModel(agentCfg.Model)
 		model := cfg.GetModelByType(agentCfg.Model)
@@ -387,6 +439,14 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				return util.CmdHandler(ToggleYoloModeMsg{})
 			},
 		},
+		{
+			ID:          "toggle_plan_mode",
+			Title:       "Toggle Plan Mode",
+			Description: "Only explore with read-only tools and agree on a plan before any edit",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(TogglePlanModeMsg{})
+			},
+		},
 		{
 			ID:          "toggle_help",
 			Title:       "Toggle Help",
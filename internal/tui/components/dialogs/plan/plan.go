@@ -0,0 +1,141 @@
+package plan
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const (
+	question                      = "Approve the plan and start implementing it?"
+	PlanDialogID dialogs.DialogID = "plan"
+)
+
+// ApprovePlanMsg is sent when the user approves the plan of a session.
+type ApprovePlanMsg struct {
+	SessionID string
+}
+
+// PlanDialog asks the user to approve the plan the agent came up with in
+// plan mode.
+type PlanDialog interface {
+	dialogs.DialogModel
+}
+
+type planDialogCmp struct {
+	wWidth  int
+	wHeight int
+
+	sessionID  string
+	selectedNo bool // true if "No" button is selected
+	keymap     KeyMap
+}
+
+// NewPlanDialog creates a new plan approval dialog for the given session.
+func NewPlanDialog(sessionID string) PlanDialog {
+	return &planDialogCmp{
+		sessionID: sessionID,
+		keymap:    DefaultKeymap(),
+	}
+}
+
+func (p *planDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles keyboard input for the plan dialog.
+func (p *planDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.wWidth = msg.Width
+		p.wHeight = msg.Height
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, p.keymap.LeftRight, p.keymap.Tab):
+			p.selectedNo = !p.selectedNo
+			return p, nil
+		case key.Matches(msg, p.keymap.EnterSpace):
+			if !p.selectedNo {
+				return p, p.approve()
+			}
+			return p, p.keepPlanning()
+		case key.Matches(msg, p.keymap.Yes):
+			return p, p.approve()
+		case key.Matches(msg, p.keymap.No, p.keymap.Close):
+			return p, p.keepPlanning()
+		}
+	}
+	return p, nil
+}
+
+func (p *planDialogCmp) approve() tea.Cmd {
+	return tea.Batch(
+		util.CmdHandler(dialogs.CloseDialogMsg{}),
+		util.CmdHandler(ApprovePlanMsg{SessionID: p.sessionID}),
+	)
+}
+
+func (p *planDialogCmp) keepPlanning() tea.Cmd {
+	return tea.Batch(
+		util.CmdHandler(dialogs.CloseDialogMsg{}),
+		util.ReportInfo("Still in plan mode, reply with the changes you want to the plan"),
+	)
+}
+
+// View renders the plan dialog with Yes/No buttons.
+func (p *planDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	baseStyle := t.S().Base
+	yesStyle := t.S().Text
+	noStyle := yesStyle
+
+	if p.selectedNo {
+		noStyle = noStyle.Foreground(t.White).Background(t.Secondary)
+		yesStyle = yesStyle.Background(t.BgSubtle)
+	} else {
+		yesStyle = yesStyle.Foreground(t.White).Background(t.Secondary)
+		noStyle = noStyle.Background(t.BgSubtle)
+	}
+
+	const horizontalPadding = 3
+	yesButton := yesStyle.PaddingLeft(horizontalPadding).Underline(true).Render("Y") +
+		yesStyle.PaddingRight(horizontalPadding).Render("es")
+	noButton := noStyle.PaddingLeft(horizontalPadding).Underline(true).Render("N") +
+		noStyle.PaddingRight(horizontalPadding).Render("o")
+
+	buttons := baseStyle.Width(lipgloss.Width(question)).Align(lipgloss.Right).Render(
+		lipgloss.JoinHorizontal(lipgloss.Center, yesButton, "  ", noButton),
+	)
+
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			question,
+			"",
+			buttons,
+		),
+	)
+
+	planDialogStyle := baseStyle.
+		Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+
+	return planDialogStyle.Render(content)
+}
+
+func (p *planDialogCmp) Position() (int, int) {
+	row := p.wHeight / 2
+	row -= 7 / 2
+	col := p.wWidth / 2
+	col -= (lipgloss.Width(question) + 4) / 2
+
+	return row, col
+}
+
+func (p *planDialogCmp) ID() dialogs.DialogID {
+	return PlanDialogID
+}
@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+)
+
+type KeyMap struct {
+	Next,
+	Previous,
+	MoveDown,
+	MoveUp,
+	Edit,
+	Delete,
+	Promote,
+	Save,
+	Close key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Next: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓", "next"),
+		),
+		Previous: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑", "previous"),
+		),
+		MoveDown: key.NewBinding(
+			key.WithKeys("shift+down", "J"),
+			key.WithHelp("shift+↓", "move down"),
+		),
+		MoveUp: key.NewBinding(
+			key.WithKeys("shift+up", "K"),
+			key.WithHelp("shift+↑", "move up"),
+		),
+		Edit: key.NewBinding(
+			key.WithKeys("e", "E"),
+			key.WithHelp("e", "edit"),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("d", "D", "delete"),
+			key.WithHelp("d", "delete"),
+		),
+		Promote: key.NewBinding(
+			key.WithKeys("enter", "p", "P"),
+			key.WithHelp("enter", "send now"),
+		),
+		Save: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "save"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc", "alt+esc"),
+			key.WithHelp("esc", "close"),
+		),
+	}
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Next,
+		k.Previous,
+		k.MoveDown,
+		k.MoveUp,
+		k.Edit,
+		k.Delete,
+		k.Promote,
+		k.Close,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	m := [][]key.Binding{}
+	slice := k.KeyBindings()
+	for i := 0; i < len(slice); i += 4 {
+		end := min(i+4, len(slice))
+		m = append(m, slice[i:end])
+	}
+	return m
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		key.NewBinding(
+			key.WithKeys("shift+up", "shift+down"),
+			key.WithHelp("shift+↑↓", "move"),
+		),
+		k.Edit,
+		k.Delete,
+		k.Promote,
+		k.Close,
+	}
+}
+
+// editKeyMap is the help shown while a prompt is being edited.
+type editKeyMap struct {
+	KeyMap
+}
+
+// ShortHelp implements help.KeyMap.
+func (k editKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		k.Save,
+		key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "discard"),
+		),
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k editKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
@@ -0,0 +1,281 @@
+package queue
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	QueueDialogID dialogs.DialogID = "queue"
+
+	// maxVisiblePrompts is the number of prompts listed at once.
+	maxVisiblePrompts = 10
+)
+
+// QueueDialog lists the prompts queued for a busy session and lets the user
+// edit, reorder, delete or send them right away.
+type QueueDialog interface {
+	dialogs.DialogModel
+}
+
+type queueDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+
+	agent     agent.Service
+	sessionID string
+	prompts   []agent.QueuedPrompt
+	selected  int
+
+	editing bool
+	input   textinput.Model
+
+	positionRow int
+	positionCol int
+
+	keyMap KeyMap
+	help   help.Model
+}
+
+// NewQueueDialog creates a new dialog for the prompt queue of the given
+// session.
+func NewQueueDialog(coderAgent agent.Service, sessionID string) QueueDialog {
+	t := styles.CurrentTheme()
+	input := textinput.New()
+	input.SetVirtualCursor(false)
+	input.Prompt = ""
+	input.SetStyles(t.S().TextInput)
+	return &queueDialogCmp{
+		agent:     coderAgent,
+		sessionID: sessionID,
+		prompts:   coderAgent.ListQueue(sessionID),
+		input:     input,
+		keyMap:    DefaultKeyMap(),
+		help:      help.New(),
+	}
+}
+
+func (q *queueDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (q *queueDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		q.wWidth = msg.Width
+		q.wHeight = msg.Height
+		q.width = min(int(float64(q.wWidth)*0.8), 80)
+		q.positionCol = q.wWidth/2 - q.width/2
+	case agent.AgentEvent:
+		// The agent takes the queue when it starts a new turn.
+		return q, q.refresh()
+	case tea.KeyPressMsg:
+		if q.editing {
+			return q, q.updateEditing(msg)
+		}
+		switch {
+		case key.Matches(msg, q.keyMap.Next):
+			q.selected = min(q.selected+1, len(q.prompts)-1)
+		case key.Matches(msg, q.keyMap.Previous):
+			q.selected = max(q.selected-1, 0)
+		case key.Matches(msg, q.keyMap.MoveDown):
+			return q, q.move(1)
+		case key.Matches(msg, q.keyMap.MoveUp):
+			return q, q.move(-1)
+		case key.Matches(msg, q.keyMap.Edit):
+			if prompt, ok := q.selectedPrompt(); ok {
+				q.editing = true
+				q.input.SetWidth(q.width - 6 - len(q.numbering(q.selected)))
+				q.input.SetValue(prompt.Content)
+				q.input.CursorEnd()
+				return q, q.input.Focus()
+			}
+		case key.Matches(msg, q.keyMap.Delete):
+			if prompt, ok := q.selectedPrompt(); ok {
+				if err := q.agent.RemoveQueuedPrompt(q.sessionID, prompt.ID); err != nil {
+					return q, util.ReportError(err)
+				}
+				return q, q.refresh()
+			}
+		case key.Matches(msg, q.keyMap.Promote):
+			if prompt, ok := q.selectedPrompt(); ok {
+				if err := q.agent.PromoteQueuedPrompt(q.sessionID, prompt.ID); err != nil {
+					return q, util.ReportError(err)
+				}
+				return q, util.CmdHandler(dialogs.CloseDialogMsg{})
+			}
+		case key.Matches(msg, q.keyMap.Close):
+			return q, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+	}
+	return q, nil
+}
+
+func (q *queueDialogCmp) updateEditing(msg tea.KeyPressMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, q.keyMap.Save):
+		q.editing = false
+		q.input.Blur()
+		prompt, ok := q.selectedPrompt()
+		if !ok {
+			return nil
+		}
+		content := strings.TrimSpace(q.input.Value())
+		if content == "" {
+			return util.ReportWarn("A queued prompt can't be empty, delete it instead")
+		}
+		if err := q.agent.UpdateQueuedPrompt(q.sessionID, prompt.ID, content); err != nil {
+			return util.ReportError(err)
+		}
+		return q.refresh()
+	case key.Matches(msg, q.keyMap.Close):
+		q.editing = false
+		q.input.Blur()
+		return nil
+	}
+	var cmd tea.Cmd
+	q.input, cmd = q.input.Update(msg)
+	return cmd
+}
+
+func (q *queueDialogCmp) move(offset int) tea.Cmd {
+	prompt, ok := q.selectedPrompt()
+	if !ok {
+		return nil
+	}
+	if err := q.agent.MoveQueuedPrompt(q.sessionID, prompt.ID, offset); err != nil {
+		return util.ReportError(err)
+	}
+	return q.refresh()
+}
+
+func (q *queueDialogCmp) selectedPrompt() (agent.QueuedPrompt, bool) {
+	if q.selected < 0 || q.selected >= len(q.prompts) {
+		return agent.QueuedPrompt{}, false
+	}
+	return q.prompts[q.selected], true
+}
+
+// refresh reloads the queue, keeping the selected prompt selected. The dialog
+// closes once there is nothing left in the queue.
+func (q *queueDialogCmp) refresh() tea.Cmd {
+	selected, _ := q.selectedPrompt()
+	q.prompts = q.agent.ListQueue(q.sessionID)
+	if len(q.prompts) == 0 {
+		q.editing = false
+		return util.CmdHandler(dialogs.CloseDialogMsg{})
+	}
+	idx := slices.IndexFunc(q.prompts, func(p agent.QueuedPrompt) bool {
+		return p.ID == selected.ID
+	})
+	if idx == -1 {
+		// The prompt being edited was sent in the meantime.
+		q.editing = false
+		q.input.Blur()
+		idx = min(q.selected, len(q.prompts)-1)
+	}
+	q.selected = idx
+	return nil
+}
+
+func (q *queueDialogCmp) numbering(i int) string {
+	return fmt.Sprintf("%d. ", i+1)
+}
+
+func (q *queueDialogCmp) visibleRange() (int, int) {
+	start := max(0, min(q.selected-maxVisiblePrompts/2, len(q.prompts)-maxVisiblePrompts))
+	end := min(start+maxVisiblePrompts, len(q.prompts))
+	return start, end
+}
+
+func (q *queueDialogCmp) renderPrompts() string {
+	t := styles.CurrentTheme()
+
+	start, end := q.visibleRange()
+	lines := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		numbering := q.numbering(i)
+		style := t.S().Text.Width(q.width - 4).PaddingLeft(1)
+		if i == q.selected && q.editing {
+			lines = append(lines, style.Render(numbering+q.input.View()))
+			continue
+		}
+		if i == q.selected {
+			style = style.Foreground(t.White).Background(t.Primary)
+		}
+		content := strings.Join(strings.Fields(q.prompts[i].Content), " ")
+		lines = append(lines, style.Render(numbering+ansi.Truncate(content, q.width-5-len(numbering), "…")))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (q *queueDialogCmp) View() string {
+	t := styles.CurrentTheme()
+
+	var helpView string
+	if q.editing {
+		helpView = q.help.View(editKeyMap{q.keyMap})
+	} else {
+		helpView = q.help.View(q.keyMap)
+	}
+	title := fmt.Sprintf("Queued Prompts (%d)", len(q.prompts))
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		core.Title(title, q.width-4),
+		"",
+		q.renderPrompts(),
+		"",
+		helpView,
+	)
+
+	dialog := t.S().Base.
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Width(q.width).
+		Render(content)
+
+	q.positionRow = max(0, q.wHeight/2-lipgloss.Height(dialog)/2)
+	return dialog
+}
+
+// Cursor implements util.Cursor.
+func (q *queueDialogCmp) Cursor() *tea.Cursor {
+	if !q.editing {
+		return nil
+	}
+	cursor := q.input.Cursor()
+	if cursor == nil {
+		return nil
+	}
+	start, _ := q.visibleRange()
+	// Border, title and the blank line below it.
+	cursor.Y += q.positionRow + 3 + q.selected - start
+	// Border, padding and the numbering of the prompt.
+	cursor.X += q.positionCol + 3 + len(q.numbering(q.selected))
+	return cursor
+}
+
+// ID implements QueueDialog.
+func (q *queueDialogCmp) ID() dialogs.DialogID {
+	return QueueDialogID
+}
+
+// Position implements QueueDialog.
+func (q *queueDialogCmp) Position() (int, int) {
+	return q.positionRow, q.positionCol
+}
@@ -0,0 +1,136 @@
+package rewind
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+)
+
+type KeyMap struct {
+	Left,
+	Right,
+	Tab,
+	Select,
+	Rewind,
+	Fork,
+	Cancel,
+	NextFile,
+	PrevFile,
+	KeepFiles,
+	ToggleDiffMode,
+	ScrollDown,
+	ScrollUp,
+	ScrollLeft,
+	ScrollRight key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Left: key.NewBinding(
+			key.WithKeys("left", "h"),
+			key.WithHelp("←", "previous"),
+		),
+		Right: key.NewBinding(
+			key.WithKeys("right", "l"),
+			key.WithHelp("→", "next"),
+		),
+		Tab: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "switch"),
+		),
+		Select: key.NewBinding(
+			key.WithKeys("enter", "ctrl+y"),
+			key.WithHelp("enter", "confirm"),
+		),
+		Rewind: key.NewBinding(
+			key.WithKeys("r", "R"),
+			key.WithHelp("r", "rewind"),
+		),
+		Fork: key.NewBinding(
+			key.WithKeys("f", "F"),
+			key.WithHelp("f", "fork"),
+		),
+		Cancel: key.NewBinding(
+			key.WithKeys("c", "C", "esc", "alt+esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+		NextFile: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓", "next file"),
+		),
+		PrevFile: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑", "previous file"),
+		),
+		KeepFiles: key.NewBinding(
+			key.WithKeys("space"),
+			key.WithHelp("space", "keep/restore files"),
+		),
+		ToggleDiffMode: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "toggle diff mode"),
+		),
+		ScrollDown: key.NewBinding(
+			key.WithKeys("shift+down", "J"),
+			key.WithHelp("shift+↓", "scroll down"),
+		),
+		ScrollUp: key.NewBinding(
+			key.WithKeys("shift+up", "K"),
+			key.WithHelp("shift+↑", "scroll up"),
+		),
+		ScrollLeft: key.NewBinding(
+			key.WithKeys("shift+left", "H"),
+			key.WithHelp("shift+←", "scroll left"),
+		),
+		ScrollRight: key.NewBinding(
+			key.WithKeys("shift+right", "L"),
+			key.WithHelp("shift+→", "scroll right"),
+		),
+	}
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Left,
+		k.Right,
+		k.Tab,
+		k.Select,
+		k.Rewind,
+		k.Fork,
+		k.Cancel,
+		k.NextFile,
+		k.PrevFile,
+		k.KeepFiles,
+		k.ToggleDiffMode,
+		k.ScrollDown,
+		k.ScrollUp,
+		k.ScrollLeft,
+		k.ScrollRight,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	m := [][]key.Binding{}
+	slice := k.KeyBindings()
+	for i := 0; i < len(slice); i += 4 {
+		end := min(i+4, len(slice))
+		m = append(m, slice[i:end])
+	}
+	return m
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		key.NewBinding(
+			key.WithKeys("up", "down"),
+			key.WithHelp("↑↓", "choose file"),
+		),
+		k.KeepFiles,
+		k.ToggleDiffMode,
+		key.NewBinding(
+			key.WithKeys("shift+left", "shift+down", "shift+up", "shift+right"),
+			key.WithHelp("shift+←↓↑→", "scroll"),
+		),
+	}
+}
@@ -0,0 +1,352 @@
+package rewind
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/components/chat"
+	"github.com/charmbracelet/crush/internal/tui/components/chat/messages"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	RewindDialogID dialogs.DialogID = "rewind"
+
+	// maxVisibleFiles is the number of files listed above the diff.
+	maxVisibleFiles = 5
+)
+
+// ApplyRewindMsg is sent when the user confirms a rewind.
+type ApplyRewindMsg struct {
+	Rewind app.Rewind
+	// Fork continues in a copy of the session instead of truncating it.
+	Fork bool
+	// Resend is sent once the session is rewound, if set.
+	Resend *chat.SendMsg
+}
+
+// RewindDialog previews what rewinding a session changes and asks the user
+// to confirm it.
+type RewindDialog interface {
+	dialogs.DialogModel
+}
+
+type rewindDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+	height  int
+
+	rewind          app.Rewind
+	resend          *chat.SendMsg
+	keepFiles       bool
+	selectedFile    int
+	selectedOption  int // 0: Rewind, 1: Fork, 2: Cancel
+	contentViewPort viewport.Model
+
+	// Diff view state
+	diffSplitMode *bool // nil means split when the dialog is wide enough
+	diffXOffset   int
+	diffYOffset   int
+
+	positionRow int
+	positionCol int
+
+	keyMap KeyMap
+}
+
+// NewRewindDialog creates a new dialog for the given rewind. When resend is
+// set, the user message the session is rewound to is replaced with it.
+func NewRewindDialog(r app.Rewind, resend *chat.SendMsg) RewindDialog {
+	return &rewindDialogCmp{
+		rewind:          r,
+		resend:          resend,
+		contentViewPort: viewport.New(),
+		keyMap:          DefaultKeyMap(),
+	}
+}
+
+func (r *rewindDialogCmp) Init() tea.Cmd {
+	return r.contentViewPort.Init()
+}
+
+func (r *rewindDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		r.wWidth = msg.Width
+		r.wHeight = msg.Height
+		r.setSize()
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, r.keyMap.Right, r.keyMap.Tab):
+			r.selectedOption = (r.selectedOption + 1) % 3
+		case key.Matches(msg, r.keyMap.Left):
+			r.selectedOption = (r.selectedOption + 2) % 3
+		case key.Matches(msg, r.keyMap.Select):
+			switch r.selectedOption {
+			case 0:
+				return r, r.apply(false)
+			case 1:
+				return r, r.apply(true)
+			}
+			return r, r.cancel()
+		case key.Matches(msg, r.keyMap.Rewind):
+			return r, r.apply(false)
+		case key.Matches(msg, r.keyMap.Fork):
+			return r, r.apply(true)
+		case key.Matches(msg, r.keyMap.Cancel):
+			return r, r.cancel()
+		case key.Matches(msg, r.keyMap.NextFile):
+			if r.selectedFile < len(r.rewind.Files)-1 {
+				r.selectedFile++
+				r.diffXOffset, r.diffYOffset = 0, 0
+			}
+		case key.Matches(msg, r.keyMap.PrevFile):
+			if r.selectedFile > 0 {
+				r.selectedFile--
+				r.diffXOffset, r.diffYOffset = 0, 0
+			}
+		case key.Matches(msg, r.keyMap.KeepFiles):
+			r.keepFiles = !r.keepFiles
+		case key.Matches(msg, r.keyMap.ToggleDiffMode):
+			split := !r.useDiffSplitMode()
+			r.diffSplitMode = &split
+		case key.Matches(msg, r.keyMap.ScrollDown):
+			r.diffYOffset++
+		case key.Matches(msg, r.keyMap.ScrollUp):
+			r.diffYOffset = max(0, r.diffYOffset-1)
+		case key.Matches(msg, r.keyMap.ScrollLeft):
+			r.diffXOffset = max(0, r.diffXOffset-5)
+		case key.Matches(msg, r.keyMap.ScrollRight):
+			r.diffXOffset += 5
+		}
+	}
+	return r, nil
+}
+
+func (r *rewindDialogCmp) apply(fork bool) tea.Cmd {
+	rewind := r.rewind
+	if r.keepFiles {
+		rewind.Files = nil
+	}
+	return tea.Batch(
+		util.CmdHandler(dialogs.CloseDialogMsg{}),
+		util.CmdHandler(ApplyRewindMsg{Rewind: rewind, Fork: fork, Resend: r.resend}),
+	)
+}
+
+// cancel closes the dialog. An edited message goes back to the editor so the
+// edit isn't lost.
+func (r *rewindDialogCmp) cancel() tea.Cmd {
+	if r.resend == nil {
+		return util.CmdHandler(dialogs.CloseDialogMsg{})
+	}
+	edited := r.rewind.Message
+	edited.Parts = []message.ContentPart{message.TextContent{Text: r.resend.Text}}
+	for _, attachment := range r.resend.Attachments {
+		edited.Parts = append(edited.Parts, message.BinaryContent{
+			Path:     attachment.FilePath,
+			MIMEType: attachment.MimeType,
+			Data:     attachment.Content,
+		})
+	}
+	return tea.Batch(
+		util.CmdHandler(dialogs.CloseDialogMsg{}),
+		util.CmdHandler(messages.EditMsg{Message: edited}),
+	)
+}
+
+func (r *rewindDialogCmp) useDiffSplitMode() bool {
+	if r.diffSplitMode != nil {
+		return *r.diffSplitMode
+	}
+	return r.width >= 140
+}
+
+func (r *rewindDialogCmp) setSize() {
+	r.width = min(int(float64(r.wWidth)*0.8), 180)
+	r.height = int(float64(r.wHeight) * 0.8)
+	if len(r.rewind.Files) == 0 {
+		r.width = min(r.width, 80)
+	}
+	r.positionCol = r.wWidth/2 - r.width/2
+}
+
+func (r *rewindDialogCmp) renderHeader() string {
+	t := styles.CurrentTheme()
+
+	prompt := r.rewind.Message.Content().Text
+	promptKey := t.S().Muted.Render("Rewind to")
+	if r.resend != nil {
+		prompt = r.resend.Text
+		promptKey = t.S().Muted.Render("Resend as")
+	}
+	prompt, _, _ = strings.Cut(strings.TrimSpace(prompt), "\n")
+	promptValue := t.S().Text.
+		Width(r.width - 4 - lipgloss.Width(promptKey)).
+		Render(" " + ansi.Truncate(prompt, r.width-5-lipgloss.Width(promptKey), "…"))
+
+	summary := fmt.Sprintf(
+		"Drops %d messages from the conversation and restores %d files.",
+		len(r.rewind.Messages),
+		len(r.rewind.Files),
+	)
+	switch {
+	case r.keepFiles:
+		summary = fmt.Sprintf(
+			"Drops %d messages from the conversation and keeps the files as they are.",
+			len(r.rewind.Messages),
+		)
+	case len(r.rewind.Files) == 0:
+		summary = fmt.Sprintf(
+			"Drops %d messages from the conversation. No files changed since.",
+			len(r.rewind.Messages),
+		)
+	}
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Left, promptKey, promptValue),
+		"",
+		t.S().Muted.Width(r.width-4).Render(summary),
+	)
+}
+
+func (r *rewindDialogCmp) renderFiles() string {
+	t := styles.CurrentTheme()
+
+	start := max(0, min(r.selectedFile-maxVisibleFiles/2, len(r.rewind.Files)-maxVisibleFiles))
+	end := min(start+maxVisibleFiles, len(r.rewind.Files))
+	lines := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		file := r.rewind.Files[i]
+		text := fsext.PrettyPath(file.Path)
+		if file.Delete {
+			text += " (deleted)"
+		}
+		style := t.S().Text.Width(r.width - 4).PaddingLeft(1)
+		if r.keepFiles {
+			style = t.S().Subtle.Width(r.width - 4).PaddingLeft(1).Strikethrough(true)
+		}
+		if i == r.selectedFile {
+			style = style.Foreground(t.White).Background(t.Primary)
+		}
+		lines = append(lines, style.Render(ansi.Truncate(text, r.width-5, "…")))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (r *rewindDialogCmp) renderDiff(height int) string {
+	file := r.rewind.Files[r.selectedFile]
+	path := fsext.PrettyPath(file.Path)
+	formatter := core.DiffFormatter().
+		Before(path, file.Current).
+		After(path, file.Content).
+		Height(height).
+		Width(r.width - 4).
+		XOffset(r.diffXOffset).
+		YOffset(r.diffYOffset)
+	if r.useDiffSplitMode() {
+		formatter = formatter.Split()
+	} else {
+		formatter = formatter.Unified()
+	}
+	return formatter.String()
+}
+
+func (r *rewindDialogCmp) renderButtons() string {
+	t := styles.CurrentTheme()
+	buttons := []core.ButtonOpts{
+		{
+			Text:           "Rewind",
+			UnderlineIndex: 0, // "R"
+			Selected:       r.selectedOption == 0,
+		},
+		{
+			Text:           "Fork",
+			UnderlineIndex: 0, // "F"
+			Selected:       r.selectedOption == 1,
+		},
+		{
+			Text:           "Cancel",
+			UnderlineIndex: 0, // "C"
+			Selected:       r.selectedOption == 2,
+		},
+	}
+	return t.S().Base.AlignHorizontal(lipgloss.Right).Width(r.width - 4).Render(
+		core.SelectableButtons(buttons, "  "),
+	)
+}
+
+func (r *rewindDialogCmp) View() string {
+	t := styles.CurrentTheme()
+
+	header := r.renderHeader()
+	buttons := r.renderButtons()
+	title := "Rewind Session"
+	if r.resend != nil {
+		title = "Edit Message"
+	}
+	strs := []string{
+		core.Title(title, r.width-4),
+		"",
+		header,
+	}
+
+	if len(r.rewind.Files) > 0 {
+		files := r.renderFiles()
+		helpView := help.New().View(r.keyMap)
+		const minDiffHeight = 5
+		// Title, header, files, buttons and help, plus the blank lines
+		// around them and the border.
+		chrome := lipgloss.Height(header) + lipgloss.Height(files) + 11
+		diffHeight := max(minDiffHeight, r.height-chrome)
+		r.contentViewPort.SetWidth(r.width - 4)
+		r.contentViewPort.SetHeight(diffHeight)
+		r.contentViewPort.SetContent(r.renderDiff(diffHeight))
+		strs = append(strs,
+			"",
+			files,
+			"",
+			t.S().Base.Render(r.contentViewPort.View()),
+			"",
+			buttons,
+			"",
+			helpView,
+		)
+	} else {
+		strs = append(strs, "", buttons)
+	}
+
+	dialog := t.S().Base.
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Width(r.width).
+		Render(lipgloss.JoinVertical(lipgloss.Top, strs...))
+
+	r.positionRow = max(0, r.wHeight/2-lipgloss.Height(dialog)/2)
+	return dialog
+}
+
+// ID implements RewindDialog.
+func (r *rewindDialogCmp) ID() dialogs.DialogID {
+	return RewindDialogID
+}
+
+// Position implements RewindDialog.
+func (r *rewindDialogCmp) Position() (int, int) {
+	return r.positionRow, r.positionCol
+}
@@ -1,6 +1,8 @@
 package sessions
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/bubbles/v2/help"
 	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
@@ -45,11 +47,14 @@ func NewSessionDialogCmp(sessions []session.Session, selectedID string) SessionD
 	listKeyMap.DownOneItem = keyMap.Next
 	listKeyMap.UpOneItem = keyMap.Previous
 
-	items := make([]list.CompletionItem[session.Session], len(sessions))
-	if len(sessions) > 0 {
-		for i, session := range sessions {
-			items[i] = list.NewCompletionItem(session.Title, session, list.WithCompletionID(session.ID))
+	tree := sessionTree(sessions)
+	items := make([]list.CompletionItem[session.Session], len(tree))
+	for i, node := range tree {
+		title := node.session.Title
+		if node.depth > 0 {
+			title = strings.Repeat("  ", node.depth-1) + "└ " + title
 		}
+		items[i] = list.NewCompletionItem(title, node.session, list.WithCompletionID(node.session.ID))
 	}
 
 	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
@@ -179,3 +184,40 @@ func (s *sessionDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
 func (s *sessionDialogCmp) ID() dialogs.DialogID {
 	return SessionsDialogID
 }
+
+type sessionTreeNode struct {
+	session session.Session
+	depth   int
+}
+
+// sessionTree orders sessions so that forks come right after the session they
+// were forked from, one level deeper. Sessions forked from a session that is
+// gone are shown at the top level.
+func sessionTree(sessions []session.Session) []sessionTreeNode {
+	ids := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		ids[s.ID] = true
+	}
+	var roots []session.Session
+	forks := make(map[string][]session.Session)
+	for _, s := range sessions {
+		if s.ForkedFromSessionID == "" || !ids[s.ForkedFromSessionID] {
+			roots = append(roots, s)
+			continue
+		}
+		forks[s.ForkedFromSessionID] = append(forks[s.ForkedFromSessionID], s)
+	}
+
+	nodes := make([]sessionTreeNode, 0, len(sessions))
+	var add func(s session.Session, depth int)
+	add = func(s session.Session, depth int) {
+		nodes = append(nodes, sessionTreeNode{session: s, depth: depth})
+		for _, fork := range forks[s.ID] {
+			add(fork, depth+1)
+		}
+	}
+	for _, s := range roots {
+		add(s, 0)
+	}
+	return nodes
+}
@@ -0,0 +1,39 @@
+package sessions
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionTree(t *testing.T) {
+	t.Parallel()
+
+	sessions := []session.Session{
+		{ID: "fork-of-fork", ForkedFromSessionID: "fork"},
+		{ID: "newest"},
+		{ID: "fork", ForkedFromSessionID: "root"},
+		{ID: "orphan", ForkedFromSessionID: "deleted"},
+		{ID: "root"},
+		{ID: "second-fork", ForkedFromSessionID: "root"},
+	}
+
+	type node struct {
+		id    string
+		depth int
+	}
+	var got []node
+	for _, n := range sessionTree(sessions) {
+		got = append(got, node{n.session.ID, n.depth})
+	}
+	require.Equal(t, []node{
+		{"newest", 0},
+		// Sessions forked from a deleted session are shown at the top level.
+		{"orphan", 0},
+		{"root", 0},
+		{"fork", 1},
+		{"fork-of-fork", 2},
+		{"second-fork", 1},
+	}, got)
+}
@@ -0,0 +1,104 @@
+package todos
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/charmbracelet/crush/internal/todo"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+)
+
+// RenderOptions contains options for rendering todo lists.
+type RenderOptions struct {
+	MaxWidth    int
+	MaxItems    int
+	ShowSection bool
+	SectionName string
+}
+
+// visibleRange returns the range of todos to show when at most maxItems fit.
+// Completed items at the top of the list make room for the ones still to do.
+func visibleRange(todos []todo.Todo, maxItems int) (start, end int) {
+	if maxItems <= 0 || len(todos) <= maxItems {
+		return 0, len(todos)
+	}
+	for len(todos)-start > maxItems && todos[start].Status == todo.StatusCompleted {
+		start++
+	}
+	return start, min(start+maxItems, len(todos))
+}
+
+// RenderTodoList renders a list of todo items with the given options.
+func RenderTodoList(todos []todo.Todo, opts RenderOptions) []string {
+	t := styles.CurrentTheme()
+	todoList := []string{}
+
+	if opts.ShowSection {
+		sectionName := opts.SectionName
+		if sectionName == "" {
+			sectionName = "Todos"
+		}
+		section := t.S().Subtle.Render(sectionName)
+		todoList = append(todoList, section, "")
+	}
+
+	if len(todos) == 0 {
+		todoList = append(todoList, t.S().Base.Foreground(t.Border).Render("None"))
+		return todoList
+	}
+
+	start, end := visibleRange(todos, opts.MaxItems)
+	for _, item := range todos[start:end] {
+		icon := t.S().Base.Foreground(t.FgSubtle).Render(styles.ToolPending)
+		titleColor := t.FgMuted
+		switch item.Status {
+		case todo.StatusInProgress:
+			icon = t.S().Base.Foreground(t.Yellow).Render(styles.ToolPending)
+			titleColor = t.FgBase
+		case todo.StatusCompleted:
+			icon = t.S().Base.Foreground(t.Success).Render(styles.CheckIcon)
+			titleColor = t.FgSubtle
+		}
+		todoList = append(todoList,
+			core.Status(
+				core.StatusOpts{
+					Icon:       icon,
+					Title:      ansi.Truncate(item.Content, opts.MaxWidth-lipgloss.Width(icon)-2, "…"),
+					TitleColor: titleColor,
+				},
+				opts.MaxWidth,
+			),
+		)
+	}
+
+	return todoList
+}
+
+// RenderTodoBlock renders a complete todo block with optional truncation indicator.
+func RenderTodoBlock(todos []todo.Todo, opts RenderOptions, showTruncationIndicator bool) string {
+	t := styles.CurrentTheme()
+	todoList := RenderTodoList(todos, opts)
+
+	// Add truncation indicator if needed
+	if showTruncationIndicator && opts.MaxItems > 0 && len(todos) > opts.MaxItems {
+		_, end := visibleRange(todos, opts.MaxItems)
+		remaining := len(todos) - end
+		switch {
+		case remaining == 1:
+			todoList = append(todoList, t.S().Base.Foreground(t.FgMuted).Render("…"))
+		case remaining > 1:
+			todoList = append(todoList,
+				t.S().Base.Foreground(t.FgSubtle).Render(fmt.Sprintf("…and %d more", remaining)),
+			)
+		}
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, todoList...)
+	if opts.MaxWidth > 0 {
+		return lipgloss.NewStyle().Width(opts.MaxWidth).Render(content)
+	}
+	return content
+}
@@ -13,10 +13,12 @@ import (
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/llm/agent"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/todo"
 	"github.com/charmbracelet/crush/internal/tui/components/anim"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/editor"
@@ -31,6 +33,8 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/plan"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/queue"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/reasoning"
 	"github.com/charmbracelet/crush/internal/tui/page"
 	"github.com/charmbracelet/crush/internal/tui/styles"
@@ -115,6 +119,7 @@ type chatPage struct {
 	splashFullScreen bool
 	isOnboarding     bool
 	isProjectInit    bool
+	planMode         bool
 }
 
 func New(app *app.App) ChatPage {
@@ -122,7 +127,7 @@ func New(app *app.App) ChatPage {
 		app:         app,
 		keyMap:      DefaultKeyMap(),
 		header:      header.New(app.LSPClients),
-		sidebar:     sidebar.New(app.History, app.LSPClients, false),
+		sidebar:     sidebar.New(app.History, app.Todos, app.LSPClients, false),
 		chat:        chat.New(app),
 		editor:      editor.New(app),
 		splash:      splash.New(),
@@ -266,6 +271,16 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		u, cmd := p.editor.Update(msg)
 		p.editor = u.(editor.Editor)
 		return p, cmd
+	case messages.EditMsg:
+		if p.app.CoderAgent != nil && p.app.CoderAgent.IsSessionBusy(p.session.ID) {
+			return p, util.ReportWarn("Agent is working, please wait before editing a message...")
+		}
+		if p.focusedPane == PanelTypeChat {
+			p.changeFocus()
+		}
+		u, cmd := p.editor.Update(msg)
+		p.editor = u.(editor.Editor)
+		return p, cmd
 	case pubsub.Event[session.Session]:
 		u, cmd := p.header.Update(msg)
 		p.header = u.(header.Header)
@@ -314,12 +329,24 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		return p, tea.Batch(cmds...)
+	case commands.TogglePlanModeMsg:
+		p.setPlanMode(!p.planMode)
+		if p.planMode {
+			return p, util.ReportInfo("Plan mode on: the agent will only explore and come up with a plan")
+		}
+		return p, util.ReportInfo("Plan mode off")
+	case plan.ApprovePlanMsg:
+		if msg.SessionID != p.session.ID {
+			return p, nil
+		}
+		p.setPlanMode(false)
+		return p, p.sendMessage(agent.PlanApprovedPrompt, nil)
 	case commands.ToggleYoloModeMsg:
 		// update the editor style
 		u, cmd := p.editor.Update(msg)
 		p.editor = u.(editor.Editor)
 		return p, cmd
-	case pubsub.Event[history.File], sidebar.SessionFilesMsg:
+	case pubsub.Event[history.File], sidebar.SessionFilesMsg, pubsub.Event[todo.Todo], sidebar.SessionTodosMsg:
 		u, cmd := p.sidebar.Update(msg)
 		p.sidebar = u.(sidebar.Sidebar)
 		cmds = append(cmds, cmd)
@@ -393,6 +420,12 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, p.keyMap.Details):
 			p.toggleDetails()
 			return p, nil
+		case key.Matches(msg, p.keyMap.Queue):
+			if p.hasQueuedPrompts() {
+				return p, util.CmdHandler(dialogs.OpenDialogMsg{
+					Model: queue.NewQueueDialog(p.app.CoderAgent, p.session.ID),
+				})
+			}
 		}
 
 		switch p.focusedPane {
@@ -712,7 +745,7 @@ func (p *chatPage) cancel() tea.Cmd {
 		return nil
 	}
 
-	if p.app.CoderAgent != nil && p.app.CoderAgent.QueuedPrompts(p.session.ID) > 0 {
+	if p.hasQueuedPrompts() {
 		p.app.CoderAgent.ClearQueue(p.session.ID)
 		return nil
 	}
@@ -720,6 +753,10 @@ func (p *chatPage) cancel() tea.Cmd {
 	return cancelTimerCmd()
 }
 
+func (p *chatPage) hasQueuedPrompts() bool {
+	return p.session.ID != "" && p.app.CoderAgent != nil && p.app.CoderAgent.QueuedPrompts(p.session.ID) > 0
+}
+
 func (p *chatPage) setShowDetails(show bool) {
 	p.showingDetails = show
 	p.header.SetDetailsOpen(p.showingDetails)
@@ -749,6 +786,7 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 	if p.app.CoderAgent == nil {
 		return util.ReportError(fmt.Errorf("coder agent is not initialized"))
 	}
+	p.app.CoderAgent.SetPlanMode(session.ID, p.planMode)
 	_, err := p.app.CoderAgent.Run(context.Background(), session.ID, text, attachments...)
 	if err != nil {
 		return util.ReportError(err)
@@ -757,6 +795,14 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 	return tea.Batch(cmds...)
 }
 
+func (p *chatPage) setPlanMode(enabled bool) {
+	p.planMode = enabled
+	p.editor.SetPlanMode(enabled)
+	if p.session.ID != "" && p.app.CoderAgent != nil {
+		p.app.CoderAgent.SetPlanMode(p.session.ID, enabled)
+	}
+}
+
 func (p *chatPage) Bindings() []key.Binding {
 	bindings := []key.Binding{
 		p.keyMap.NewSession,
@@ -772,6 +818,9 @@ func (p *chatPage) Bindings() []key.Binding {
 		}
 		bindings = append([]key.Binding{cancelBinding}, bindings...)
 	}
+	if p.hasQueuedPrompts() {
+		bindings = append(bindings, p.keyMap.Queue)
+	}
 
 	switch p.focusedPane {
 	case PanelTypeChat:
@@ -894,18 +943,17 @@ func (p *chatPage) Help() help.KeyMap {
 					key.WithHelp("esc", "press again to cancel"),
 				)
 			}
-			if p.app.CoderAgent != nil && p.app.CoderAgent.QueuedPrompts(p.session.ID) > 0 {
+			busyBindings := []key.Binding{}
+			if p.hasQueuedPrompts() {
 				cancelBinding = key.NewBinding(
 					key.WithKeys("esc", "alt+esc"),
 					key.WithHelp("esc", "clear queue"),
 				)
+				busyBindings = append(busyBindings, p.keyMap.Queue)
 			}
-			shortList = append(shortList, cancelBinding)
-			fullList = append(fullList,
-				[]key.Binding{
-					cancelBinding,
-				},
-			)
+			busyBindings = append([]key.Binding{cancelBinding}, busyBindings...)
+			shortList = append(shortList, busyBindings...)
+			fullList = append(fullList, busyBindings)
 		}
 		globalBindings := []key.Binding{}
 		// we are in a session
@@ -999,6 +1047,9 @@ func (p *chatPage) Help() help.KeyMap {
 				},
 				[]key.Binding{
 					messages.CopyKey,
+					messages.EditKey,
+					messages.RewindKey,
+					messages.ForkKey,
 					messages.ClearSelectionKey,
 				},
 			)
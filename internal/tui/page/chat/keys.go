@@ -10,6 +10,7 @@ type KeyMap struct {
 	Cancel        key.Binding
 	Tab           key.Binding
 	Details       key.Binding
+	Queue         key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
@@ -34,5 +35,9 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+d"),
 			key.WithHelp("ctrl+d", "toggle details"),
 		),
+		Queue: key.NewBinding(
+			key.WithKeys("ctrl+q"),
+			key.WithHelp("ctrl+q", "queue"),
+		),
 	}
 }
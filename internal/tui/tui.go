@@ -12,9 +12,11 @@ import (
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/event"
 	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	cmpChat "github.com/charmbracelet/crush/internal/tui/components/chat"
+	"github.com/charmbracelet/crush/internal/tui/components/chat/messages"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/splash"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
@@ -26,7 +28,10 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/permissions"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/plan"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/queue"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/quit"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/rewind"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/sessions"
 	"github.com/charmbracelet/crush/internal/tui/page"
 	"github.com/charmbracelet/crush/internal/tui/page/chat"
@@ -182,10 +187,61 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, util.CmdHandler(dialogs.OpenDialogMsg{
 			Model: compact.NewCompactDialogCmp(a.app.CoderAgent, msg.SessionID, true),
 		})
+	case commands.ExportSessionMsg:
+		return a, func() tea.Msg {
+			path, err := a.app.ExportSession(context.Background(), msg.SessionID, msg.Format)
+			if err != nil {
+				return util.InfoMsg{
+					Type: util.InfoTypeError,
+					Msg:  "Failed to export session: " + err.Error(),
+				}
+			}
+			return util.InfoMsg{
+				Type: util.InfoTypeInfo,
+				Msg:  "Session exported to " + path,
+			}
+		}
+	// Rewind
+	case messages.RewindMsg:
+		return a, a.openRewindDialog(msg.SessionID, msg.MessageID, nil)
+	case cmpChat.ResendMsg:
+		return a, a.openRewindDialog(msg.SessionID, msg.MessageID, &msg.SendMsg)
+	case rewind.ApplyRewindMsg:
+		sess, err := a.app.ApplyRewind(context.Background(), msg.Rewind, msg.Fork)
+		if err != nil {
+			return a, util.ReportError(fmt.Errorf("failed to rewind session: %w", err))
+		}
+		info := fmt.Sprintf("Rewound session, restored %d files", len(msg.Rewind.Files))
+		if msg.Fork {
+			info = fmt.Sprintf("Forked session, restored %d files", len(msg.Rewind.Files))
+		}
+		cmds := []tea.Cmd{
+			util.CmdHandler(cmpChat.SessionSelectedMsg(sess)),
+			util.ReportInfo(info),
+		}
+		if msg.Resend != nil {
+			cmds = append(cmds, util.CmdHandler(*msg.Resend))
+		}
+		return a, tea.Sequence(cmds...)
+	// Fork
+	case messages.ForkMsg:
+		fork, err := a.app.ForkSession(context.Background(), msg.SessionID, msg.MessageID)
+		if err != nil {
+			return a, util.ReportError(fmt.Errorf("failed to fork session: %w", err))
+		}
+		return a, tea.Batch(
+			util.CmdHandler(cmpChat.SessionSelectedMsg(fork)),
+			util.ReportInfo("Forked session, now in "+fork.Title),
+		)
 	case commands.QuitMsg:
 		return a, util.CmdHandler(dialogs.OpenDialogMsg{
 			Model: quit.NewQuitDialog(),
 		})
+	case commands.SwitchAgentMsg:
+		if err := a.app.SwitchAgent(msg.AgentID); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, util.ReportInfo("Switched to the " + a.app.AgentConfig().Name + " agent")
 	case commands.ToggleYoloModeMsg:
 		a.app.Permissions.SetSkipRequests(!a.app.Permissions.SkipRequests())
 	case commands.ToggleHelpMsg:
@@ -257,7 +313,8 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		payload := msg.Payload
 
 		// Forward agent events to dialogs
-		if a.dialog.HasDialogs() && a.dialog.ActiveDialogID() == compact.CompactDialogID {
+		activeDialog := a.dialog.ActiveDialogID()
+		if a.dialog.HasDialogs() && (activeDialog == compact.CompactDialogID || activeDialog == queue.QueueDialogID) {
 			u, dialogCmd := a.dialog.Update(payload)
 			if model, ok := u.(dialogs.DialogCmp); ok {
 				a.dialog = model
@@ -266,6 +323,20 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, dialogCmd)
 		}
 
+		if payload.Type == agent.AgentEventTypeBudgetWarning && payload.SessionID == a.selectedSessionID {
+			cmds = append(cmds, util.ReportWarn(payload.Progress))
+		}
+
+		// Ask to approve the plan the agent came up with in plan mode
+		if payload.Done && payload.Type == agent.AgentEventTypeResponse &&
+			payload.Message.SessionID == a.selectedSessionID &&
+			payload.Message.FinishReason() == message.FinishReasonEndTurn &&
+			a.app.CoderAgent.IsPlanMode(payload.Message.SessionID) {
+			cmds = append(cmds, util.CmdHandler(dialogs.OpenDialogMsg{
+				Model: plan.NewPlanDialog(payload.Message.SessionID),
+			}))
+		}
+
 		// Handle auto-compact logic
 		if payload.Done && payload.Type == agent.AgentEventTypeResponse && a.selectedSessionID != "" {
 			// Get current session to check token usage
@@ -408,6 +479,26 @@ func (a *appModel) handleWindowResize(width, height int) tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// openRewindDialog previews rewinding a session to one of its user messages,
+// replacing it with resend when set.
+func (a *appModel) openRewindDialog(sessionID, messageID string, resend *cmpChat.SendMsg) tea.Cmd {
+	if a.app.CoderAgent != nil && a.app.CoderAgent.IsSessionBusy(sessionID) {
+		return util.ReportWarn("Agent is busy, please wait before rewinding the session...")
+	}
+	return func() tea.Msg {
+		r, err := a.app.PlanRewind(context.Background(), sessionID, messageID)
+		if err != nil {
+			return util.InfoMsg{
+				Type: util.InfoTypeError,
+				Msg:  "Failed to rewind session: " + err.Error(),
+			}
+		}
+		return dialogs.OpenDialogMsg{
+			Model: rewind.NewRewindDialog(r, resend),
+		}
+	}
+}
+
 // handleKeyPressMsg processes keyboard input and routes to appropriate handlers.
 func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 	// Check this first as the user should be able to quit no matter what.
@@ -0,0 +1,122 @@
+package usage
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GroupBy is a dimension usage can be aggregated by.
+type GroupBy string
+
+const (
+	GroupByDay      GroupBy = "day"
+	GroupByModel    GroupBy = "model"
+	GroupByProvider GroupBy = "provider"
+)
+
+// GroupBys lists the supported dimensions.
+var GroupBys = []GroupBy{GroupByDay, GroupByModel, GroupByProvider}
+
+// ParseGroupBy parses a comma separated list of dimensions.
+func ParseGroupBy(s string) ([]GroupBy, error) {
+	var groupBy []GroupBy
+	for part := range strings.SplitSeq(s, ",") {
+		g := GroupBy(strings.TrimSpace(part))
+		if g == "" {
+			continue
+		}
+		if !slices.Contains(GroupBys, g) {
+			return nil, fmt.Errorf("invalid group %q, must be one of day, model or provider", g)
+		}
+		if !slices.Contains(groupBy, g) {
+			groupBy = append(groupBy, g)
+		}
+	}
+	return groupBy, nil
+}
+
+// Row is the aggregated usage of a group. Only the fields of the dimensions
+// grouped by are set.
+type Row struct {
+	Day                 string  `json:"day,omitempty"`
+	Model               string  `json:"model,omitempty"`
+	Provider            string  `json:"provider,omitempty"`
+	Requests            int64   `json:"requests"`
+	Sessions            int64   `json:"sessions"`
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	Cost                float64 `json:"cost"`
+}
+
+// Report is usage aggregated by some dimensions.
+type Report struct {
+	Rows  []Row `json:"rows"`
+	Total Row   `json:"total"`
+}
+
+// Aggregate groups records by the given dimensions, with days in loc. Rows
+// are sorted by day, model and provider.
+func Aggregate(records []Record, groupBy []GroupBy, loc *time.Location) Report {
+	type key struct{ day, model, provider string }
+
+	rows := make(map[key]*Row)
+	sessions := make(map[key]map[string]struct{})
+	totalSessions := make(map[string]struct{})
+	var order []key
+	var total Row
+
+	for _, r := range records {
+		var k key
+		for _, g := range groupBy {
+			switch g {
+			case GroupByDay:
+				k.day = time.Unix(r.CreatedAt, 0).In(loc).Format(time.DateOnly)
+			case GroupByModel:
+				k.model = r.Model
+			case GroupByProvider:
+				k.provider = r.Provider
+			}
+		}
+		row, ok := rows[k]
+		if !ok {
+			row = &Row{Day: k.day, Model: k.model, Provider: k.provider}
+			rows[k] = row
+			sessions[k] = make(map[string]struct{})
+			order = append(order, k)
+		}
+		row.add(r)
+		total.add(r)
+		sessions[k][r.SessionID] = struct{}{}
+		totalSessions[r.SessionID] = struct{}{}
+	}
+
+	report := Report{Rows: make([]Row, 0, len(order)), Total: total}
+	for _, k := range order {
+		row := rows[k]
+		row.Sessions = int64(len(sessions[k]))
+		report.Rows = append(report.Rows, *row)
+	}
+	report.Total.Sessions = int64(len(totalSessions))
+	slices.SortFunc(report.Rows, func(a, b Row) int {
+		return cmp.Or(
+			strings.Compare(a.Day, b.Day),
+			strings.Compare(a.Model, b.Model),
+			strings.Compare(a.Provider, b.Provider),
+		)
+	})
+	return report
+}
+
+func (row *Row) add(r Record) {
+	row.Requests++
+	row.InputTokens += r.InputTokens
+	row.OutputTokens += r.OutputTokens
+	row.CacheCreationTokens += r.CacheCreationTokens
+	row.CacheReadTokens += r.CacheReadTokens
+	row.Cost += r.Cost
+}
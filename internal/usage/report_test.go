@@ -0,0 +1,76 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	t.Parallel()
+
+	groupBy, err := ParseGroupBy("day, model,day")
+	require.NoError(t, err)
+	require.Equal(t, []GroupBy{GroupByDay, GroupByModel}, groupBy)
+
+	groupBy, err = ParseGroupBy("")
+	require.NoError(t, err)
+	require.Empty(t, groupBy)
+
+	_, err = ParseGroupBy("week")
+	require.Error(t, err)
+}
+
+func TestAggregate(t *testing.T) {
+	t.Parallel()
+
+	day1 := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC).Unix()
+	day2 := time.Date(2025, 7, 2, 23, 30, 0, 0, time.UTC).Unix()
+	records := []Record{
+		{SessionID: "a", Model: "gpt-4o", Provider: "openai", InputTokens: 100, OutputTokens: 10, Cost: 1, CreatedAt: day2},
+		{SessionID: "a", Model: "claude", Provider: "anthropic", InputTokens: 200, OutputTokens: 20, CacheReadTokens: 5, Cost: 2, CreatedAt: day1},
+		{SessionID: "b", Model: "gpt-4o", Provider: "openai", InputTokens: 300, OutputTokens: 30, Cost: 3, CreatedAt: day1},
+	}
+
+	t.Run("no grouping", func(t *testing.T) {
+		t.Parallel()
+		report := Aggregate(records, nil, time.UTC)
+		want := Row{Requests: 3, Sessions: 2, InputTokens: 600, OutputTokens: 60, CacheReadTokens: 5, Cost: 6}
+		require.Equal(t, []Row{want}, report.Rows)
+		require.Equal(t, want, report.Total)
+	})
+
+	t.Run("by day and model", func(t *testing.T) {
+		t.Parallel()
+		report := Aggregate(records, []GroupBy{GroupByDay, GroupByModel}, time.UTC)
+		require.Equal(t, []Row{
+			{Day: "2025-07-01", Model: "claude", Requests: 1, Sessions: 1, InputTokens: 200, OutputTokens: 20, CacheReadTokens: 5, Cost: 2},
+			{Day: "2025-07-01", Model: "gpt-4o", Requests: 1, Sessions: 1, InputTokens: 300, OutputTokens: 30, Cost: 3},
+			{Day: "2025-07-02", Model: "gpt-4o", Requests: 1, Sessions: 1, InputTokens: 100, OutputTokens: 10, Cost: 1},
+		}, report.Rows)
+	})
+
+	t.Run("by provider", func(t *testing.T) {
+		t.Parallel()
+		report := Aggregate(records, []GroupBy{GroupByProvider}, time.UTC)
+		require.Equal(t, []Row{
+			{Provider: "anthropic", Requests: 1, Sessions: 1, InputTokens: 200, OutputTokens: 20, CacheReadTokens: 5, Cost: 2},
+			{Provider: "openai", Requests: 2, Sessions: 2, InputTokens: 400, OutputTokens: 40, Cost: 4},
+		}, report.Rows)
+	})
+
+	t.Run("days in location", func(t *testing.T) {
+		t.Parallel()
+		loc := time.FixedZone("UTC+2", 2*60*60)
+		report := Aggregate(records[:1], []GroupBy{GroupByDay}, loc)
+		require.Equal(t, "2025-07-03", report.Rows[0].Day)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+		report := Aggregate(nil, []GroupBy{GroupByDay}, time.UTC)
+		require.Empty(t, report.Rows)
+		require.Equal(t, Row{}, report.Total)
+	})
+}
@@ -0,0 +1,146 @@
+// Package usage records the tokens and cost of every provider request, so
+// spend can be reported across sessions.
+package usage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/google/uuid"
+)
+
+// Kind is what a provider request was made for.
+type Kind string
+
+const (
+	// KindAgent is a request of the agent loop, the turns of a prompt.
+	KindAgent Kind = "agent"
+	// KindTitle is a request generating the title of a session.
+	KindTitle Kind = "title"
+	// KindSummarize is a request summarizing a session.
+	KindSummarize Kind = "summarize"
+)
+
+// Record is the usage of a single provider request.
+type Record struct {
+	ID string
+	// SessionID is the top-level session the request was made for: requests
+	// of task sessions count toward the session that started them.
+	SessionID string
+	// MessageID is the assistant message the request produced, if any.
+	MessageID           string
+	Kind                Kind
+	Model               string
+	Provider            string
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+	Cost                float64
+	CreatedAt           int64
+}
+
+// SessionTotal is what the requests of a session spent in total. Prompt
+// tokens include the tokens written to the cache, and completion tokens the
+// tokens read from it, like the token counts of sessions. Agent requests only
+// count the turns of the agent loop, not the title and summary requests.
+type SessionTotal struct {
+	AgentRequests    int64
+	Tokens           int64
+	PromptTokens     int64
+	CompletionTokens int64
+	Cost             float64
+}
+
+// Since returns what was spent since the session had spent start.
+func (t SessionTotal) Since(start SessionTotal) SessionTotal {
+	return SessionTotal{
+		AgentRequests:    t.AgentRequests - start.AgentRequests,
+		Tokens:           t.Tokens - start.Tokens,
+		PromptTokens:     t.PromptTokens - start.PromptTokens,
+		CompletionTokens: t.CompletionTokens - start.CompletionTokens,
+		Cost:             t.Cost - start.Cost,
+	}
+}
+
+type Service interface {
+	Create(ctx context.Context, record Record) error
+	// SessionTotal returns what the requests recorded for a session spent,
+	// including the requests of its task sessions.
+	SessionTotal(ctx context.Context, sessionID string) (SessionTotal, error)
+	// List returns the records created in [since, until).
+	List(ctx context.Context, since, until time.Time) ([]Record, error)
+}
+
+type service struct {
+	q db.Querier
+}
+
+func NewService(q db.Querier) Service {
+	return &service{q: q}
+}
+
+func (s *service) Create(ctx context.Context, record Record) error {
+	return s.q.CreateUsage(ctx, db.CreateUsageParams{
+		ID:                  uuid.New().String(),
+		SessionID:           record.SessionID,
+		MessageID:           sql.NullString{String: record.MessageID, Valid: record.MessageID != ""},
+		Model:               record.Model,
+		Provider:            record.Provider,
+		InputTokens:         record.InputTokens,
+		OutputTokens:        record.OutputTokens,
+		CacheCreationTokens: record.CacheCreationTokens,
+		CacheReadTokens:     record.CacheReadTokens,
+		Cost:                record.Cost,
+		Kind:                string(cmp.Or(record.Kind, KindAgent)),
+	})
+}
+
+func (s *service) SessionTotal(ctx context.Context, sessionID string) (SessionTotal, error) {
+	total, err := s.q.GetSessionUsage(ctx, sessionID)
+	if err != nil {
+		return SessionTotal{}, err
+	}
+	return SessionTotal{
+		AgentRequests:    total.AgentRequests,
+		Tokens:           total.Tokens,
+		PromptTokens:     total.PromptTokens,
+		CompletionTokens: total.CompletionTokens,
+		Cost:             total.Cost,
+	}, nil
+}
+
+func (s *service) List(ctx context.Context, since, until time.Time) ([]Record, error) {
+	dbRecords, err := s.q.ListUsage(ctx, db.ListUsageParams{
+		Since: since.Unix(),
+		Until: until.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, len(dbRecords))
+	for i, item := range dbRecords {
+		records[i] = s.fromDBItem(item)
+	}
+	return records, nil
+}
+
+func (s *service) fromDBItem(item db.Usage) Record {
+	return Record{
+		ID:                  item.ID,
+		SessionID:           item.SessionID,
+		MessageID:           item.MessageID.String,
+		Kind:                Kind(item.Kind),
+		Model:               item.Model,
+		Provider:            item.Provider,
+		InputTokens:         item.InputTokens,
+		OutputTokens:        item.OutputTokens,
+		CacheCreationTokens: item.CacheCreationTokens,
+		CacheReadTokens:     item.CacheReadTokens,
+		Cost:                item.Cost,
+		CreatedAt:           item.CreatedAt,
+	}
+}
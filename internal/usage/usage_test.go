@@ -0,0 +1,36 @@
+package usage
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionTotal(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	svc := NewService(db.New(conn))
+
+	total, err := svc.SessionTotal(t.Context(), "session")
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	for _, record := range []Record{
+		{SessionID: "session", Model: "gpt-4o", Provider: "openai", InputTokens: 1000, OutputTokens: 100, Cost: 0.5},
+		{SessionID: "session", Kind: KindTitle, Model: "gpt-4o-mini", Provider: "openai", CacheCreationTokens: 10, CacheReadTokens: 20, Cost: 0.25},
+		{SessionID: "other", Model: "gpt-4o", Provider: "openai", InputTokens: 5000, Cost: 3},
+	} {
+		require.NoError(t, svc.Create(t.Context(), record))
+	}
+
+	total, err = svc.SessionTotal(t.Context(), "session")
+	require.NoError(t, err)
+	require.Equal(t, SessionTotal{AgentRequests: 1, Tokens: 1130, PromptTokens: 1010, CompletionTokens: 120, Cost: 0.75}, total)
+
+	spent := total.Since(SessionTotal{AgentRequests: 1, Tokens: 1100, PromptTokens: 1000, CompletionTokens: 100, Cost: 0.5})
+	require.Equal(t, SessionTotal{Tokens: 30, PromptTokens: 10, CompletionTokens: 20, Cost: 0.25}, spent)
+}